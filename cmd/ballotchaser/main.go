@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/andersfylling/disgord"
+	"github.com/hitecherik/Tabulatron/internal/ballotchaser"
+	"github.com/hitecherik/Tabulatron/internal/chat"
+	"github.com/hitecherik/Tabulatron/internal/db"
+	"github.com/hitecherik/Tabulatron/internal/hermes"
+	"github.com/hitecherik/Tabulatron/internal/scheduler"
+	"github.com/hitecherik/Tabulatron/internal/templates"
+	"github.com/hitecherik/Tabulatron/internal/util"
+	"github.com/hitecherik/Tabulatron/pkg/tabbycat"
+	"github.com/joho/godotenv"
+)
+
+type options struct {
+	round          uint64
+	interval       time.Duration
+	channel        uint64
+	db             db.Database
+	botTokens      []string
+	tabbycatApiKey string
+	tabbycatUrl    string
+	tabbycatSlug   string
+	verbose        bool
+	templates      templates.Templates
+	location       *time.Location
+}
+
+var opts options
+
+func bail(err error) {
+	if err != nil {
+		panic(err.Error())
+	}
+}
+
+func verbose(format string, a ...interface{}) {
+	if opts.verbose {
+		fmt.Printf(format, a...)
+	}
+}
+
+func init() {
+	var envFile string
+
+	flag.StringVar(&envFile, "env", ".env", "file to read environment variables from")
+	flag.Uint64Var(&opts.round, "round", 0, "the round to chase ballots for")
+	flag.DurationVar(&opts.interval, "interval", 0, "how often to remind chairs (defaults to BALLOT_CHASE_INTERVAL)")
+	flag.Uint64Var(&opts.channel, "channel", 0, "a Discord channel to post the summary in")
+	flag.Var(&opts.db, "db", "SQLite3 database representing the tournament")
+	flag.BoolVar(&opts.verbose, "verbose", false, "print additional output")
+	flag.Var(&opts.templates, "templates", "path to a TOML document overriding message templates")
+	flag.Parse()
+
+	if opts.round == 0 {
+		fmt.Fprintln(os.Stderr, "please specify a round")
+		os.Exit(2)
+	}
+
+	bail(godotenv.Load(envFile))
+
+	opts.tabbycatApiKey = os.Getenv("TABBYCAT_API_KEY")
+	opts.tabbycatUrl = os.Getenv("TABBYCAT_URL")
+	opts.tabbycatSlug = os.Getenv("TABBYCAT_SLUG")
+
+	var err error
+	if opts.interval == 0 {
+		opts.interval, err = util.EnvDuration("BALLOT_CHASE_INTERVAL", 5*time.Minute)
+		bail(err)
+	}
+
+	opts.location, err = util.EnvLocation("TOURNAMENT_TIMEZONE")
+	bail(err)
+
+	opts.botTokens = []string{os.Getenv("DISCORD_BOT_TOKEN")}
+	for i := 1; true; i++ {
+		token := os.Getenv(fmt.Sprintf("DISCORD_HELPER_%v", i))
+
+		if token == "" {
+			break
+		}
+
+		opts.botTokens = append(opts.botTokens, token)
+	}
+
+	bail(opts.db.SetIfNotExists(fmt.Sprintf("%v.db", opts.tabbycatSlug)))
+}
+
+func main() {
+	var bot chat.Platform
+	bots := scheduler.New()
+	for _, token := range opts.botTokens {
+		client := disgord.New(disgord.Config{
+			BotToken: token,
+		})
+		go client.StayConnectedUntilInterrupted(context.Background())
+
+		platform := chat.NewDiscord(client)
+		if bot == nil {
+			bot = platform
+		}
+		bots.AddClient(platform)
+	}
+
+	h := hermes.New(bots)
+	h.SetLanguages(&opts.db)
+	go h.Listen()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 1)
+	go func() {
+		<-signals
+		verbose("Stopping\n")
+		cancel()
+	}()
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	board := ballotchaser.NewBoard(bot, disgord.Snowflake(opts.channel))
+	report := func(pages []string) {
+		if opts.channel == 0 {
+			fmt.Printf("%v\n\n", strings.Join(pages, "\n"))
+			return
+		}
+
+		bail(board.Show(pages))
+		verbose("Updated summary in channel %v\n", opts.channel)
+	}
+
+	tabbycat := tabbycat.New(opts.tabbycatApiKey, opts.tabbycatUrl, opts.tabbycatSlug)
+	chaser := ballotchaser.New(tabbycat, &opts.db, h, opts.interval)
+	chaser.SetTemplates(&opts.templates)
+	chaser.SetLocation(opts.location)
+
+	err := chaser.Chase(ctx, opts.round, report)
+	if !errors.Is(err, context.Canceled) {
+		bail(err)
+	}
+
+	printReport(h.Wait())
+	verbose("%v\n", bots.Metrics())
+}
+
+func printReport(report hermes.Report) {
+	fmt.Println(report.Summary())
+
+	if len(report.Failed) > 0 {
+		fmt.Println("These chairs need to be contacted manually:")
+
+		for _, line := range hermes.DescribeFailures(&opts.db, report.Failed) {
+			fmt.Println(line)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/hitecherik/Tabulatron/internal/locale"
+	"github.com/hitecherik/Tabulatron/internal/templates"
+)
+
+type options struct {
+	templates templates.Templates
+	catalogue locale.Catalogue
+	verbose   bool
+}
+
+var opts options
+
+func bail(err error) {
+	if err != nil {
+		panic(err.Error())
+	}
+}
+
+func init() {
+	flag.Var(&opts.templates, "templates", "path to a TOML document overriding message templates")
+	flag.Var(&opts.catalogue, "locale", "path to a TOML document translating replies")
+	flag.BoolVar(&opts.verbose, "verbose", false, "print every rendered template")
+	flag.Parse()
+}
+
+func main() {
+	samples, err := opts.templates.Validate()
+
+	if opts.verbose || err != nil {
+		for _, sample := range samples {
+			fmt.Printf("=== %v\n%v\n\n", sample.Name, sample.Text)
+		}
+	}
+
+	bail(err)
+	bail(opts.catalogue.Validate())
+
+	fmt.Printf("All %v templates rendered successfully.\n", len(samples))
+}
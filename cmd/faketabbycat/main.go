@@ -0,0 +1,60 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/hitecherik/Tabulatron/pkg/tabbycat/tabbycattest"
+	"github.com/joho/godotenv"
+)
+
+type options struct {
+	tabbycatApiKey string
+	tabbycatSlug   string
+	fixtures       string
+	address        string
+	verbose        bool
+}
+
+var opts options
+
+func bail(err error) {
+	if err != nil {
+		panic(err.Error())
+	}
+}
+
+func init() {
+	var envFile string
+
+	flag.StringVar(&envFile, "env", ".env", "file to read environment variables from")
+	flag.StringVar(&opts.fixtures, "fixtures", "pkg/tabbycat/tabbycattest/fixtures", "directory of JSON fixtures to serve")
+	flag.StringVar(&opts.address, "address", "localhost:8000", "address to listen on")
+	flag.BoolVar(&opts.verbose, "verbose", false, "print additional output")
+	flag.Parse()
+
+	bail(godotenv.Load(envFile))
+
+	opts.tabbycatApiKey = os.Getenv("TABBYCAT_API_KEY")
+	opts.tabbycatSlug = os.Getenv("TABBYCAT_SLUG")
+}
+
+func main() {
+	server, err := tabbycattest.New(opts.tabbycatSlug, opts.fixtures)
+	bail(err)
+	server.SetApiKey(opts.tabbycatApiKey)
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if opts.verbose || r.Method != http.MethodGet {
+			log.Printf("%v %v", r.Method, r.URL.Path)
+		}
+
+		server.ServeHTTP(w, r)
+	})
+
+	fmt.Printf("Serving tournament %v from %v on http://%v\n", opts.tabbycatSlug, opts.fixtures, opts.address)
+	bail(http.ListenAndServe(opts.address, handler))
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/andersfylling/disgord"
+	"github.com/hitecherik/Tabulatron/internal/chat"
+	"github.com/hitecherik/Tabulatron/internal/guildsetup"
+	"github.com/hitecherik/Tabulatron/internal/layout"
+	"github.com/hitecherik/Tabulatron/internal/multiroom"
+	"github.com/hitecherik/Tabulatron/internal/util"
+	"github.com/hitecherik/Tabulatron/pkg/tabbycat"
+	"github.com/joho/godotenv"
+)
+
+type options struct {
+	tabbycatApiKey string
+	tabbycatUrl    string
+	tabbycatSlug   string
+	botToken       string
+	guild          string
+	layout         layout.Layout
+	categories     multiroom.Categories
+	dryRun         bool
+}
+
+var opts options
+
+func bail(err error) {
+	if err != nil {
+		panic(err.Error())
+	}
+}
+
+func init() {
+	var envFile string
+
+	flag.StringVar(&envFile, "env", ".env", "file to read environment variables from")
+	flag.StringVar(&opts.guild, "guild", "", "ID of the guild to set up (defaults to the one in the layout)")
+	flag.Var(&opts.layout, "layout", "path to a TOML document naming the guild's roles and channels")
+	flag.Var(&opts.categories, "categories", "path to the categories TOML document")
+	flag.BoolVar(&opts.dryRun, "dry-run", false, "print what would be changed without changing the guild")
+	flag.Parse()
+
+	bail(godotenv.Load(envFile))
+
+	if opts.guild == "" {
+		opts.guild = opts.layout.Guild()
+	}
+
+	if opts.guild == "" {
+		fmt.Fprintln(os.Stderr, "please specify the guild to set up")
+		os.Exit(2)
+	}
+
+	opts.tabbycatApiKey = os.Getenv("TABBYCAT_API_KEY")
+	opts.tabbycatUrl = os.Getenv("TABBYCAT_URL")
+	opts.tabbycatSlug = os.Getenv("TABBYCAT_SLUG")
+	opts.botToken = os.Getenv("DISCORD_BOT_TOKEN")
+}
+
+func main() {
+	guild, err := util.StringToSnowflake(opts.guild)
+	bail(err)
+
+	tabbycat := tabbycat.New(opts.tabbycatApiKey, opts.tabbycatUrl, opts.tabbycatSlug)
+	venues, err := tabbycat.GetVenues()
+	bail(err)
+
+	client := disgord.New(disgord.Config{
+		BotToken: opts.botToken,
+	})
+
+	builder := guildsetup.NewBuilder(chat.NewDiscord(client), guild, &opts.layout, opts.dryRun, func(format string, a ...interface{}) {
+		fmt.Printf(format, a...)
+	})
+	bail(builder.Setup(context.Background(), venues, opts.categories))
+}
@@ -1,24 +1,43 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"os"
+	"text/template"
+	"time"
 
 	"github.com/andersfylling/disgord"
+	"github.com/hitecherik/Tabulatron/internal/audience"
+	"github.com/hitecherik/Tabulatron/internal/chat"
 	"github.com/hitecherik/Tabulatron/internal/db"
 	"github.com/hitecherik/Tabulatron/internal/hermes"
+	"github.com/hitecherik/Tabulatron/internal/mailer"
+	"github.com/hitecherik/Tabulatron/internal/scheduler"
+	"github.com/hitecherik/Tabulatron/internal/templates"
 	"github.com/hitecherik/Tabulatron/internal/util"
+	"github.com/hitecherik/Tabulatron/pkg/tabbycat"
 	"github.com/joho/godotenv"
 )
 
 type options struct {
-	db           db.Database
-	botTokens    []string
-	tabbycatSlug string
-	verbose      bool
-	message      string
+	db             db.Database
+	botTokens      []string
+	tabbycatApiKey string
+	tabbycatUrl    string
+	tabbycatSlug   string
+	verbose        bool
+	preview        bool
+	message        string
+	templated      bool
+	id             string
+	subject        string
+	template       *template.Template
+	filter         audience.Filter
+	mailer         *mailer.Mailer
 }
 
 var opts options
@@ -42,6 +61,16 @@ func init() {
 	flag.Var(&opts.db, "db", "SQLite3 database representing the tournament")
 	flag.BoolVar(&opts.verbose, "verbose", false, "print additional output")
 	flag.StringVar(&opts.message, "message", "", "the message to send all participants")
+	flag.BoolVar(&opts.templated, "template", false, "treat the message as a text/template, which may use {{.Name}}")
+	flag.StringVar(&opts.id, "id", "", "identifies this announcement, so that rerunning with the same id only messages those who missed it (defaults to a new id on every run)")
+	flag.StringVar(&opts.subject, "subject", "A message from the tab team", "the subject of emails sent to unregistered participants")
+	flag.BoolVar(&opts.filter.Speakers, "speakers", false, "only message speakers")
+	flag.BoolVar(&opts.filter.Adjudicators, "adjudicators", false, "only message adjudicators")
+	flag.Var(&opts.filter.Teams, "team", "only message speakers in this team (can be repeated)")
+	flag.Var(&opts.filter.Institutions, "institution", "only message participants from this institution (can be repeated)")
+	flag.BoolVar(&opts.filter.NotCheckedIn, "not-checked-in", false, "only message participants who aren't checked in")
+	flag.BoolVar(&opts.filter.Unregistered, "unregistered", false, "email participants who haven't registered on Discord instead")
+	flag.BoolVar(&opts.preview, "preview", false, "print who would be messaged without sending anything")
 	flag.Parse()
 
 	bail(godotenv.Load(envFile))
@@ -50,8 +79,33 @@ func init() {
 		bail(fmt.Errorf("Please provide a non-empty message to send all participants."))
 	}
 
+	if opts.id == "" {
+		opts.id = fmt.Sprint(time.Now().Unix())
+	}
+
+	if opts.templated {
+		var err error
+		opts.template, err = template.New("message").Parse(opts.message)
+		bail(err)
+	}
+
+	opts.tabbycatApiKey = os.Getenv("TABBYCAT_API_KEY")
+	opts.tabbycatUrl = os.Getenv("TABBYCAT_URL")
 	opts.tabbycatSlug = os.Getenv("TABBYCAT_SLUG")
 
+	if host := os.Getenv("SMTP_HOST"); host != "" {
+		port := os.Getenv("SMTP_PORT")
+		if port == "" {
+			port = "587"
+		}
+
+		opts.mailer = mailer.New(host, port, os.Getenv("SMTP_USERNAME"), os.Getenv("SMTP_PASSWORD"), os.Getenv("SMTP_FROM"))
+	}
+
+	if opts.filter.Unregistered && opts.mailer == nil && !opts.preview {
+		bail(fmt.Errorf("Please set SMTP_HOST to email unregistered participants."))
+	}
+
 	opts.botTokens = []string{os.Getenv("DISCORD_BOT_TOKEN")}
 	for i := 1; true; i++ {
 		token := os.Getenv(fmt.Sprintf("DISCORD_HELPER_%v", i))
@@ -67,29 +121,103 @@ func init() {
 }
 
 func main() {
-	clients := make([]*hermes.Hermes, 0, len(opts.botTokens))
+	tabbycat := tabbycat.New(opts.tabbycatApiKey, opts.tabbycatUrl, opts.tabbycatSlug)
+	recipients, err := audience.Select(context.Background(), tabbycat, &opts.db, opts.filter)
+	reportCheckinFailures(err)
+
+	if opts.preview {
+		fmt.Printf("This would message %v %v:\n", len(recipients), opts.filter)
+		for _, recipient := range recipients {
+			fmt.Printf("%v <%v>\n", recipient.Name, recipient.Email)
+		}
+		return
+	}
+
+	if opts.filter.Unregistered {
+		emailAll(recipients)
+		return
+	}
+
+	bots := scheduler.New()
 	for _, token := range opts.botTokens {
 		client := disgord.New(disgord.Config{
 			BotToken: token,
 		})
 		go client.StayConnectedUntilInterrupted(context.Background())
+		bots.AddClient(chat.NewDiscord(client))
+	}
+
+	messenger := hermes.New(bots)
+	messenger.SetOutbox(&opts.db)
+	go messenger.Listen()
 
-		h := hermes.New(client)
-		clients = append(clients, h)
+	fmt.Printf("Sending announcement %v; rerun with -id %v to retry anyone it misses.\n", opts.id, opts.id)
+	for _, recipient := range recipients {
+		snowflake, err := util.StringToSnowflake(recipient.Discord)
+		bail(err)
 
-		go h.Listen()
-		defer h.Wait()
+		key := fmt.Sprintf("mass:%v:%v", opts.id, snowflake)
+		messenger.SendOnce(key, snowflake, render(recipient), nil)
 	}
 
-	discords, err := opts.db.AllDiscords()
-	bail(err)
+	verbose("Queued %v messages.\n", len(recipients))
 
-	snowflakes, err := util.StringsToSnowflakes(discords)
-	bail(err)
+	printReport(messenger.Wait())
+	verbose("%v\n", bots.Metrics())
+}
 
-	for i, snowflake := range snowflakes {
-		clients[i%len(clients)].SendMessage(snowflake, opts.message)
+func reportCheckinFailures(err error) {
+	var barcodeErr *tabbycat.BarcodeError
+	if !errors.As(err, &barcodeErr) {
+		bail(err)
+		return
 	}
 
-	verbose("Queued %v messages.\n", len(snowflakes))
+	for _, failure := range barcodeErr.Failures {
+		fmt.Fprintf(os.Stderr, "Left out %v (%v), since their check-in could not be fetched: %v\n", failure.Participant.Name, failure.Participant.Id, failure.Err)
+	}
+}
+
+func render(recipient db.Participant) string {
+	if opts.template == nil {
+		return opts.message
+	}
+
+	var message bytes.Buffer
+	bail(opts.template.Execute(&message, templates.Participant{Name: recipient.Name}))
+
+	return message.String()
+}
+
+func emailAll(recipients []db.Participant) {
+	sent := 0
+
+	for _, recipient := range recipients {
+		if recipient.Email == "" {
+			fmt.Printf("%v has no email address and needs to be contacted manually.\n", recipient.Name)
+			continue
+		}
+
+		if err := opts.mailer.Send(recipient.Email, opts.subject, render(recipient)); err != nil {
+			fmt.Printf("Could not email %v <%v>: %v\n", recipient.Name, recipient.Email, err.Error())
+			continue
+		}
+
+		verbose("Emailed %v <%v>\n", recipient.Name, recipient.Email)
+		sent++
+	}
+
+	fmt.Printf("Emailed %v/%v participants.\n", sent, len(recipients))
+}
+
+func printReport(report hermes.Report) {
+	fmt.Println(report.Summary())
+
+	if len(report.Failed) > 0 {
+		fmt.Println("These participants need to be contacted manually:")
+
+		for _, line := range hermes.DescribeFailures(&opts.db, report.Failed) {
+			fmt.Println(line)
+		}
+	}
 }
@@ -1,12 +1,15 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/hitecherik/Tabulatron/internal/db"
+	"github.com/hitecherik/Tabulatron/internal/util"
 	"github.com/hitecherik/Tabulatron/pkg/tabbycat"
 	"github.com/joho/godotenv"
 )
@@ -15,6 +18,9 @@ var opts struct {
 	tabbycatApiKey string
 	tabbycatUrl    string
 	tabbycatSlug   string
+	concurrency    int
+	rateLimit      float64
+	timeout        time.Duration
 	verbose        bool
 	redact         bool
 	reset          bool
@@ -49,6 +55,14 @@ func init() {
 	opts.tabbycatUrl = os.Getenv("TABBYCAT_URL")
 	opts.tabbycatSlug = os.Getenv("TABBYCAT_SLUG")
 
+	var err error
+	opts.concurrency, err = util.EnvInt("TABBYCAT_CONCURRENCY", 4)
+	bail(err)
+	opts.rateLimit, err = util.EnvFloat("TABBYCAT_RATE_LIMIT", 0)
+	bail(err)
+	opts.timeout, err = util.EnvDuration("TABBYCAT_TIMEOUT", 30*time.Second)
+	bail(err)
+
 	bail(opts.db.SetIfNotExists(fmt.Sprintf("%v.db", opts.tabbycatSlug)))
 }
 
@@ -58,8 +72,12 @@ func main() {
 	}
 
 	tabbycat := tabbycat.New(opts.tabbycatApiKey, opts.tabbycatUrl, opts.tabbycatSlug)
+	tabbycat.SetConcurrency(opts.concurrency)
+	tabbycat.SetRateLimit(opts.rateLimit)
+	tabbycat.SetTimeout(opts.timeout)
+
 	teams, err := tabbycat.GetTeams()
-	bail(err)
+	reportBarcodeFailures(err)
 
 	verbose("Fetched %v teams\n", len(teams))
 
@@ -70,7 +88,7 @@ func main() {
 	}
 
 	adjudicators, err := tabbycat.GetAdjudicators()
-	bail(err)
+	reportBarcodeFailures(err)
 
 	if opts.redact {
 		redactNames(adjudicators)
@@ -85,6 +103,18 @@ func main() {
 	verbose("Inserted %v adjudicators into database\n", len(adjudicators))
 }
 
+func reportBarcodeFailures(err error) {
+	var barcodeErr *tabbycat.BarcodeError
+	if !errors.As(err, &barcodeErr) {
+		bail(err)
+		return
+	}
+
+	for _, failure := range barcodeErr.Failures {
+		fmt.Fprintf(os.Stderr, "Could not fetch barcode for %v (%v): %v\n", failure.Participant.Name, failure.Participant.Id, failure.Err)
+	}
+}
+
 func redactNames(participants []tabbycat.Participant) {
 	for i := range participants {
 		components := strings.Split(participants[i].Name, " ")
@@ -4,16 +4,17 @@ import (
 	"context"
 	"flag"
 	"fmt"
-	"log"
 	"os"
 
 	"github.com/andersfylling/disgord"
+	"github.com/hitecherik/Tabulatron/internal/chat"
 	"github.com/hitecherik/Tabulatron/internal/db"
 	"github.com/hitecherik/Tabulatron/internal/hermes"
 	"github.com/hitecherik/Tabulatron/internal/multiroom"
-	"github.com/hitecherik/Tabulatron/internal/roundrunner"
+	"github.com/hitecherik/Tabulatron/internal/roundmessenger"
 	"github.com/hitecherik/Tabulatron/internal/rounds"
-	"github.com/hitecherik/Tabulatron/internal/util"
+	"github.com/hitecherik/Tabulatron/internal/scheduler"
+	"github.com/hitecherik/Tabulatron/internal/templates"
 	"github.com/hitecherik/Tabulatron/pkg/tabbycat"
 	"github.com/joho/godotenv"
 )
@@ -26,7 +27,9 @@ type options struct {
 	tabbycatUrl    string
 	tabbycatSlug   string
 	verbose        bool
+	resend         bool
 	categories     multiroom.Categories
+	templates      templates.Templates
 }
 
 var opts options
@@ -50,7 +53,9 @@ func init() {
 	flag.Var(&opts.round, "round", "a round to run")
 	flag.Var(&opts.db, "db", "SQLite3 database representing the tournament")
 	flag.BoolVar(&opts.verbose, "verbose", false, "print additional output")
+	flag.BoolVar(&opts.resend, "resend", false, "message everyone again, even if they've already been sent this round")
 	flag.Var(&opts.categories, "categories", "path to the categories TOML document")
+	flag.Var(&opts.templates, "templates", "path to a TOML document overriding message templates")
 	flag.Parse()
 
 	if len(opts.round) == 0 {
@@ -79,115 +84,49 @@ func init() {
 }
 
 func main() {
-	clients := make([]*hermes.Hermes, 0, len(opts.botTokens))
+	bots := scheduler.New()
 	for _, token := range opts.botTokens {
 		client := disgord.New(disgord.Config{
 			BotToken: token,
 		})
 		go client.StayConnectedUntilInterrupted(context.Background())
-
-		h := hermes.New(client)
-		clients = append(clients, h)
-
-		go h.Listen()
+		bots.AddClient(chat.NewDiscord(client))
 	}
 
-	var rooms []tabbycat.Room
+	h := hermes.New(bots)
+	h.SetOutbox(&opts.db)
+	h.SetLanguages(&opts.db)
+	go h.Listen()
+
 	tabbycat := tabbycat.New(opts.tabbycatApiKey, opts.tabbycatUrl, opts.tabbycatSlug)
+	messenger := roundmessenger.New(tabbycat, &opts.db, h, opts.categories)
+	messenger.SetTemplates(&opts.templates)
 
-	for _, round := range opts.round {
-		r, err := tabbycat.GetDraw(round)
-		bail(err)
-		rooms = append(rooms, r...)
+	if opts.resend {
+		bail(messenger.Forget(opts.round))
 	}
 
-	verbose("Fetched %v pairings\n", len(rooms))
-
-	venues, err := tabbycat.GetVenues()
-	bail(err)
-	venueMap := roundrunner.BuildVenueMap(venues)
-
-	verbose("Fetched %v venues\n", len(venues))
-
-	messageCounter := 0
-
-	for _, room := range rooms {
-		venueName := venueMap[room.VenueId]
-		category, err := opts.categories.Lookup(venueName)
-		if err != nil {
-			log.Print(err.Error())
-		}
-
-		for i, team := range room.TeamIds {
-			discords, urlKeys, err := opts.db.ParticipantsFromTeamId(team)
-			bail(err)
-
-			snowflakes, err := util.StringsToSnowflakes(discords)
-			bail(err)
-
-			for j, snowflake := range snowflakes {
-				message := fmt.Sprintf(
-					"In this round, you will be speaking in **%v** in room **%v**.%v",
-					room.SideNames[i],
-					venueName,
-					addLinks(tabbycat, category.Url, urlKeys[j]),
-				)
-
-				clients[messageCounter%len(clients)].SendMessage(snowflake, message)
-				messageCounter += 1
-			}
-		}
-
-		judgeIds := append([]string{room.ChairId}, append(room.PanellistIds, room.TraineeIds...)...)
-		discords, urlKeys, err := opts.db.DiscordFromParticipantIds(judgeIds)
-		bail(err)
-
-		for j, discord := range discords {
-			if discord == "" {
-				log.Printf("Adjudicator %v has no discord ID.\n", judgeIds[j])
-				continue
-			}
-
-			snowflake, err := util.StringToSnowflake(discord)
-			bail(err)
-
-			position := "a panellist"
-			if j == 0 {
-				position = "the chair"
-			} else if j > len(room.PanellistIds) {
-				position = "a trainee"
-			}
-
-			message := fmt.Sprintf(
-				"In this round, you will be judging as **%v** in room **%v**.%v",
-				position,
-				venueName,
-				addLinks(tabbycat, category.Url, urlKeys[j]),
-			)
-
-			clients[messageCounter%len(clients)].SendMessage(snowflake, message)
-			messageCounter += 1
+	_, err := messenger.Release(context.Background(), opts.round, func(progress roundmessenger.Progress) {
+		if progress.Rooms == 0 {
+			verbose("Fetched %v pairings\n", progress.TotalRooms)
+		} else if progress.Delivered == 0 && progress.Skipped == 0 && len(progress.Failed) == 0 {
+			verbose("Queued messages for %v/%v rooms\n", progress.Rooms, progress.TotalRooms)
 		}
+	})
+	bail(err)
 
-		verbose("Queued messages for room %v\n", venueName)
-	}
-
-	for _, h := range clients {
-		h.Wait()
-	}
+	printReport(h.Wait())
+	verbose("%v\n", bots.Metrics())
 }
 
-func addLinks(tabbycat *tabbycat.Tabbycat, venueUrl string, urlKey string) string {
-	links := ""
+func printReport(report hermes.Report) {
+	fmt.Println(report.Summary())
 
-	if venueUrl != "" {
-		links = fmt.Sprintf("\n\nThe link to your Zoom room is %v.", venueUrl)
-	}
+	if len(report.Failed) > 0 {
+		fmt.Println("These participants need to be contacted manually:")
 
-	if urlKey != "" {
-		privateUrl := tabbycat.PrivateUrlFromKey(urlKey)
-		links = fmt.Sprintf("%v\n\nYour private URL is %v.", links, privateUrl)
+		for _, line := range hermes.DescribeFailures(&opts.db, report.Failed) {
+			fmt.Println(line)
+		}
 	}
-
-	return links
 }
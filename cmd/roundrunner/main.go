@@ -1,15 +1,21 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"os"
 	"strings"
 
+	"github.com/andersfylling/disgord"
+	"github.com/hitecherik/Tabulatron/internal/chat"
 	"github.com/hitecherik/Tabulatron/internal/db"
+	"github.com/hitecherik/Tabulatron/internal/discordrooms"
+	"github.com/hitecherik/Tabulatron/internal/layout"
 	"github.com/hitecherik/Tabulatron/internal/multiroom"
 	"github.com/hitecherik/Tabulatron/internal/roundrunner"
 	"github.com/hitecherik/Tabulatron/internal/rounds"
+	"github.com/hitecherik/Tabulatron/internal/util"
 	"github.com/hitecherik/Tabulatron/internal/zoom"
 	"github.com/hitecherik/Tabulatron/pkg/tabbycat"
 	"github.com/joho/godotenv"
@@ -24,6 +30,11 @@ type options struct {
 	categories     multiroom.Categories
 	adjsOnly       bool
 	verbose        bool
+	discord        bool
+	close          bool
+	botToken       string
+	guild          string
+	layout         layout.Layout
 }
 
 var opts options
@@ -49,6 +60,10 @@ func init() {
 	flag.Var(&opts.categories, "categories", "path to the categories TOML document")
 	flag.BoolVar(&opts.adjsOnly, "adjs-only", false, "only include adjudicators in CSVs")
 	flag.BoolVar(&opts.verbose, "verbose", false, "print additional output")
+	flag.BoolVar(&opts.discord, "discord", false, "let participants into their venue's Discord channels instead of writing CSVs")
+	flag.BoolVar(&opts.close, "close", false, "take away the Discord channel access given for the round")
+	flag.StringVar(&opts.guild, "guild", "", "ID of the tournament's guild (defaults to the one in the layout)")
+	flag.Var(&opts.layout, "layout", "path to a TOML document naming the guild's roles and channels")
 	flag.Parse()
 
 	if len(opts.round) == 0 {
@@ -61,6 +76,16 @@ func init() {
 	opts.tabbycatApiKey = os.Getenv("TABBYCAT_API_KEY")
 	opts.tabbycatUrl = os.Getenv("TABBYCAT_URL")
 	opts.tabbycatSlug = os.Getenv("TABBYCAT_SLUG")
+	opts.botToken = os.Getenv("DISCORD_BOT_TOKEN")
+
+	if opts.guild == "" {
+		opts.guild = opts.layout.Guild()
+	}
+
+	if (opts.discord || opts.close) && opts.guild == "" {
+		fmt.Fprintln(os.Stderr, "please specify the tournament's guild")
+		os.Exit(2)
+	}
 
 	bail(opts.db.SetIfNotExists(fmt.Sprintf("%v.db", opts.tabbycatSlug)))
 }
@@ -69,6 +94,11 @@ func main() {
 	var rooms []tabbycat.Room
 	tabbycat := tabbycat.New(opts.tabbycatApiKey, opts.tabbycatUrl, opts.tabbycatSlug)
 
+	if opts.discord || opts.close {
+		runDiscord(tabbycat)
+		return
+	}
+
 	for _, round := range opts.round {
 		r, err := tabbycat.GetDraw(round)
 		bail(err)
@@ -108,3 +138,31 @@ func main() {
 
 	verbose("Wrote %v files\n", written)
 }
+
+func runDiscord(tabbycat *tabbycat.Tabbycat) {
+	guild, err := util.StringToSnowflake(opts.guild)
+	bail(err)
+
+	client := disgord.New(disgord.Config{
+		BotToken: opts.botToken,
+	})
+	rooms := discordrooms.New(chat.NewDiscord(client), guild, tabbycat, &opts.db)
+	ctx := context.Background()
+
+	for _, round := range opts.round {
+		if opts.close {
+			revoked, err := rooms.Close(ctx, round)
+			bail(err)
+			verbose("Removed %v permissions for round %v\n", revoked, round)
+			continue
+		}
+
+		report, err := rooms.Open(ctx, round)
+		bail(err)
+		verbose("Let %v participants into %v rooms for round %v\n", report.Participants, report.Rooms, round)
+
+		for _, venue := range report.Missing {
+			fmt.Printf("Couldn't find Discord channels for %v\n", venue)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/hitecherik/Tabulatron/internal/tabbyimport"
+	"github.com/hitecherik/Tabulatron/pkg/tabbycat"
+	"github.com/joho/godotenv"
+)
+
+type options struct {
+	tabbycatApiKey string
+	tabbycatUrl    string
+	tabbycatSlug   string
+	kind           string
+	csv            string
+	dryRun         bool
+}
+
+var opts options
+
+func bail(err error) {
+	if err != nil {
+		panic(err.Error())
+	}
+}
+
+func init() {
+	var envFile string
+
+	flag.StringVar(&envFile, "env", ".env", "file to read environment variables from")
+	flag.StringVar(&opts.kind, "type", "", "what the CSV contains: teams, adjudicators, venues or rounds")
+	flag.StringVar(&opts.csv, "csv", "", "path to the CSV file to import")
+	flag.BoolVar(&opts.dryRun, "dry-run", false, "print what would be created without changing Tabbycat")
+	flag.Parse()
+
+	if opts.csv == "" {
+		fmt.Fprintln(os.Stderr, "please specify a CSV file to import")
+		os.Exit(2)
+	}
+
+	bail(godotenv.Load(envFile))
+
+	opts.tabbycatApiKey = os.Getenv("TABBYCAT_API_KEY")
+	opts.tabbycatUrl = os.Getenv("TABBYCAT_URL")
+	opts.tabbycatSlug = os.Getenv("TABBYCAT_SLUG")
+}
+
+func main() {
+	file, err := os.Open(opts.csv)
+	bail(err)
+	defer file.Close()
+
+	tabbycat := tabbycat.New(opts.tabbycatApiKey, opts.tabbycatUrl, opts.tabbycatSlug)
+	importer := tabbyimport.NewImporter(tabbycat, opts.dryRun, func(format string, a ...interface{}) {
+		fmt.Printf(format, a...)
+	})
+	ctx := context.Background()
+
+	switch opts.kind {
+	case "teams":
+		teams, err := tabbyimport.ReadTeams(file)
+		bail(err)
+		bail(importer.ImportTeams(ctx, teams))
+	case "adjudicators":
+		adjudicators, err := tabbyimport.ReadAdjudicators(file)
+		bail(err)
+		bail(importer.ImportAdjudicators(ctx, adjudicators))
+	case "venues":
+		venues, err := tabbyimport.ReadVenues(file)
+		bail(err)
+		bail(importer.ImportVenues(ctx, venues))
+	case "rounds":
+		rounds, err := tabbyimport.ReadRounds(file)
+		bail(err)
+		bail(importer.ImportRounds(ctx, rounds))
+	default:
+		fmt.Fprintf(os.Stderr, "unknown import type %q: expected teams, adjudicators, venues or rounds\n", opts.kind)
+		os.Exit(2)
+	}
+}
@@ -2,17 +2,33 @@ package main
 
 import (
 	"context"
+	"crypto/ed25519"
 	"flag"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
 	"os/signal"
+	"strings"
+	"sync/atomic"
 	"syscall"
+	"time"
 
 	"github.com/andersfylling/disgord"
+	"github.com/hitecherik/Tabulatron/internal/chat"
 	"github.com/hitecherik/Tabulatron/internal/db"
+	"github.com/hitecherik/Tabulatron/internal/hermes"
+	"github.com/hitecherik/Tabulatron/internal/interactions"
+	"github.com/hitecherik/Tabulatron/internal/layout"
+	"github.com/hitecherik/Tabulatron/internal/locale"
+	"github.com/hitecherik/Tabulatron/internal/mailer"
+	"github.com/hitecherik/Tabulatron/internal/multiroom"
 	"github.com/hitecherik/Tabulatron/internal/pundit"
+	"github.com/hitecherik/Tabulatron/internal/scheduler"
 	"github.com/hitecherik/Tabulatron/internal/tabulatron"
+	"github.com/hitecherik/Tabulatron/internal/templates"
+	"github.com/hitecherik/Tabulatron/internal/tracks"
+	"github.com/hitecherik/Tabulatron/internal/util"
 	"github.com/hitecherik/Tabulatron/pkg/tabbycat"
 	"github.com/joho/godotenv"
 )
@@ -24,12 +40,28 @@ func panic(err error) {
 }
 
 type options struct {
-	db              db.Database
-	tabbycatApiKey  string
-	tabbycatUrl     string
-	tabbycatSlug    string
-	botToken        string
-	helperBotTokens []string
+	db               db.Database
+	categories       multiroom.Categories
+	tracks           tracks.Tracks
+	templates        templates.Templates
+	catalogue        locale.Catalogue
+	layout           layout.Layout
+	tabbycatApiKey   string
+	tabbycatUrl      string
+	tabbycatSlug     string
+	concurrency      int
+	rateLimit        float64
+	timeout          time.Duration
+	ballotInterval   time.Duration
+	prepTime         time.Duration
+	infoSlideLead    time.Duration
+	location         *time.Location
+	botToken         string
+	helperBotTokens  []string
+	mailer           *mailer.Mailer
+	applicationId    disgord.Snowflake
+	publicKey        ed25519.PublicKey
+	interactionsAddr string
 }
 
 var opts options
@@ -39,6 +71,11 @@ func init() {
 
 	flag.StringVar(&envFile, "env", ".env", "file to read environment variables from")
 	flag.Var(&opts.db, "db", "SQLite3 database representing the tournament")
+	flag.Var(&opts.categories, "categories", "path to the categories TOML document")
+	flag.Var(&opts.tracks, "tracks", "path to a TOML document giving each release track's motions")
+	flag.Var(&opts.templates, "templates", "path to a TOML document overriding message templates")
+	flag.Var(&opts.catalogue, "locale", "path to a TOML document translating replies")
+	flag.Var(&opts.layout, "layout", "path to a TOML document naming the guild's roles and channels")
 	flag.Parse()
 
 	panic(godotenv.Load(envFile))
@@ -48,6 +85,43 @@ func init() {
 	opts.tabbycatSlug = os.Getenv("TABBYCAT_SLUG")
 	opts.botToken = os.Getenv("DISCORD_BOT_TOKEN")
 
+	if host := os.Getenv("SMTP_HOST"); host != "" {
+		port := os.Getenv("SMTP_PORT")
+		if port == "" {
+			port = "587"
+		}
+
+		opts.mailer = mailer.New(host, port, os.Getenv("SMTP_USERNAME"), os.Getenv("SMTP_PASSWORD"), os.Getenv("SMTP_FROM"))
+	}
+
+	var err error
+	if key := os.Getenv("DISCORD_PUBLIC_KEY"); key != "" {
+		opts.publicKey, err = interactions.ParsePublicKey(key)
+		panic(err)
+		opts.applicationId, err = util.StringToSnowflake(os.Getenv("DISCORD_APPLICATION_ID"))
+		panic(err)
+
+		opts.interactionsAddr = os.Getenv("INTERACTIONS_ADDRESS")
+		if opts.interactionsAddr == "" {
+			opts.interactionsAddr = ":8080"
+		}
+	}
+
+	opts.concurrency, err = util.EnvInt("TABBYCAT_CONCURRENCY", 4)
+	panic(err)
+	opts.rateLimit, err = util.EnvFloat("TABBYCAT_RATE_LIMIT", 0)
+	panic(err)
+	opts.timeout, err = util.EnvDuration("TABBYCAT_TIMEOUT", 30*time.Second)
+	panic(err)
+	opts.ballotInterval, err = util.EnvDuration("BALLOT_CHASE_INTERVAL", 5*time.Minute)
+	panic(err)
+	opts.prepTime, err = util.EnvDuration("PREP_TIME", 15*time.Minute)
+	panic(err)
+	opts.infoSlideLead, err = util.EnvDuration("INFO_SLIDE_LEAD", 5*time.Minute)
+	panic(err)
+	opts.location, err = util.EnvLocation("TOURNAMENT_TIMEZONE")
+	panic(err)
+
 	for i := 1; true; i++ {
 		token := os.Getenv(fmt.Sprintf("DISCORD_HELPER_%v", i))
 
@@ -62,60 +136,168 @@ func init() {
 }
 
 func main() {
-	p := pundit.Pundit{}
+	bots := scheduler.New()
 	for _, token := range opts.helperBotTokens {
 		helperClient := disgord.New(disgord.Config{
 			BotToken: token,
 		})
 		go helperClient.StayConnectedUntilInterrupted(context.Background())
-		p.AddClient(helperClient)
+		bots.AddClient(chat.NewDiscord(helperClient))
 	}
 
 	client := disgord.New(disgord.Config{
 		BotToken: opts.botToken,
 	})
 	defer client.StayConnectedUntilInterrupted(context.Background())
-	p.AddClient(client)
+	// Reactions can go out from the main bot, but bulk DMs are left to the
+	// helpers so that it keeps its rate limit for replying to commands.
+	bots.AddReservedClient(chat.NewDiscord(client))
+
+	p := pundit.New(bots)
+
+	var messenger *hermes.Hermes
+	if len(opts.helperBotTokens) > 0 {
+		messenger = hermes.New(bots)
+		messenger.SetOutbox(&opts.db)
+		messenger.SetLanguages(&opts.db)
 
-	// Make sure all reactions are sent before Tabulatron exits
-	signals := make(chan os.Signal)
+		go messenger.Listen()
+	}
+
+	// Make sure all reactions and reminders are sent before Tabulatron exits
+	signals := make(chan os.Signal, 1)
 	go func() {
 		<-signals
 		p.Wait()
+		if messenger != nil {
+			report := messenger.Wait()
+			if len(report.Failed) > 0 {
+				log.Printf("%v: %v", report.Summary(), strings.Join(hermes.DescribeFailures(&opts.db, report.Failed), "; "))
+			}
+		}
+		log.Print(bots.Metrics())
 		os.Exit(0)
 	}()
 	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
 
 	tabbycat := tabbycat.New(opts.tabbycatApiKey, opts.tabbycatUrl, opts.tabbycatSlug)
-	tron := tabulatron.New(client, &opts.db, tabbycat, &p)
+	tabbycat.SetConcurrency(opts.concurrency)
+	tabbycat.SetRateLimit(opts.rateLimit)
+	tabbycat.SetTimeout(opts.timeout)
+	tabbycat.SetLocation(opts.location)
+	tron := tabulatron.New(chat.NewDiscord(client), &opts.db, tabbycat, p, messenger, bots)
+	tron.SetBallotInterval(opts.ballotInterval)
+	tron.SetPrepTime(opts.prepTime)
+	tron.SetInfoSlideLead(opts.infoSlideLead)
+	tron.SetLocation(opts.location)
+	tron.SetCategories(opts.categories)
+	tron.SetTracks(opts.tracks)
+	tron.SetTemplates(&opts.templates)
+	tron.SetCatalogue(&opts.catalogue)
+	tron.SetMailer(opts.mailer)
+	tron.SetLayout(&opts.layout)
+
+	var slash *interactions.Client
+	if opts.publicKey != nil {
+		slash = interactions.NewClient(opts.botToken, opts.applicationId)
+		tron.SetInteractions(slash)
+	}
+
+	if err := tron.RestoreTimers(); err != nil {
+		log.Printf("error restoring timers: %v", err.Error())
+	}
 
 	me, err := client.Myself(context.Background())
 	panic(err)
 
-	guildId := 0
+	// The handlers run concurrently, so the guild is only read and bound
+	// atomically.
+	var guildId uint64
+	bound := func() disgord.Snowflake {
+		return disgord.Snowflake(atomic.LoadUint64(&guildId))
+	}
+
+	if opts.layout.Guild() != "" {
+		guild, err := util.StringToSnowflake(opts.layout.Guild())
+		panic(err)
+
+		guildId = uint64(guild)
+		checkLayout(tron, guild)
+		go registerCommands(tron, guild)
+	}
 
 	client.On(disgord.EvtMessageCreate, func(s disgord.Session, evt *disgord.MessageCreate) {
 		if me.ID == evt.Message.Author.ID {
 			return
 		}
 
-		if guildId == 0 {
-			guildId = int(evt.Message.GuildID)
+		if evt.Message.GuildID != 0 && atomic.CompareAndSwapUint64(&guildId, 0, uint64(evt.Message.GuildID)) {
 			fmt.Printf("Bound to guild %v\n", evt.Message.GuildID)
+			go checkLayout(tron, evt.Message.GuildID)
+			go registerCommands(tron, evt.Message.GuildID)
 		}
 
-		if guildId != int(evt.Message.GuildID) || guildId == 0 {
+		if guild := bound(); guild != evt.Message.GuildID || guild == 0 {
 			return
 		}
 
-		tron.HandleMessage(s, evt)
+		tron.HandleMessage(evt)
 	})
 
 	client.On(disgord.EvtGuildMemberRemove, func(s disgord.Session, evt *disgord.GuildMemberRemove) {
-		if guildId == 0 || int(evt.GuildID) == 0 || int(evt.GuildID) != guildId {
+		if guild := bound(); guild == 0 || evt.GuildID != guild {
+			return
+		}
+
+		tron.HandleDeparture(evt)
+	})
+
+	client.On(disgord.EvtGuildCreate, func(s disgord.Session, evt *disgord.GuildCreate) {
+		if guild := bound(); guild != 0 && evt.Guild.ID != guild {
 			return
 		}
 
-		tron.HandleDeparture(s, evt)
+		tron.HandleGuild(evt)
 	})
+
+	client.On(disgord.EvtVoiceStateUpdate, func(s disgord.Session, evt *disgord.VoiceStateUpdate) {
+		if guild := bound(); guild != 0 && evt.GuildID != guild {
+			return
+		}
+
+		tron.HandleVoiceState(evt)
+	})
+
+	if slash != nil {
+		endpoint := interactions.NewEndpoint(slash, opts.publicKey, func(interaction *interactions.Interaction) string {
+			if guild := bound(); guild == 0 || interaction.GuildID != guild {
+				return "I'm not running in this server."
+			}
+
+			return tron.HandleInteraction(interaction)
+		})
+
+		go func() {
+			fmt.Printf("Receiving interactions on %v\n", opts.interactionsAddr)
+			panic(http.ListenAndServe(opts.interactionsAddr, endpoint))
+		}()
+	}
+}
+
+func registerCommands(tron *tabulatron.Tabulatron, guild disgord.Snowflake) {
+	if err := tron.RegisterCommands(guild); err != nil {
+		log.Printf("error registering slash commands: %v", err.Error())
+	}
+}
+
+func checkLayout(tron *tabulatron.Tabulatron, guild disgord.Snowflake) {
+	missing, err := tron.CheckLayout(guild)
+	if err != nil {
+		log.Printf("error checking guild layout: %v", err.Error())
+		return
+	}
+
+	for _, thing := range missing {
+		log.Printf("guild %v is missing the %v", guild, thing)
+	}
 }
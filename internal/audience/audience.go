@@ -0,0 +1,286 @@
+package audience
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/hitecherik/Tabulatron/internal/db"
+	"github.com/hitecherik/Tabulatron/pkg/tabbycat"
+)
+
+// Names collects a flag that can be given more than once.
+type Names []string
+
+func (ns *Names) String() string {
+	return strings.Join(*ns, ", ")
+}
+
+func (ns *Names) Set(name string) error {
+	*ns = append(*ns, name)
+	return nil
+}
+
+// Filter narrows down who an announcement goes to. A participant has to match
+// every criterion that's set, and leaving both Speakers and Adjudicators unset
+// includes everyone. Registered participants are messaged on Discord, unless
+// Unregistered is set, in which case only the people who haven't registered
+// are selected so they can be emailed instead.
+type Filter struct {
+	Speakers     bool
+	Adjudicators bool
+	Teams        Names
+	Institutions Names
+	NotCheckedIn bool
+	Unregistered bool
+}
+
+var token *regexp.Regexp = regexp.MustCompile(`[^\s:]+:"[^"]*"|\S+`)
+
+// Parse reads filters written like
+//
+//	speakers team:"Imperial A" institution:LSE notcheckedin
+func Parse(text string) (Filter, error) {
+	var f Filter
+
+	for _, word := range token.FindAllString(text, -1) {
+		key, value := word, ""
+		if i := strings.Index(word, ":"); i >= 0 {
+			key, value = word[:i], strings.Trim(word[i+1:], `"`)
+		}
+
+		key = strings.ToLower(key)
+		if value == "" && (key == "team" || key == "institution") {
+			return Filter{}, fmt.Errorf("filter \"%v\" needs a name", word)
+		}
+
+		switch key {
+		case "everyone":
+		case "speakers":
+			f.Speakers = true
+		case "adjudicators", "judges":
+			f.Adjudicators = true
+		case "team":
+			f.Teams = append(f.Teams, value)
+		case "institution":
+			f.Institutions = append(f.Institutions, value)
+		case "notcheckedin":
+			f.NotCheckedIn = true
+		case "unregistered":
+			f.Unregistered = true
+		default:
+			return Filter{}, fmt.Errorf("unknown filter \"%v\"", word)
+		}
+	}
+
+	return f, nil
+}
+
+func (f Filter) String() string {
+	who := "participants"
+	if f.Speakers && !f.Adjudicators {
+		who = "speakers"
+	} else if f.Adjudicators && !f.Speakers {
+		who = "adjudicators"
+	}
+
+	parts := []string{"registered", who}
+	if f.Unregistered {
+		parts[0] = "unregistered"
+	}
+
+	if len(f.Teams) > 0 {
+		parts = append(parts, fmt.Sprintf("in %v", strings.Join(f.Teams, " or ")))
+	}
+	if len(f.Institutions) > 0 {
+		parts = append(parts, fmt.Sprintf("from %v", strings.Join(f.Institutions, " or ")))
+	}
+	if f.NotCheckedIn {
+		parts = append(parts, "who aren't checked in")
+	}
+	return strings.Join(parts, " ")
+}
+
+func (f Filter) includeSpeakers() bool {
+	return f.Speakers || !f.Adjudicators
+}
+
+func (f Filter) includeAdjudicators() bool {
+	return (f.Adjudicators || !f.Speakers) && len(f.Teams) == 0
+}
+
+// Select only asks Tabbycat for what the filter needs. Check-ins take a request
+// per participant, so they're only fetched to find who isn't checked in, and
+// anyone whose check-in couldn't be fetched is left out and listed in a
+// *tabbycat.BarcodeError returned alongside everyone else.
+func Select(ctx context.Context, t *tabbycat.Tabbycat, database *db.Database, f Filter) ([]db.Participant, error) {
+	participants, err := database.AllParticipants()
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		teams        map[uint]tabbycat.Team
+		adjudicators map[uint]tabbycat.Participant
+		institutions map[string]bool
+		failures     []tabbycat.BarcodeFailure
+		unknown      = map[bool]map[uint]bool{true: {}, false: {}}
+	)
+
+	if len(f.Institutions) > 0 {
+		if institutions, err = matchingInstitutions(ctx, t, f.Institutions); err != nil {
+			return nil, err
+		}
+	}
+
+	if f.includeSpeakers() && (len(f.Teams) > 0 || len(f.Institutions) > 0 || f.NotCheckedIn) {
+		list := t.ListTeamsContext
+		if f.NotCheckedIn {
+			list = t.GetTeamsContext
+		}
+
+		all, err := list(ctx)
+		if !collectFailures(err, &failures, unknown[true]) {
+			return nil, err
+		}
+
+		teams = make(map[uint]tabbycat.Team)
+		for _, team := range all {
+			for _, speaker := range team.Speakers {
+				teams[speaker.Id] = team
+			}
+		}
+	}
+
+	if f.includeAdjudicators() && (len(f.Institutions) > 0 || f.NotCheckedIn) {
+		list := t.ListAdjudicatorsContext
+		if f.NotCheckedIn {
+			list = t.GetAdjudicatorsContext
+		}
+
+		all, err := list(ctx)
+		if !collectFailures(err, &failures, unknown[false]) {
+			return nil, err
+		}
+
+		adjudicators = make(map[uint]tabbycat.Participant, len(all))
+		for _, adjudicator := range all {
+			adjudicators[adjudicator.Id] = adjudicator
+		}
+	}
+
+	selected := make([]db.Participant, 0)
+	for _, participant := range participants {
+		if f.Unregistered != (participant.Discord == "") {
+			continue
+		}
+
+		if participant.Speaker && !f.includeSpeakers() || !participant.Speaker && !f.includeAdjudicators() {
+			continue
+		}
+
+		if unknown[participant.Speaker][participant.Id] {
+			continue
+		}
+
+		var (
+			institution string
+			checkedIn   bool
+		)
+
+		if participant.Speaker && teams != nil {
+			team, ok := teams[participant.Id]
+			if !ok || len(f.Teams) > 0 && !matchesTeam(team, f.Teams) {
+				continue
+			}
+
+			institution = team.Institution
+			for _, speaker := range team.Speakers {
+				if speaker.Id == participant.Id {
+					checkedIn = speaker.CheckedIn
+				}
+			}
+		} else if !participant.Speaker && adjudicators != nil {
+			adjudicator, ok := adjudicators[participant.Id]
+			if !ok {
+				continue
+			}
+
+			institution = adjudicator.Institution
+			checkedIn = adjudicator.CheckedIn
+		}
+
+		if institutions != nil && !institutions[institution] {
+			continue
+		}
+
+		if f.NotCheckedIn && checkedIn {
+			continue
+		}
+
+		selected = append(selected, participant)
+	}
+
+	if len(failures) > 0 {
+		return selected, &tabbycat.BarcodeError{Failures: failures}
+	}
+
+	return selected, nil
+}
+
+// collectFailures adds the people a partial *tabbycat.BarcodeError is about to
+// failures and ids, and reports whether err was nothing worse than that.
+func collectFailures(err error, failures *[]tabbycat.BarcodeFailure, ids map[uint]bool) bool {
+	if err == nil {
+		return true
+	}
+
+	var barcodeErr *tabbycat.BarcodeError
+	if !errors.As(err, &barcodeErr) {
+		return false
+	}
+
+	for _, failure := range barcodeErr.Failures {
+		*failures = append(*failures, failure)
+		ids[failure.Participant.Id] = true
+	}
+
+	return true
+}
+
+func matchesTeam(team tabbycat.Team, names []string) bool {
+	for _, name := range names {
+		if strings.EqualFold(name, team.Reference) || strings.EqualFold(name, team.ShortName) || name == strconv.FormatUint(uint64(team.Id), 10) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func matchingInstitutions(ctx context.Context, t *tabbycat.Tabbycat, names []string) (map[string]bool, error) {
+	all, err := t.GetInstitutionsContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	urls := make(map[string]bool)
+	for _, name := range names {
+		found := false
+		for _, institution := range all {
+			if strings.EqualFold(name, institution.Name) || strings.EqualFold(name, institution.Code) {
+				urls[institution.Url] = true
+				found = true
+			}
+		}
+
+		if !found {
+			return nil, fmt.Errorf("no institution called \"%v\"", name)
+		}
+	}
+
+	return urls, nil
+}
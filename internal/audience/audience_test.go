@@ -0,0 +1,95 @@
+package audience
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/hitecherik/Tabulatron/pkg/tabbycat"
+	"github.com/hitecherik/Tabulatron/pkg/tabbycat/tabbycattest"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		text  string
+		want  Filter
+		fails bool
+	}{
+		{"everyone", Filter{}, false},
+		{`speakers team:"Team 1" notcheckedin`, Filter{Speakers: true, Teams: Names{"Team 1"}, NotCheckedIn: true}, false},
+		{"judges institution:LSE unregistered", Filter{Adjudicators: true, Institutions: Names{"LSE"}, Unregistered: true}, false},
+		{"team:", Filter{}, true},
+		{"novices", Filter{}, true},
+	}
+
+	for _, test := range tests {
+		got, err := Parse(test.text)
+		if (err != nil) != test.fails || !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Parse(%q) = %+v, %v", test.text, got, err)
+		}
+	}
+}
+
+func TestSelect(t *testing.T) {
+	client, fake, database := tabbycattest.NewTournament(t)
+
+	teams, err := client.GetTeams()
+	if err != nil {
+		t.Fatalf("GetTeams: %v", err)
+	}
+
+	for _, team := range teams {
+		for _, speaker := range team.Speakers {
+			if _, _, _, err := database.ParticipantFromBarcode(speaker.Barcode, fmt.Sprint(1000+speaker.Id)); err != nil {
+				t.Fatalf("registering %v: %v", speaker.Name, err)
+			}
+		}
+	}
+
+	// Only speaker 1 is checked in, and speaker 2's check-in can't be fetched.
+	fake.Fail(http.MethodGet, "speakers/2/checkin", tabbycattest.Failure{Status: http.StatusNotFound, Body: `{"detail":"Not found."}`})
+
+	tests := []struct {
+		name    string
+		filter  Filter
+		want    []string
+		unknown []string
+	}{
+		{"team without check-ins", Filter{Teams: Names{"Team 1"}}, []string{"Ada Lovelace", "Alan Turing"}, nil},
+		{
+			"not checked in",
+			Filter{Speakers: true, NotCheckedIn: true},
+			[]string{"Barbara Liskov", "Dennis Ritchie", "Donald Knuth", "Edsger Dijkstra", "Grace Hopper", "Margaret Hamilton"},
+			[]string{"Alan Turing"},
+		},
+	}
+
+	for _, test := range tests {
+		selected, err := Select(context.Background(), client, database, test.filter)
+
+		var unknown []string
+		var barcodeErr *tabbycat.BarcodeError
+		if errors.As(err, &barcodeErr) {
+			for _, failure := range barcodeErr.Failures {
+				unknown = append(unknown, failure.Participant.Name)
+			}
+		} else if err != nil {
+			t.Errorf("%v: %v", test.name, err)
+			continue
+		}
+
+		names := make([]string, 0, len(selected))
+		for _, participant := range selected {
+			names = append(names, participant.Name)
+		}
+		sort.Strings(names)
+
+		if !reflect.DeepEqual(names, test.want) || !reflect.DeepEqual(unknown, test.unknown) {
+			t.Errorf("%v: got %v and unknown %v, want %v and unknown %v", test.name, names, unknown, test.want, test.unknown)
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package ballotchaser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/hitecherik/Tabulatron/internal/db"
+	"github.com/hitecherik/Tabulatron/internal/hermes"
+	"github.com/hitecherik/Tabulatron/internal/roundrunner"
+	"github.com/hitecherik/Tabulatron/internal/templates"
+	"github.com/hitecherik/Tabulatron/internal/util"
+	"github.com/hitecherik/Tabulatron/pkg/tabbycat"
+)
+
+type Chaser struct {
+	tabbycat    *tabbycat.Tabbycat
+	database    *db.Database
+	messenger   *hermes.Hermes
+	interval    time.Duration
+	templates   *templates.Templates
+	location    *time.Location
+	mutex       sync.Mutex
+	unreachable map[string]bool
+}
+
+type outstanding struct {
+	room        tabbycat.Room
+	venue       string
+	chair       string
+	submitted   bool
+	messaged    bool
+	unreachable bool
+}
+
+func New(t *tabbycat.Tabbycat, database *db.Database, messenger *hermes.Hermes, interval time.Duration) *Chaser {
+	return &Chaser{
+		tabbycat:    t,
+		database:    database,
+		messenger:   messenger,
+		interval:    interval,
+		templates:   &templates.Templates{},
+		location:    time.Local,
+		unreachable: make(map[string]bool),
+	}
+}
+
+func (c *Chaser) SetTemplates(t *templates.Templates) {
+	c.templates = t
+}
+
+// SetLocation sets the timezone that the next reminder's time is shown in.
+func (c *Chaser) SetLocation(location *time.Location) {
+	c.location = location
+}
+
+// Chase reminds chairs about their ballots every interval until they're all
+// in, reporting the outstanding rooms as pages of a summary each time. Errors
+// that might go away, like timeouts, are only logged and retried at the next
+// interval.
+func (c *Chaser) Chase(ctx context.Context, round uint64, report func(pages []string)) error {
+	details, err := c.tabbycat.GetRoundContext(ctx, round)
+	if err != nil {
+		return err
+	}
+
+	venues, err := c.tabbycat.GetVenuesContext(ctx)
+	if err != nil {
+		return err
+	}
+	venueMap := roundrunner.BuildVenueMap(venues)
+
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	var rooms []outstanding
+	for {
+		latest, err := c.findOutstanding(ctx, round, venueMap)
+
+		switch {
+		case ctx.Err() != nil:
+			// the select below reports that chasing has stopped
+		case errors.Is(err, tabbycat.ErrNotFound) || errors.Is(err, tabbycat.ErrUnauthorised):
+			return err
+		case err != nil:
+			log.Printf("error checking ballots for round %v, trying again in %v: %v", round, c.interval, err.Error())
+		case len(latest) == 0:
+			report([]string{fmt.Sprintf("All ballots for **%v** are in.", details.Name)})
+			return nil
+		default:
+			rooms = latest
+			for i := range rooms {
+				if !rooms[i].submitted {
+					rooms[i].messaged = c.remind(details.Name, rooms[i])
+				}
+
+				c.mutex.Lock()
+				rooms[i].unreachable = c.unreachable[rooms[i].room.ChairId]
+				c.mutex.Unlock()
+			}
+
+			report(c.summarise(details.Name, rooms, time.Now().Add(c.interval)))
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			stopped := fmt.Sprintf("Stopped chasing ballots for **%v**.", details.Name)
+			if rooms == nil {
+				report([]string{stopped})
+			} else {
+				report(append([]string{stopped}, c.summarise(details.Name, rooms, time.Time{})...))
+			}
+
+			return ctx.Err()
+		}
+	}
+}
+
+func (c *Chaser) findOutstanding(ctx context.Context, round uint64, venueMap map[string]string) ([]outstanding, error) {
+	rooms, err := c.tabbycat.GetDrawContext(ctx, round)
+	if err != nil {
+		return nil, err
+	}
+
+	chairIds := make([]string, 0, len(rooms))
+	for _, room := range rooms {
+		chairIds = append(chairIds, room.ChairId)
+	}
+
+	names, err := c.database.ParticipantNames(chairIds)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]outstanding, 0)
+	for _, room := range rooms {
+		ballots, err := c.tabbycat.GetBallotsContext(ctx, round, room.Id)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, ok := tabbycat.ConfirmedBallot(ballots); ok {
+			continue
+		}
+
+		submitted := false
+		for _, ballot := range ballots {
+			submitted = submitted || !ballot.Discarded
+		}
+
+		chair := names[room.ChairId]
+		if chair == "" {
+			chair = fmt.Sprintf("adjudicator %v", room.ChairId)
+		}
+
+		result = append(result, outstanding{room, venueMap[room.VenueId], chair, submitted, false, false})
+	}
+
+	return result, nil
+}
+
+func (c *Chaser) remind(roundName string, room outstanding) bool {
+	discords, urlKeys, err := c.database.DiscordFromParticipantIds([]string{room.room.ChairId})
+	if err != nil {
+		log.Printf("error finding chair %v: %v", room.room.ChairId, err.Error())
+		return false
+	}
+
+	if len(discords) == 0 || discords[0] == "" {
+		return false
+	}
+
+	snowflake, err := util.StringToSnowflake(discords[0])
+	if err != nil {
+		log.Printf("error converting to snowflake: %v", err.Error())
+		return false
+	}
+
+	reminder := templates.Ballot{Round: roundName, Venue: room.venue}
+	if urlKeys[0] != "" {
+		reminder.PrivateUrl = c.tabbycat.PrivateUrlFromKey(urlKeys[0])
+	}
+	message := func(language string) string {
+		return c.templates.RenderIn(language, "ballot.reminder", reminder)
+	}
+
+	chairId := room.room.ChairId
+	c.messenger.SendLocalised(snowflake, message, func(delivery hermes.Delivery) {
+		c.mutex.Lock()
+		c.unreachable[chairId] = !delivery.Sent
+		c.mutex.Unlock()
+	})
+
+	return true
+}
+
+func (c *Chaser) summarise(roundName string, rooms []outstanding, next time.Time) []string {
+	header := fmt.Sprintf("**Outstanding ballots for %v** (%v rooms", roundName, len(rooms))
+	if !next.IsZero() {
+		header += fmt.Sprintf(", next reminder at %v", next.In(c.location).Format("15:04 MST"))
+	}
+	header += ")"
+
+	lines := make([]string, 0, len(rooms))
+	for _, room := range rooms {
+		status := "no ballot submitted"
+		if room.submitted {
+			status = "awaiting confirmation"
+		} else if !room.messaged {
+			status = "no ballot submitted, chair not on Discord"
+		} else if room.unreachable {
+			status = "no ballot submitted, chair can't be messaged"
+		}
+
+		lines = append(lines, fmt.Sprintf("• %v – %v – %v", room.venue, room.chair, status))
+	}
+
+	return util.PaginateLines(header, lines, util.MessageLimit)
+}
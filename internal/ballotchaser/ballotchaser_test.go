@@ -0,0 +1,73 @@
+package ballotchaser
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/hitecherik/Tabulatron/internal/chat/simguild"
+	"github.com/hitecherik/Tabulatron/internal/util"
+	"github.com/hitecherik/Tabulatron/pkg/tabbycat"
+)
+
+func TestSummariseSplitsLargeRounds(t *testing.T) {
+	location, err := time.LoadLocation("Asia/Kolkata")
+	if err != nil {
+		t.Skipf("no timezone data: %v", err)
+	}
+
+	c := &Chaser{location: location}
+	rooms := make([]outstanding, 0, 100)
+	for i := 0; i < 100; i++ {
+		rooms = append(rooms, outstanding{
+			room:  tabbycat.Room{Id: fmt.Sprint(i)},
+			venue: fmt.Sprintf("Room %v", i),
+			chair: fmt.Sprintf("Chair with a fairly long name %v", i),
+		})
+	}
+
+	next := time.Date(2020, 11, 7, 10, 0, 0, 0, time.UTC)
+	pages := c.summarise("Round 1", rooms, next)
+
+	if len(pages) < 2 {
+		t.Fatalf("got %v pages for 100 rooms, want them split", len(pages))
+	}
+
+	listed := 0
+	for _, page := range pages {
+		if utf8.RuneCountInString(page) > util.MessageLimit {
+			t.Errorf("got a page of %v characters", utf8.RuneCountInString(page))
+		}
+
+		if !strings.Contains(page, "next reminder at 15:30 IST") {
+			t.Errorf("page %q doesn't give the next reminder in the tournament's timezone", page[:80])
+		}
+
+		listed += strings.Count(page, "Chair with a fairly long name")
+	}
+
+	if listed != len(rooms) {
+		t.Errorf("listed %v rooms, want %v", listed, len(rooms))
+	}
+}
+
+func TestBoardEditsAndTrimsPages(t *testing.T) {
+	guild := simguild.New()
+	channel := guild.AddChannel("tab")
+	board := NewBoard(guild, channel.ID)
+
+	if err := board.Show([]string{"one", "two", "three"}); err != nil {
+		t.Fatalf("Show: %v", err)
+	}
+
+	if err := board.Show([]string{"uno", "dos"}); err != nil {
+		t.Fatalf("Show: %v", err)
+	}
+
+	messages := guild.Messages(channel)
+	if len(messages) != 2 || messages[0].Content != "uno" || messages[1].Content != "dos" {
+		t.Errorf("got messages %v, want the first two pages edited and the third deleted", messages)
+	}
+}
@@ -0,0 +1,55 @@
+package ballotchaser
+
+import (
+	"context"
+
+	"github.com/andersfylling/disgord"
+	"github.com/hitecherik/Tabulatron/internal/chat"
+)
+
+// Board keeps a summary up to date in one channel, editing the pages it has
+// already posted and posting or deleting pages as the summary grows or shrinks.
+type Board struct {
+	platform chat.Platform
+	channel  disgord.Snowflake
+	pages    []*disgord.Message
+}
+
+func NewBoard(platform chat.Platform, channel disgord.Snowflake) *Board {
+	return &Board{
+		platform: platform,
+		channel:  channel,
+	}
+}
+
+func (b *Board) Show(pages []string) error {
+	for i, content := range pages {
+		if i < len(b.pages) {
+			edited, err := b.platform.EditMessage(context.Background(), b.channel, b.pages[i].ID, content)
+			if err != nil {
+				return err
+			}
+
+			b.pages[i] = edited
+			continue
+		}
+
+		sent, err := b.platform.SendMessage(context.Background(), b.channel, content)
+		if err != nil {
+			return err
+		}
+
+		b.pages = append(b.pages, sent)
+	}
+
+	for len(b.pages) > len(pages) {
+		last := b.pages[len(b.pages)-1]
+		if err := b.platform.DeleteMessage(context.Background(), b.channel, last.ID); err != nil {
+			return err
+		}
+
+		b.pages = b.pages[:len(b.pages)-1]
+	}
+
+	return nil
+}
@@ -0,0 +1,23 @@
+package chat
+
+import (
+	"context"
+
+	"github.com/andersfylling/disgord"
+)
+
+type Platform interface {
+	GetGuildChannels(ctx context.Context, guildId disgord.Snowflake) ([]*disgord.Channel, error)
+	GetGuildRoles(ctx context.Context, guildId disgord.Snowflake) ([]*disgord.Role, error)
+	UpdateMember(ctx context.Context, guildId, userId disgord.Snowflake, nick string, roles []disgord.Snowflake) error
+	MoveMember(ctx context.Context, guildId, userId, channelId disgord.Snowflake) error
+	SendMessage(ctx context.Context, channelId disgord.Snowflake, content string) (*disgord.Message, error)
+	EditMessage(ctx context.Context, channelId, messageId disgord.Snowflake, content string) (*disgord.Message, error)
+	DeleteMessage(ctx context.Context, channelId, messageId disgord.Snowflake) error
+	CreateDM(ctx context.Context, userId disgord.Snowflake) (*disgord.Channel, error)
+	CreateReaction(ctx context.Context, channelId, messageId disgord.Snowflake, emoji string) error
+	CreateRole(ctx context.Context, guildId disgord.Snowflake, name string) (*disgord.Role, error)
+	CreateChannel(ctx context.Context, guildId disgord.Snowflake, params *disgord.CreateGuildChannelParams) (*disgord.Channel, error)
+	SetPermissions(ctx context.Context, channelId disgord.Snowflake, overwrite disgord.PermissionOverwrite) error
+	DeletePermissions(ctx context.Context, channelId, overwriteId disgord.Snowflake) error
+}
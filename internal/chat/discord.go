@@ -0,0 +1,79 @@
+package chat
+
+import (
+	"context"
+
+	"github.com/andersfylling/disgord"
+)
+
+type Discord struct {
+	client *disgord.Client
+}
+
+func NewDiscord(client *disgord.Client) *Discord {
+	return &Discord{client}
+}
+
+func (d *Discord) GetGuildChannels(ctx context.Context, guildId disgord.Snowflake) ([]*disgord.Channel, error) {
+	return d.client.GetGuildChannels(ctx, guildId)
+}
+
+func (d *Discord) GetGuildRoles(ctx context.Context, guildId disgord.Snowflake) ([]*disgord.Role, error) {
+	return d.client.GetGuildRoles(ctx, guildId)
+}
+
+func (d *Discord) UpdateMember(ctx context.Context, guildId, userId disgord.Snowflake, nick string, roles []disgord.Snowflake) error {
+	builder := d.client.UpdateGuildMember(ctx, guildId, userId)
+
+	if nick == "" {
+		builder = builder.DeleteNick()
+	} else {
+		builder = builder.SetNick(nick)
+	}
+
+	return builder.SetRoles(roles).Execute()
+}
+
+func (d *Discord) MoveMember(ctx context.Context, guildId, userId, channelId disgord.Snowflake) error {
+	return d.client.UpdateGuildMember(ctx, guildId, userId).SetChannelID(channelId).Execute()
+}
+
+func (d *Discord) SendMessage(ctx context.Context, channelId disgord.Snowflake, content string) (*disgord.Message, error) {
+	return d.client.SendMsg(ctx, channelId, content)
+}
+
+func (d *Discord) EditMessage(ctx context.Context, channelId, messageId disgord.Snowflake, content string) (*disgord.Message, error) {
+	return d.client.UpdateMessage(ctx, channelId, messageId).SetContent(content).Execute()
+}
+
+func (d *Discord) DeleteMessage(ctx context.Context, channelId, messageId disgord.Snowflake) error {
+	return d.client.DeleteMessage(ctx, channelId, messageId)
+}
+
+func (d *Discord) CreateDM(ctx context.Context, userId disgord.Snowflake) (*disgord.Channel, error) {
+	return d.client.CreateDM(ctx, userId)
+}
+
+func (d *Discord) CreateReaction(ctx context.Context, channelId, messageId disgord.Snowflake, emoji string) error {
+	return d.client.CreateReaction(ctx, channelId, messageId, emoji)
+}
+
+func (d *Discord) CreateRole(ctx context.Context, guildId disgord.Snowflake, name string) (*disgord.Role, error) {
+	return d.client.CreateGuildRole(ctx, guildId, &disgord.CreateGuildRoleParams{Name: name})
+}
+
+func (d *Discord) CreateChannel(ctx context.Context, guildId disgord.Snowflake, params *disgord.CreateGuildChannelParams) (*disgord.Channel, error) {
+	return d.client.CreateGuildChannel(ctx, guildId, params.Name, params)
+}
+
+func (d *Discord) SetPermissions(ctx context.Context, channelId disgord.Snowflake, overwrite disgord.PermissionOverwrite) error {
+	return d.client.UpdateChannelPermissions(ctx, channelId, overwrite.ID, &disgord.UpdateChannelPermissionsParams{
+		Allow: overwrite.Allow,
+		Deny:  overwrite.Deny,
+		Type:  overwrite.Type,
+	})
+}
+
+func (d *Discord) DeletePermissions(ctx context.Context, channelId, overwriteId disgord.Snowflake) error {
+	return d.client.DeleteChannelPermission(ctx, channelId, overwriteId)
+}
@@ -0,0 +1,42 @@
+package chat
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/andersfylling/disgord"
+)
+
+var ErrForbidden = errors.New("forbidden")
+
+// Discord's codes for things that don't exist.
+const (
+	UnknownChannel   int = 10003
+	UnknownOverwrite int = 10009
+)
+
+// IsPermanent reports whether retrying a failed request can't succeed, e.g.
+// because the recipient has closed their DMs.
+func IsPermanent(err error) bool {
+	if errors.Is(err, ErrForbidden) {
+		return true
+	}
+
+	var rest *disgord.ErrRest
+	if errors.As(err, &rest) {
+		return rest.HTTPCode >= 400 && rest.HTTPCode < 500 && rest.HTTPCode != http.StatusTooManyRequests
+	}
+
+	return false
+}
+
+// IsUnknownOverwrite reports whether Discord couldn't find a permission
+// overwrite, because either it or its channel has been deleted.
+func IsUnknownOverwrite(err error) bool {
+	var rest *disgord.ErrRest
+	if !errors.As(err, &rest) || rest.HTTPCode != http.StatusNotFound {
+		return false
+	}
+
+	return rest.Code == UnknownOverwrite || rest.Code == UnknownChannel
+}
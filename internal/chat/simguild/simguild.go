@@ -0,0 +1,516 @@
+package simguild
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/andersfylling/disgord"
+	"github.com/hitecherik/Tabulatron/internal/chat"
+)
+
+// contentLimit is the most characters Discord accepts in a message.
+const contentLimit int = 2000
+
+var mention *regexp.Regexp = regexp.MustCompile(`<@!?(\d+)>`)
+
+var _ chat.Platform = (*Guild)(nil)
+
+type Guild struct {
+	ID        disgord.Snowflake
+	Bot       *disgord.User
+	mutex     sync.Mutex
+	counter   uint64
+	channels  []*disgord.Channel
+	roles     []*disgord.Role
+	members   map[disgord.Snowflake]*disgord.Member
+	users     map[disgord.Snowflake]*disgord.User
+	dms       map[disgord.Snowflake]*disgord.Channel
+	blocked   map[disgord.Snowflake]bool
+	locked    map[disgord.Snowflake]bool
+	messages  map[disgord.Snowflake][]*disgord.Message
+	reactions map[disgord.Snowflake][]string
+	voice     map[disgord.Snowflake]disgord.Snowflake
+}
+
+func New() *Guild {
+	g := &Guild{
+		members:   make(map[disgord.Snowflake]*disgord.Member),
+		users:     make(map[disgord.Snowflake]*disgord.User),
+		dms:       make(map[disgord.Snowflake]*disgord.Channel),
+		blocked:   make(map[disgord.Snowflake]bool),
+		locked:    make(map[disgord.Snowflake]bool),
+		messages:  make(map[disgord.Snowflake][]*disgord.Message),
+		reactions: make(map[disgord.Snowflake][]string),
+		voice:     make(map[disgord.Snowflake]disgord.Snowflake),
+	}
+
+	g.ID = g.nextSnowflake()
+	g.Bot = &disgord.User{ID: g.nextSnowflake(), Username: "Tabulatron", Bot: true}
+	g.users[g.Bot.ID] = g.Bot
+
+	return g
+}
+
+func (g *Guild) AddChannel(name string) *disgord.Channel {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	channel := &disgord.Channel{
+		ID:      g.nextSnowflake(),
+		GuildID: g.ID,
+		Name:    name,
+		Type:    disgord.ChannelTypeGuildText,
+	}
+	g.channels = append(g.channels, channel)
+
+	return channel
+}
+
+func (g *Guild) AddVoiceChannel(name string) *disgord.Channel {
+	channel := g.AddChannel(name)
+	channel.Type = disgord.ChannelTypeGuildVoice
+
+	return channel
+}
+
+func (g *Guild) AddRole(name string) *disgord.Role {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	role := &disgord.Role{ID: g.nextSnowflake(), Name: name}
+	g.roles = append(g.roles, role)
+
+	return role
+}
+
+func (g *Guild) AddMember(username string, roles ...*disgord.Role) *disgord.Member {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	user := &disgord.User{ID: g.nextSnowflake(), Username: username}
+	member := &disgord.Member{
+		GuildID: g.ID,
+		User:    user,
+		UserID:  user.ID,
+		Roles:   make([]disgord.Snowflake, 0, len(roles)),
+	}
+
+	for _, role := range roles {
+		member.Roles = append(member.Roles, role.ID)
+	}
+
+	g.users[user.ID] = user
+	g.members[user.ID] = member
+
+	return member
+}
+
+func (g *Guild) RemoveMember(member *disgord.Member) *disgord.GuildMemberRemove {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	delete(g.members, member.User.ID)
+
+	return &disgord.GuildMemberRemove{GuildID: g.ID, User: member.User}
+}
+
+func (g *Guild) Member(userId disgord.Snowflake) *disgord.Member {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	member, ok := g.members[userId]
+	if !ok {
+		return nil
+	}
+
+	copied := *member
+	copied.Roles = append([]disgord.Snowflake{}, member.Roles...)
+
+	return &copied
+}
+
+func (g *Guild) BlockDMs(member *disgord.Member) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	g.blocked[member.User.ID] = true
+}
+
+// LockChannel takes away the bot's permission to change who can see channel.
+func (g *Guild) LockChannel(channel *disgord.Channel) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	g.locked[channel.ID] = true
+}
+
+func (g *Guild) Post(channel *disgord.Channel, member *disgord.Member, content string) *disgord.MessageCreate {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	message := g.newMessage(channel.ID, member.User, content)
+	message.Member = g.members[member.User.ID]
+
+	for _, match := range mention.FindAllStringSubmatch(content, -1) {
+		var id disgord.Snowflake
+		if _, err := fmt.Sscan(match[1], &id); err != nil {
+			continue
+		}
+
+		if user, ok := g.users[id]; ok {
+			message.Mentions = append(message.Mentions, user)
+		}
+	}
+
+	return &disgord.MessageCreate{Message: message, Ctx: context.Background()}
+}
+
+func (g *Guild) Join(member *disgord.Member) *disgord.MessageCreate {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	var channelId disgord.Snowflake
+	if len(g.channels) > 0 {
+		channelId = g.channels[0].ID
+	}
+
+	message := g.newMessage(channelId, member.User, "")
+	message.Member = g.members[member.User.ID]
+	message.Type = disgord.MessageTypeGuildMemberJoin
+
+	return &disgord.MessageCreate{Message: message, Ctx: context.Background()}
+}
+
+func (g *Guild) JoinVoice(member *disgord.Member, channel *disgord.Channel) *disgord.VoiceStateUpdate {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	g.voice[member.User.ID] = channel.ID
+
+	return &disgord.VoiceStateUpdate{VoiceState: &disgord.VoiceState{GuildID: g.ID, ChannelID: channel.ID, UserID: member.User.ID}}
+}
+
+// VoiceChannel is zero if the member isn't in a voice channel.
+func (g *Guild) VoiceChannel(member *disgord.Member) disgord.Snowflake {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	return g.voice[member.User.ID]
+}
+
+func (g *Guild) Messages(channel *disgord.Channel) []*disgord.Message {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	return append([]*disgord.Message{}, g.messages[channel.ID]...)
+}
+
+func (g *Guild) DMs(member *disgord.Member) []*disgord.Message {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	channel, ok := g.dms[member.User.ID]
+	if !ok {
+		return []*disgord.Message{}
+	}
+
+	return append([]*disgord.Message{}, g.messages[channel.ID]...)
+}
+
+func (g *Guild) Reactions(message *disgord.Message) []string {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	return append([]string{}, g.reactions[message.ID]...)
+}
+
+func (g *Guild) GetGuildChannels(_ context.Context, guildId disgord.Snowflake) ([]*disgord.Channel, error) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	if guildId != g.ID {
+		return nil, fmt.Errorf("unknown guild %v", guildId)
+	}
+
+	return append([]*disgord.Channel{}, g.channels...), nil
+}
+
+func (g *Guild) GetGuildRoles(_ context.Context, guildId disgord.Snowflake) ([]*disgord.Role, error) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	if guildId != g.ID {
+		return nil, fmt.Errorf("unknown guild %v", guildId)
+	}
+
+	return append([]*disgord.Role{}, g.roles...), nil
+}
+
+func (g *Guild) UpdateMember(_ context.Context, guildId, userId disgord.Snowflake, nick string, roles []disgord.Snowflake) error {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	member, ok := g.members[userId]
+	if guildId != g.ID || !ok {
+		return fmt.Errorf("unknown member %v", userId)
+	}
+
+	member.Nick = nick
+	member.Roles = append([]disgord.Snowflake{}, roles...)
+
+	return nil
+}
+
+func (g *Guild) MoveMember(_ context.Context, guildId, userId, channelId disgord.Snowflake) error {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	if _, ok := g.voice[userId]; guildId != g.ID || !ok {
+		return fmt.Errorf("member %v isn't in a voice channel", userId)
+	}
+
+	if !g.channelExists(channelId) {
+		return fmt.Errorf("unknown channel %v", channelId)
+	}
+
+	g.voice[userId] = channelId
+
+	return nil
+}
+
+func (g *Guild) SendMessage(_ context.Context, channelId disgord.Snowflake, content string) (*disgord.Message, error) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	if !g.channelExists(channelId) {
+		return nil, fmt.Errorf("unknown channel %v", channelId)
+	}
+
+	if err := checkContent(content); err != nil {
+		return nil, err
+	}
+
+	for userId, channel := range g.dms {
+		if channel.ID == channelId && g.blocked[userId] {
+			return nil, fmt.Errorf("cannot send messages to user %v: %w", userId, chat.ErrForbidden)
+		}
+	}
+
+	return g.newMessage(channelId, g.Bot, content), nil
+}
+
+func (g *Guild) EditMessage(_ context.Context, channelId, messageId disgord.Snowflake, content string) (*disgord.Message, error) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	if err := checkContent(content); err != nil {
+		return nil, err
+	}
+
+	for _, message := range g.messages[channelId] {
+		if message.ID == messageId {
+			message.Content = content
+			return message, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unknown message %v", messageId)
+}
+
+func (g *Guild) DeleteMessage(_ context.Context, channelId, messageId disgord.Snowflake) error {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	messages := g.messages[channelId]
+	for i, message := range messages {
+		if message.ID == messageId {
+			g.messages[channelId] = append(messages[:i:i], messages[i+1:]...)
+			return nil
+		}
+	}
+
+	return fmt.Errorf("unknown message %v", messageId)
+}
+
+func (g *Guild) CreateDM(_ context.Context, userId disgord.Snowflake) (*disgord.Channel, error) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	if channel, ok := g.dms[userId]; ok {
+		return channel, nil
+	}
+
+	user, ok := g.users[userId]
+	if !ok {
+		return nil, fmt.Errorf("unknown user %v", userId)
+	}
+
+	channel := &disgord.Channel{
+		ID:         g.nextSnowflake(),
+		Type:       disgord.ChannelTypeDM,
+		Recipients: []*disgord.User{user},
+	}
+	g.dms[userId] = channel
+
+	return channel, nil
+}
+
+func (g *Guild) CreateReaction(_ context.Context, channelId, messageId disgord.Snowflake, emoji string) error {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	for _, message := range g.messages[channelId] {
+		if message.ID == messageId {
+			g.reactions[messageId] = append(g.reactions[messageId], emoji)
+			return nil
+		}
+	}
+
+	return fmt.Errorf("unknown message %v", messageId)
+}
+
+func (g *Guild) CreateRole(_ context.Context, guildId disgord.Snowflake, name string) (*disgord.Role, error) {
+	if guildId != g.ID {
+		return nil, fmt.Errorf("unknown guild %v", guildId)
+	}
+
+	return g.AddRole(name), nil
+}
+
+func (g *Guild) CreateChannel(_ context.Context, guildId disgord.Snowflake, params *disgord.CreateGuildChannelParams) (*disgord.Channel, error) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	if guildId != g.ID {
+		return nil, fmt.Errorf("unknown guild %v", guildId)
+	}
+
+	channel := &disgord.Channel{
+		ID:                   g.nextSnowflake(),
+		GuildID:              g.ID,
+		Name:                 params.Name,
+		Type:                 params.Type,
+		ParentID:             params.ParentID,
+		PermissionOverwrites: append([]disgord.PermissionOverwrite{}, params.PermissionOverwrites...),
+	}
+	g.channels = append(g.channels, channel)
+
+	return channel, nil
+}
+
+func (g *Guild) SetPermissions(_ context.Context, channelId disgord.Snowflake, overwrite disgord.PermissionOverwrite) error {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	for _, channel := range g.channels {
+		if channel.ID != channelId {
+			continue
+		}
+
+		if g.locked[channelId] {
+			return missingPermissions()
+		}
+
+		for i, existing := range channel.PermissionOverwrites {
+			if existing.ID == overwrite.ID {
+				channel.PermissionOverwrites[i] = overwrite
+				return nil
+			}
+		}
+
+		channel.PermissionOverwrites = append(channel.PermissionOverwrites, overwrite)
+		return nil
+	}
+
+	return notFound(chat.UnknownChannel, "Unknown Channel")
+}
+
+func (g *Guild) DeletePermissions(_ context.Context, channelId, overwriteId disgord.Snowflake) error {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	for _, channel := range g.channels {
+		if channel.ID != channelId {
+			continue
+		}
+
+		if g.locked[channelId] {
+			return missingPermissions()
+		}
+
+		for i, existing := range channel.PermissionOverwrites {
+			if existing.ID == overwriteId {
+				channel.PermissionOverwrites = append(channel.PermissionOverwrites[:i:i], channel.PermissionOverwrites[i+1:]...)
+				return nil
+			}
+		}
+
+		return notFound(chat.UnknownOverwrite, "Unknown Overwrite")
+	}
+
+	return notFound(chat.UnknownChannel, "Unknown Channel")
+}
+
+func (g *Guild) newMessage(channelId disgord.Snowflake, author *disgord.User, content string) *disgord.Message {
+	message := &disgord.Message{
+		ID:        g.nextSnowflake(),
+		ChannelID: channelId,
+		Author:    author,
+		Content:   content,
+	}
+
+	for _, channel := range g.channels {
+		if channel.ID == channelId {
+			message.GuildID = g.ID
+		}
+	}
+	g.messages[channelId] = append(g.messages[channelId], message)
+
+	return message
+}
+
+// checkContent rejects messages that are too long the way Discord does.
+func checkContent(content string) error {
+	if utf8.RuneCountInString(content) <= contentLimit {
+		return nil
+	}
+
+	return &disgord.ErrRest{
+		Code:     50035,
+		Msg:      fmt.Sprintf("Invalid Form Body: content must be %v or fewer in length", contentLimit),
+		HTTPCode: http.StatusBadRequest,
+	}
+}
+
+func notFound(code int, msg string) error {
+	return &disgord.ErrRest{Code: code, Msg: msg, HTTPCode: http.StatusNotFound}
+}
+
+func missingPermissions() error {
+	return &disgord.ErrRest{Code: 50013, Msg: "Missing Permissions", HTTPCode: http.StatusForbidden}
+}
+
+func (g *Guild) channelExists(channelId disgord.Snowflake) bool {
+	for _, channel := range g.channels {
+		if channel.ID == channelId {
+			return true
+		}
+	}
+
+	for _, channel := range g.dms {
+		if channel.ID == channelId {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (g *Guild) nextSnowflake() disgord.Snowflake {
+	g.counter += 1
+	return disgord.NewSnowflake(g.counter)
+}
@@ -0,0 +1,35 @@
+package simguild
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/andersfylling/disgord"
+)
+
+func TestMessagesOverLimitAreRejected(t *testing.T) {
+	g := New()
+	channel := g.AddChannel("general")
+
+	message, err := g.SendMessage(context.Background(), channel.ID, strings.Repeat("é", contentLimit))
+	if err != nil {
+		t.Fatalf("rejected a message at the limit: %v", err)
+	}
+
+	tooLong := strings.Repeat("a", contentLimit+1)
+
+	var rest *disgord.ErrRest
+	if _, err := g.SendMessage(context.Background(), channel.ID, tooLong); !errors.As(err, &rest) || rest.Code != 50035 {
+		t.Errorf("expected SendMessage to fail with code 50035, got %v", err)
+	}
+
+	if _, err := g.EditMessage(context.Background(), channel.ID, message.ID, tooLong); !errors.As(err, &rest) || rest.Code != 50035 {
+		t.Errorf("expected EditMessage to fail with code 50035, got %v", err)
+	}
+
+	if len(g.Messages(channel)) != 1 || g.Messages(channel)[0].Content == tooLong {
+		t.Errorf("a message over the limit was stored")
+	}
+}
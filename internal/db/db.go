@@ -23,12 +23,13 @@ func New(file string) (*Database, error) {
 	query := `
 		CREATE TABLE IF NOT EXISTS participants (
 			id INTEGER NOT NULL PRIMARY KEY,
-			barcode TEXT NOT NULL UNIQUE,
+			barcode TEXT UNIQUE,
 			name TEXT NOT NULL,
 			email TEXT KEY,
 			type TEXT NOT NULL,
 			discord TEXT KEY,
-			urlkey TEXT NOT NULL
+			urlkey TEXT NOT NULL,
+			language TEXT
 		);
 		CREATE TABLE IF NOT EXISTS teams (
 			id INTEGER NOT NULL,
@@ -44,15 +45,100 @@ func New(file string) (*Database, error) {
 			participant INTEGER NOT NULL,
 			FOREIGN KEY (participant) REFERENCES participants (id)
 		);
+		CREATE TABLE IF NOT EXISTS preptimers (
+			round INTEGER NOT NULL,
+			track TEXT NOT NULL DEFAULT '',
+			name TEXT NOT NULL,
+			channel TEXT NOT NULL,
+			message TEXT NOT NULL,
+			ends INTEGER NOT NULL,
+			remaining INTEGER NOT NULL,
+			paused INTEGER NOT NULL,
+			PRIMARY KEY (round, track)
+		);
+		CREATE TABLE IF NOT EXISTS motionschedule (
+			round INTEGER NOT NULL,
+			track TEXT NOT NULL DEFAULT '',
+			channel TEXT NOT NULL,
+			tabchannel TEXT NOT NULL DEFAULT '',
+			infoslide INTEGER NOT NULL,
+			motion INTEGER NOT NULL,
+			infoslidereleased INTEGER NOT NULL DEFAULT 0,
+			motionreleased INTEGER NOT NULL DEFAULT 0,
+			PRIMARY KEY (round, track)
+		);
+		CREATE TABLE IF NOT EXISTS outbox (
+			key TEXT NOT NULL PRIMARY KEY,
+			recipient TEXT NOT NULL,
+			content TEXT NOT NULL,
+			status TEXT NOT NULL,
+			attempts INTEGER NOT NULL DEFAULT 0,
+			error TEXT,
+			updated TEXT DEFAULT (DATETIME())
+		);
+		CREATE TABLE IF NOT EXISTS roomaccess (
+			round INTEGER NOT NULL,
+			channel TEXT NOT NULL,
+			member TEXT NOT NULL,
+			PRIMARY KEY (round, channel, member)
+		);
 	`
 
 	if _, err := db.Exec(query); err != nil {
 		return nil, err
 	}
 
+	if err := addLanguageColumn(db); err != nil {
+		return nil, err
+	}
+
+	if err := allowMissingBarcodes(db); err != nil {
+		return nil, err
+	}
+
 	return &Database{db, file}, nil
 }
 
+// allowMissingBarcodes upgrades databases created when every participant had to
+// have a barcode. SQLite can't drop a NOT NULL constraint, so the table is
+// copied into one without it.
+func allowMissingBarcodes(db *sql.DB) error {
+	_, notNull, err := column(db, "participants", "barcode")
+	if err != nil || !notNull {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+
+	query := `
+		CREATE TABLE participants_upgrade (
+			id INTEGER NOT NULL PRIMARY KEY,
+			barcode TEXT UNIQUE,
+			name TEXT NOT NULL,
+			email TEXT KEY,
+			type TEXT NOT NULL,
+			discord TEXT KEY,
+			urlkey TEXT NOT NULL,
+			language TEXT
+		);
+		INSERT INTO participants_upgrade (id, barcode, name, email, type, discord, urlkey, language)
+		SELECT id, NULLIF(barcode, ''), name, email, type, discord, urlkey, language
+		FROM participants;
+		DROP TABLE participants;
+		ALTER TABLE participants_upgrade RENAME TO participants;
+	`
+
+	if _, err := tx.Exec(query); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
 func (d *Database) Reset() error {
 	query := `
 		DELETE FROM teams;
@@ -89,6 +175,9 @@ func (d *Database) AddTeams(teams []tabbycat.Team) error {
 	return nil
 }
 
+// AddParticipants leaves the barcode NULL for participants without one, usually
+// because fetching it from Tabbycat failed, so they can't register until it's
+// pulled again.
 func (d *Database) AddParticipants(speakers bool, participants []tabbycat.Participant) error {
 	category := "adjudicator"
 	if speakers {
@@ -97,7 +186,7 @@ func (d *Database) AddParticipants(speakers bool, participants []tabbycat.Partic
 
 	insertStmt, err := d.db.Prepare(`
 		INSERT INTO participants (id, barcode, name, email, type, urlkey)
-		VALUES (?, ?, ?, ?, ?, ?)
+		VALUES (?, NULLIF(?, ''), ?, ?, ?, ?)
 	`)
 
 	if err != nil {
@@ -107,7 +196,7 @@ func (d *Database) AddParticipants(speakers bool, participants []tabbycat.Partic
 
 	updateStmt, err := d.db.Prepare(`
 		UPDATE participants
-		SET barcode=?, name=?, email=?, urlkey=?
+		SET barcode=NULLIF(?, ''), name=?, email=?, urlkey=?
 		WHERE id=?
 	`)
 
@@ -238,6 +327,21 @@ func (d *Database) ParticipantFromDiscord(discord string) (uint, bool, error) {
 	return id, category == "speaker", nil
 }
 
+func (d *Database) TeamFromParticipant(id uint) (string, error) {
+	query := `
+		SELECT id
+		FROM teams
+		WHERE participant = ?
+	`
+
+	var team string
+	if err := d.db.QueryRow(query, id).Scan(&team); err != nil {
+		return "", err
+	}
+
+	return team, nil
+}
+
 func (d *Database) ClearParticipantFromDiscord(discord string) error {
 	query := `
 		INSERT INTO reglog (type, participant)
@@ -311,18 +415,20 @@ func (d *Database) ParticipantsFromTeamId(teamId string) ([]string, []string, er
 	return snowflakes, urlKeys, nil
 }
 
+// DiscordFromParticipantIds returns the snowflake and private URL key of each
+// participant in order, with empty strings for anyone it doesn't know about.
 func (d *Database) DiscordFromParticipantIds(participantIds []string) ([]string, []string, error) {
-	ordering := make([]string, 0, len(participantIds))
-	for i, id := range participantIds {
-		ordering = append(ordering, fmt.Sprintf("WHEN %v THEN %v", id, i))
+	snowflakes := make([]string, len(participantIds))
+	urlKeys := make([]string, len(participantIds))
+	if len(participantIds) == 0 {
+		return snowflakes, urlKeys, nil
 	}
 
 	query := fmt.Sprintf(`
-		SELECT COALESCE(discord, ""), urlkey
+		SELECT id, COALESCE(discord, ""), urlkey
 		FROM participants
 		WHERE id IN (%v)
-		ORDER BY CASE id %v END
-	`, strings.Join(participantIds, ","), strings.Join(ordering, " "))
+	`, strings.Join(participantIds, ","))
 
 	rows, err := d.db.Query(query)
 	if err != nil {
@@ -330,25 +436,98 @@ func (d *Database) DiscordFromParticipantIds(participantIds []string) ([]string,
 	}
 	defer rows.Close()
 
-	snowflakes := make([]string, 0)
-	urlKeys := make([]string, 0)
-
+	found := make(map[string][2]string)
 	for rows.Next() {
 		var (
+			id        string
 			snowflake string
 			urlKey    string
 		)
-		if err := rows.Scan(&snowflake, &urlKey); err != nil {
+		if err := rows.Scan(&id, &snowflake, &urlKey); err != nil {
 			return nil, nil, err
 		}
 
-		snowflakes = append(snowflakes, snowflake)
-		urlKeys = append(urlKeys, urlKey)
+		found[id] = [2]string{snowflake, urlKey}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	for i, id := range participantIds {
+		snowflakes[i], urlKeys[i] = found[id][0], found[id][1]
 	}
 
 	return snowflakes, urlKeys, nil
 }
 
+func (d *Database) ParticipantNames(participantIds []string) (map[string]string, error) {
+	names := make(map[string]string)
+	if len(participantIds) == 0 {
+		return names, nil
+	}
+
+	query := fmt.Sprintf(`
+		SELECT id, name
+		FROM participants
+		WHERE id IN (%v)
+	`, strings.Join(participantIds, ","))
+
+	rows, err := d.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id, name string
+		if err := rows.Scan(&id, &name); err != nil {
+			return nil, err
+		}
+
+		names[id] = name
+	}
+
+	return names, nil
+}
+
+func (d *Database) NamesFromDiscords(discords []string) (map[string]string, error) {
+	names := make(map[string]string)
+	if len(discords) == 0 {
+		return names, nil
+	}
+
+	placeholders := make([]string, 0, len(discords))
+	args := make([]interface{}, 0, len(discords))
+	for _, discord := range discords {
+		placeholders = append(placeholders, "?")
+		args = append(args, discord)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT discord, name
+		FROM participants
+		WHERE discord IN (%v)
+	`, strings.Join(placeholders, ","))
+
+	rows, err := d.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var discord, name string
+		if err := rows.Scan(&discord, &name); err != nil {
+			return nil, err
+		}
+
+		names[discord] = name
+	}
+
+	return names, nil
+}
+
 func (d *Database) AllDiscords() ([]string, error) {
 	query := `
 		SELECT discord
@@ -359,6 +538,45 @@ func (d *Database) AllDiscords() ([]string, error) {
 	return d.stringsQuery(query)
 }
 
+type Participant struct {
+	Id      uint
+	Name    string
+	Email   string
+	Speaker bool
+	Discord string
+	Team    uint
+}
+
+func (d *Database) AllParticipants() ([]Participant, error) {
+	query := `
+		SELECT p.id, name, COALESCE(email, ""), type, COALESCE(discord, ""), COALESCE(t.id, 0)
+		FROM participants p LEFT JOIN teams t ON (p.id=t.participant)
+		ORDER BY p.id
+	`
+
+	rows, err := d.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	participants := make([]Participant, 0)
+	for rows.Next() {
+		var (
+			participant Participant
+			category    string
+		)
+		if err := rows.Scan(&participant.Id, &participant.Name, &participant.Email, &category, &participant.Discord, &participant.Team); err != nil {
+			return nil, err
+		}
+
+		participant.Speaker = category == "speaker"
+		participants = append(participants, participant)
+	}
+
+	return participants, rows.Err()
+}
+
 func (d *Database) Close() error {
 	return d.db.Close()
 }
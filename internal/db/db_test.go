@@ -0,0 +1,199 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/hitecherik/Tabulatron/pkg/tabbycat"
+)
+
+func newDatabase(t *testing.T) *Database {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	database, err := New(filepath.Join(dir, "test.db"))
+	if err != nil {
+		t.Fatalf("creating database: %v", err)
+	}
+
+	return database
+}
+
+func TestAddTeamsWithoutBarcodes(t *testing.T) {
+	database := newDatabase(t)
+
+	teams := []tabbycat.Team{
+		{Id: 1, Emoji: "🐙", Speakers: []tabbycat.Participant{
+			{Id: 1, Name: "Ada Lovelace", Barcode: "100001"},
+			{Id: 2, Name: "Alan Turing"},
+		}},
+		{Id: 2, Emoji: "🦊", Speakers: []tabbycat.Participant{
+			{Id: 3, Name: "Grace Hopper"},
+			{Id: 4, Name: "Edsger Dijkstra", Barcode: "100004"},
+		}},
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := database.AddTeams(teams); err != nil {
+			t.Fatalf("AddTeams: %v", err)
+		}
+	}
+
+	for _, barcode := range []string{"100001", "100004"} {
+		if _, _, speaker, err := database.ParticipantFromBarcode(barcode, barcode); err != nil || !speaker {
+			t.Errorf("speaker %v wasn't added (%v)", barcode, err)
+		}
+	}
+
+	if _, _, _, err := database.ParticipantFromBarcode("", "5"); err == nil {
+		t.Errorf("registered someone without a barcode")
+	}
+
+	names, err := database.ParticipantNames([]string{"2", "3"})
+	if err != nil || names["2"] != "Alan Turing" || names["3"] != "Grace Hopper" {
+		t.Errorf("got names %v (%v), want the speakers without barcodes added", names, err)
+	}
+}
+
+func TestDiscordFromParticipantIds(t *testing.T) {
+	database := newDatabase(t)
+
+	adjudicators := []tabbycat.Participant{
+		{Id: 1, Name: "Hedy Lamarr", Barcode: "200001", UrlKey: "hedy"},
+		{Id: 2, Name: "Alan Turing", UrlKey: "alan"},
+	}
+	if err := database.AddParticipants(false, adjudicators); err != nil {
+		t.Fatalf("AddParticipants: %v", err)
+	}
+
+	if _, _, _, err := database.ParticipantFromBarcode("200001", "500"); err != nil {
+		t.Fatalf("registering Hedy: %v", err)
+	}
+
+	tests := []struct {
+		ids        []string
+		snowflakes []string
+		urlKeys    []string
+	}{
+		{[]string{"1"}, []string{"500"}, []string{"hedy"}},
+		{[]string{"99", "2", "1"}, []string{"", "", "500"}, []string{"", "alan", "hedy"}},
+		{[]string{}, []string{}, []string{}},
+	}
+
+	for _, test := range tests {
+		snowflakes, urlKeys, err := database.DiscordFromParticipantIds(test.ids)
+		if err != nil {
+			t.Errorf("%v: %v", test.ids, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(snowflakes, test.snowflakes) || !reflect.DeepEqual(urlKeys, test.urlKeys) {
+			t.Errorf("%v: got %q and %q, want %q and %q", test.ids, snowflakes, urlKeys, test.snowflakes, test.urlKeys)
+		}
+	}
+}
+
+func TestAllowMissingBarcodes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	file := filepath.Join(dir, "old.db")
+	old, err := sql.Open("sqlite3", file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = old.Exec(`
+		CREATE TABLE participants (
+			id INTEGER NOT NULL PRIMARY KEY,
+			barcode TEXT NOT NULL UNIQUE,
+			name TEXT NOT NULL,
+			email TEXT KEY,
+			type TEXT NOT NULL,
+			discord TEXT KEY,
+			urlkey TEXT NOT NULL
+		);
+		INSERT INTO participants VALUES (1, "100001", "Ada Lovelace", "", "speaker", "500", "ada");
+	`)
+	old.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	database, err := New(file)
+	if err != nil {
+		t.Fatalf("upgrading: %v", err)
+	}
+
+	adjudicators := []tabbycat.Participant{{Id: 2, Name: "Alan Turing"}, {Id: 3, Name: "Grace Hopper"}}
+	if err := database.AddParticipants(false, adjudicators); err != nil {
+		t.Fatalf("AddParticipants: %v", err)
+	}
+
+	if snowflakes, _, err := database.DiscordFromParticipantIds([]string{"1"}); err != nil || snowflakes[0] != "500" {
+		t.Errorf("got %v (%v), want Ada kept through the upgrade", snowflakes, err)
+	}
+}
+
+func TestAddParticipants(t *testing.T) {
+	database := newDatabase(t)
+
+	adjudicators := []tabbycat.Participant{{Id: 9, Name: "Hedy Lamarr", Barcode: "200009"}}
+	if err := database.AddParticipants(false, adjudicators); err != nil {
+		t.Fatalf("AddParticipants: %v", err)
+	}
+
+	if _, name, speaker, err := database.ParticipantFromBarcode("200009", "1"); err != nil || speaker || name != "[J] Hedy Lamarr" {
+		t.Errorf("got %q, speaker %v (%v)", name, speaker, err)
+	}
+}
+
+func TestScheduleMotionRejectsReleasedRounds(t *testing.T) {
+	database := newDatabase(t)
+
+	schedule := ScheduledMotion{Round: 1, ChannelId: "1", TabChannelId: "2", InfoSlide: time.Now(), Motion: time.Now()}
+	if err := database.ScheduleMotion(schedule); err != nil {
+		t.Fatalf("ScheduleMotion: %v", err)
+	}
+
+	schedule.Motion = time.Now().Add(time.Hour)
+	if err := database.ScheduleMotion(schedule); err != nil {
+		t.Fatalf("rescheduling a pending motion: %v", err)
+	}
+
+	novice := schedule
+	novice.Track = "novice"
+	if err := database.ScheduleMotion(novice); err != nil {
+		t.Fatalf("scheduling another track: %v", err)
+	}
+
+	if claimed, err := database.ClaimMotion(1, ""); err != nil || !claimed {
+		t.Fatalf("ClaimMotion: %v, %v", claimed, err)
+	}
+
+	if claimed, err := database.ClaimMotion(1, ""); err != nil || claimed {
+		t.Errorf("claimed the motion twice (%v)", err)
+	}
+
+	if err := database.ScheduleMotion(schedule); !errors.Is(err, ErrMotionReleased) {
+		t.Errorf("got %v rescheduling a released motion, want ErrMotionReleased", err)
+	}
+
+	if pending, err := database.PendingMotions(); err != nil || len(pending) != 1 || pending[0].Track != "novice" {
+		t.Errorf("got pending motions %+v (%v), want only the novice track", pending, err)
+	}
+}
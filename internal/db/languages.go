@@ -0,0 +1,124 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// addLanguageColumn upgrades databases created before participants had a
+// preferred language.
+func addLanguageColumn(db *sql.DB) error {
+	exists, err := hasColumn(db, "participants", "language")
+	if err != nil || exists {
+		return err
+	}
+
+	_, err = db.Exec("ALTER TABLE participants ADD COLUMN language TEXT")
+	return err
+}
+
+func hasColumn(db *sql.DB, table string, name string) (bool, error) {
+	exists, _, err := column(db, table, name)
+	return exists, err
+}
+
+// column reports whether table has the named column and whether it's NOT NULL.
+func column(db *sql.DB, table string, column string) (bool, bool, error) {
+	rows, err := db.Query(fmt.Sprintf("PRAGMA table_info(%v)", table))
+	if err != nil {
+		return false, false, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			cid          int
+			name         string
+			kind         string
+			notNull      bool
+			defaultValue sql.NullString
+			primaryKey   int
+		)
+		if err := rows.Scan(&cid, &name, &kind, &notNull, &defaultValue, &primaryKey); err != nil {
+			return false, false, err
+		}
+
+		if name == column {
+			return true, notNull, nil
+		}
+	}
+
+	return false, false, rows.Err()
+}
+
+func (d *Database) SetLanguage(discord string, language string) error {
+	query := `
+		UPDATE participants
+		SET language = NULLIF(?, '')
+		WHERE discord = ?
+	`
+
+	result, err := d.db.Exec(query, language, discord)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+func (d *Database) LanguageFromDiscord(discord string) (string, error) {
+	languages, err := d.LanguagesFromDiscords([]string{discord})
+	if err != nil {
+		return "", err
+	}
+
+	return languages[discord], nil
+}
+
+// LanguagesFromDiscords leaves out anyone who hasn't chosen a language.
+func (d *Database) LanguagesFromDiscords(discords []string) (map[string]string, error) {
+	languages := make(map[string]string, len(discords))
+	if len(discords) == 0 {
+		return languages, nil
+	}
+
+	placeholders := make([]string, 0, len(discords))
+	args := make([]interface{}, 0, len(discords))
+	for _, discord := range discords {
+		placeholders = append(placeholders, "?")
+		args = append(args, discord)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT discord, language
+		FROM participants
+		WHERE discord IN (%v) AND language IS NOT NULL
+	`, strings.Join(placeholders, ","))
+
+	rows, err := d.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var discord, language string
+		if err := rows.Scan(&discord, &language); err != nil {
+			return nil, err
+		}
+
+		languages[discord] = language
+	}
+
+	return languages, rows.Err()
+}
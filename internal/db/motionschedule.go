@@ -0,0 +1,142 @@
+package db
+
+import (
+	"errors"
+	"time"
+)
+
+var ErrMotionReleased = errors.New("the motion for that round has already been released")
+
+type ScheduledMotion struct {
+	Round             uint64
+	Track             string
+	ChannelId         string
+	TabChannelId      string
+	InfoSlide         time.Time
+	Motion            time.Time
+	InfoSlideReleased bool
+	MotionReleased    bool
+}
+
+// ScheduleMotion adds or replaces a round's schedule, but returns
+// ErrMotionReleased rather than scheduling a motion that's already out again.
+func (d *Database) ScheduleMotion(schedule ScheduledMotion) error {
+	query := `
+		INSERT INTO motionschedule (round, track, channel, tabchannel, infoslide, motion, infoslidereleased, motionreleased)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (round, track) DO UPDATE SET
+			channel = excluded.channel,
+			tabchannel = excluded.tabchannel,
+			infoslide = excluded.infoslide,
+			motion = excluded.motion,
+			infoslidereleased = excluded.infoslidereleased,
+			motionreleased = excluded.motionreleased
+		WHERE motionreleased = 0
+	`
+
+	result, err := d.db.Exec(
+		query,
+		schedule.Round,
+		schedule.Track,
+		schedule.ChannelId,
+		schedule.TabChannelId,
+		schedule.InfoSlide.Unix(),
+		schedule.Motion.Unix(),
+		schedule.InfoSlideReleased,
+		schedule.MotionReleased,
+	)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return ErrMotionReleased
+	}
+
+	return nil
+}
+
+func (d *Database) UnscheduleMotion(round uint64, track string) error {
+	query := `
+		DELETE FROM motionschedule
+		WHERE round = ? AND track = ?
+		AND motionreleased = 0
+	`
+
+	_, err := d.db.Exec(query, round, track)
+	return err
+}
+
+func (d *Database) PendingMotions() ([]ScheduledMotion, error) {
+	query := `
+		SELECT round, track, channel, tabchannel, infoslide, motion, infoslidereleased, motionreleased
+		FROM motionschedule
+		WHERE motionreleased = 0
+	`
+
+	rows, err := d.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	schedules := make([]ScheduledMotion, 0)
+
+	for rows.Next() {
+		var (
+			schedule  ScheduledMotion
+			infoSlide int64
+			motion    int64
+		)
+
+		if err := rows.Scan(&schedule.Round, &schedule.Track, &schedule.ChannelId, &schedule.TabChannelId, &infoSlide, &motion, &schedule.InfoSlideReleased, &schedule.MotionReleased); err != nil {
+			return nil, err
+		}
+
+		schedule.InfoSlide = time.Unix(infoSlide, 0)
+		schedule.Motion = time.Unix(motion, 0)
+		schedules = append(schedules, schedule)
+	}
+
+	return schedules, rows.Err()
+}
+
+// ClaimInfoSlide and ClaimMotion mark a stage as released and report whether
+// this call was the one that did so, so that a stage is never announced twice.
+// Claiming a stage that isn't scheduled reports false.
+func (d *Database) ClaimInfoSlide(round uint64, track string) (bool, error) {
+	return d.claim(`
+		UPDATE motionschedule
+		SET infoslidereleased = 1
+		WHERE round = ? AND track = ?
+		AND infoslidereleased = 0
+	`, round, track)
+}
+
+func (d *Database) ClaimMotion(round uint64, track string) (bool, error) {
+	return d.claim(`
+		UPDATE motionschedule
+		SET infoslidereleased = 1, motionreleased = 1
+		WHERE round = ? AND track = ?
+		AND motionreleased = 0
+	`, round, track)
+}
+
+func (d *Database) claim(query string, round uint64, track string) (bool, error) {
+	result, err := d.db.Exec(query, round, track)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected == 1, nil
+}
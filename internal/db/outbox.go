@@ -0,0 +1,64 @@
+package db
+
+// Failed messages are sent again when they're next queued, but undeliverable
+// ones (e.g. to someone who has closed their DMs) aren't.
+const (
+	OutboxPending       = "pending"
+	OutboxSent          = "sent"
+	OutboxFailed        = "failed"
+	OutboxUndeliverable = "undeliverable"
+)
+
+// QueueMessage records a message in the outbox under key, unless it's already
+// there, and returns its status, which is OutboxPending if it needs sending.
+func (d *Database) QueueMessage(key string, recipient string, content string) (string, error) {
+	query := `
+		INSERT INTO outbox (key, recipient, content, status)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT (key) DO UPDATE
+		SET recipient = excluded.recipient, content = excluded.content, status = excluded.status
+		WHERE status = 'failed'
+	`
+	if _, err := d.db.Exec(query, key, recipient, content, OutboxPending); err != nil {
+		return "", err
+	}
+
+	query = `
+		SELECT status
+		FROM outbox
+		WHERE key = ?
+	`
+
+	var status string
+	if err := d.db.QueryRow(query, key).Scan(&status); err != nil {
+		return "", err
+	}
+
+	return status, nil
+}
+
+func (d *Database) UpdateMessage(key string, status string, attempts int, sendErr error) error {
+	var message interface{}
+	if sendErr != nil {
+		message = sendErr.Error()
+	}
+
+	query := `
+		UPDATE outbox
+		SET status = ?, attempts = attempts + ?, error = ?, updated = DATETIME()
+		WHERE key = ?
+	`
+
+	_, err := d.db.Exec(query, status, attempts, message, key)
+	return err
+}
+
+func (d *Database) ForgetMessages(prefix string) error {
+	query := `
+		DELETE FROM outbox
+		WHERE SUBSTR(key, 1, LENGTH(?)) = ?
+	`
+
+	_, err := d.db.Exec(query, prefix, prefix)
+	return err
+}
@@ -0,0 +1,79 @@
+package db
+
+import (
+	"time"
+)
+
+type PrepTimer struct {
+	Round     uint64
+	Track     string
+	RoundName string
+	ChannelId string
+	MessageId string
+	Ends      time.Time
+	Remaining time.Duration
+	Paused    bool
+}
+
+func (d *Database) SavePrepTimer(timer PrepTimer) error {
+	query := `
+		REPLACE INTO preptimers (round, track, name, channel, message, ends, remaining, paused)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	_, err := d.db.Exec(
+		query,
+		timer.Round,
+		timer.Track,
+		timer.RoundName,
+		timer.ChannelId,
+		timer.MessageId,
+		timer.Ends.Unix(),
+		int64(timer.Remaining),
+		timer.Paused,
+	)
+	return err
+}
+
+func (d *Database) DeletePrepTimer(round uint64, track string) error {
+	query := `
+		DELETE FROM preptimers
+		WHERE round = ? AND track = ?
+	`
+
+	_, err := d.db.Exec(query, round, track)
+	return err
+}
+
+func (d *Database) PrepTimers() ([]PrepTimer, error) {
+	query := `
+		SELECT round, track, name, channel, message, ends, remaining, paused
+		FROM preptimers
+	`
+
+	rows, err := d.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	timers := make([]PrepTimer, 0)
+
+	for rows.Next() {
+		var (
+			timer     PrepTimer
+			ends      int64
+			remaining int64
+		)
+
+		if err := rows.Scan(&timer.Round, &timer.Track, &timer.RoundName, &timer.ChannelId, &timer.MessageId, &ends, &remaining, &timer.Paused); err != nil {
+			return nil, err
+		}
+
+		timer.Ends = time.Unix(ends, 0)
+		timer.Remaining = time.Duration(remaining)
+		timers = append(timers, timer)
+	}
+
+	return timers, rows.Err()
+}
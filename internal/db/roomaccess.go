@@ -0,0 +1,74 @@
+package db
+
+type RoomAccess struct {
+	Round     uint64
+	ChannelId string
+	Discord   string
+}
+
+func (d *Database) GrantRoomAccess(access RoomAccess) error {
+	query := `
+		REPLACE INTO roomaccess (round, channel, member)
+		VALUES (?, ?, ?)
+	`
+
+	_, err := d.db.Exec(query, access.Round, access.ChannelId, access.Discord)
+	return err
+}
+
+func (d *Database) RevokeRoomAccess(access RoomAccess) error {
+	query := `
+		DELETE FROM roomaccess
+		WHERE round = ? AND channel = ? AND member = ?
+	`
+
+	_, err := d.db.Exec(query, access.Round, access.ChannelId, access.Discord)
+	return err
+}
+
+// SharedRoomAccess reports whether another round also let the member into the
+// channel. Discord only keeps one overwrite per member and channel, so it has
+// to stay until the last round that needs it is closed.
+func (d *Database) SharedRoomAccess(access RoomAccess) (bool, error) {
+	query := `
+		SELECT COUNT(*)
+		FROM roomaccess
+		WHERE round != ? AND channel = ? AND member = ?
+	`
+
+	count := 0
+	if err := d.db.QueryRow(query, access.Round, access.ChannelId, access.Discord).Scan(&count); err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+// RoomAccessFor lists who was let into which channels for a round, so that it
+// can be taken away again once the round is over.
+func (d *Database) RoomAccessFor(round uint64) ([]RoomAccess, error) {
+	query := `
+		SELECT round, channel, member
+		FROM roomaccess
+		WHERE round = ?
+	`
+
+	rows, err := d.db.Query(query, round)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	grants := make([]RoomAccess, 0)
+
+	for rows.Next() {
+		var access RoomAccess
+		if err := rows.Scan(&access.Round, &access.ChannelId, &access.Discord); err != nil {
+			return nil, err
+		}
+
+		grants = append(grants, access)
+	}
+
+	return grants, rows.Err()
+}
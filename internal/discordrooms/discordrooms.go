@@ -0,0 +1,204 @@
+package discordrooms
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/andersfylling/disgord"
+	"github.com/hitecherik/Tabulatron/internal/chat"
+	"github.com/hitecherik/Tabulatron/internal/db"
+	"github.com/hitecherik/Tabulatron/internal/guildsetup"
+	"github.com/hitecherik/Tabulatron/internal/roundrunner"
+	"github.com/hitecherik/Tabulatron/internal/util"
+	"github.com/hitecherik/Tabulatron/pkg/tabbycat"
+)
+
+const (
+	textAccess  disgord.PermissionBits = disgord.PermissionReadMessages | disgord.PermissionSendMessages
+	voiceAccess disgord.PermissionBits = disgord.PermissionReadMessages | disgord.PermissionVoiceConnect | disgord.PermissionVoiceSpeak
+)
+
+// Rooms lets each room's speakers and adjudicators into their venue's voice
+// and text channels, which guildsetup hides from everyone else, and keeps
+// track of who it let in so that Close can undo it.
+type Rooms struct {
+	platform chat.Platform
+	guild    disgord.Snowflake
+	tabbycat *tabbycat.Tabbycat
+	database *db.Database
+}
+
+type Report struct {
+	Rooms        int
+	Participants int
+	Missing      []string
+}
+
+func New(platform chat.Platform, guild disgord.Snowflake, t *tabbycat.Tabbycat, database *db.Database) *Rooms {
+	return &Rooms{
+		platform: platform,
+		guild:    guild,
+		tabbycat: t,
+		database: database,
+	}
+}
+
+func (r *Rooms) Open(ctx context.Context, round uint64) (Report, error) {
+	var report Report
+
+	rooms, err := r.tabbycat.GetDrawContext(ctx, round)
+	if err != nil {
+		return report, err
+	}
+
+	venues, err := r.tabbycat.GetVenuesContext(ctx)
+	if err != nil {
+		return report, err
+	}
+	venueMap := roundrunner.BuildVenueMap(venues)
+
+	channels, err := r.platform.GetGuildChannels(ctx, r.guild)
+	if err != nil {
+		return report, err
+	}
+
+	for _, room := range rooms {
+		venue := venueMap[room.VenueId]
+		voice := findChannel(channels, disgord.ChannelTypeGuildVoice, venue)
+		text := findChannel(channels, disgord.ChannelTypeGuildText, guildsetup.TextChannelName(venue))
+
+		if voice == nil && text == nil {
+			report.Missing = append(report.Missing, venue)
+			continue
+		}
+
+		discords, err := r.participants(room)
+		if err != nil {
+			return report, err
+		}
+
+		for _, discord := range discords {
+			if voice != nil {
+				if err := r.grant(ctx, round, voice, discord, voiceAccess); err != nil {
+					return report, err
+				}
+			}
+
+			if text != nil {
+				if err := r.grant(ctx, round, text, discord, textAccess); err != nil {
+					return report, err
+				}
+			}
+		}
+
+		report.Rooms += 1
+		report.Participants += len(discords)
+	}
+
+	return report, nil
+}
+
+// Close takes away everything Open granted for the round, except where another
+// round still needs it, and returns how many permissions it removed.
+func (r *Rooms) Close(ctx context.Context, round uint64) (int, error) {
+	grants, err := r.database.RoomAccessFor(round)
+	if err != nil {
+		return 0, err
+	}
+
+	revoked := 0
+	for _, access := range grants {
+		shared, err := r.database.SharedRoomAccess(access)
+		if err != nil {
+			return revoked, err
+		}
+
+		if shared {
+			if err := r.database.RevokeRoomAccess(access); err != nil {
+				return revoked, err
+			}
+
+			continue
+		}
+
+		channel, err := util.StringToSnowflake(access.ChannelId)
+		if err != nil {
+			return revoked, err
+		}
+
+		member, err := util.StringToSnowflake(access.Discord)
+		if err != nil {
+			return revoked, err
+		}
+
+		// The channel or overwrite may already have been deleted by hand, but
+		// any other failure leaves the member with access, so the grant stays.
+		if err := r.platform.DeletePermissions(ctx, channel, member); err != nil && !chat.IsUnknownOverwrite(err) {
+			return revoked, err
+		}
+
+		if err := r.database.RevokeRoomAccess(access); err != nil {
+			return revoked, err
+		}
+
+		revoked += 1
+	}
+
+	return revoked, nil
+}
+
+func (r *Rooms) participants(room tabbycat.Room) ([]disgord.Snowflake, error) {
+	discords := make([]string, 0)
+
+	for _, team := range room.TeamIds {
+		speakers, _, err := r.database.ParticipantsFromTeamId(team)
+		if err != nil {
+			return nil, err
+		}
+
+		discords = append(discords, speakers...)
+	}
+
+	judgeIds := make([]string, 0)
+	for _, id := range append(append([]string{room.ChairId}, room.PanellistIds...), room.TraineeIds...) {
+		if id != "" {
+			judgeIds = append(judgeIds, id)
+		}
+	}
+
+	if len(judgeIds) == 0 {
+		return util.StringsToSnowflakes(discords)
+	}
+
+	judges, _, err := r.database.DiscordFromParticipantIds(judgeIds)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, judge := range judges {
+		if judge != "" {
+			discords = append(discords, judge)
+		}
+	}
+
+	return util.StringsToSnowflakes(discords)
+}
+
+func (r *Rooms) grant(ctx context.Context, round uint64, channel *disgord.Channel, member disgord.Snowflake, allow disgord.PermissionBits) error {
+	overwrite := disgord.PermissionOverwrite{ID: member, Type: "member", Allow: allow}
+	if err := r.platform.SetPermissions(ctx, channel.ID, overwrite); err != nil {
+		return fmt.Errorf("letting %v into %v: %w", member, channel.Name, err)
+	}
+
+	return r.database.GrantRoomAccess(db.RoomAccess{Round: round, ChannelId: channel.ID.String(), Discord: member.String()})
+}
+
+func findChannel(channels []*disgord.Channel, kind uint, name string) *disgord.Channel {
+	for _, channel := range channels {
+		if channel.Type == kind && channel.Name == name {
+			return channel
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,192 @@
+package discordrooms
+
+import (
+	"context"
+	"testing"
+
+	"github.com/andersfylling/disgord"
+	"github.com/hitecherik/Tabulatron/internal/chat/simguild"
+	"github.com/hitecherik/Tabulatron/pkg/tabbycat/tabbycattest"
+)
+
+func setup(t *testing.T) (*Rooms, *simguild.Guild, *disgord.Channel, *disgord.Channel) {
+	t.Helper()
+
+	client, _, database := tabbycattest.NewTournament(t)
+
+	teams, err := client.GetTeams()
+	if err != nil {
+		t.Fatalf("GetTeams: %v", err)
+	}
+
+	adjudicators, err := client.GetAdjudicators()
+	if err != nil {
+		t.Fatalf("GetAdjudicators: %v", err)
+	}
+
+	guild := simguild.New()
+	text := guild.AddChannel("open-1")
+	voice := guild.AddVoiceChannel("Open 1")
+
+	for _, team := range teams {
+		for _, speaker := range team.Speakers {
+			member := guild.AddMember(speaker.Name)
+			if _, _, _, err := database.ParticipantFromBarcode(speaker.Barcode, member.User.ID.String()); err != nil {
+				t.Fatalf("registering %v: %v", speaker.Name, err)
+			}
+		}
+	}
+
+	for _, adjudicator := range adjudicators {
+		member := guild.AddMember(adjudicator.Name)
+		if _, _, _, err := database.ParticipantFromBarcode(adjudicator.Barcode, member.User.ID.String()); err != nil {
+			t.Fatalf("registering %v: %v", adjudicator.Name, err)
+		}
+	}
+
+	return New(guild, guild.ID, client, database), guild, text, voice
+}
+
+func overwrites(t *testing.T, guild *simguild.Guild, channel *disgord.Channel) int {
+	t.Helper()
+
+	channels, err := guild.GetGuildChannels(context.Background(), guild.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range channels {
+		if c.ID == channel.ID {
+			return len(c.PermissionOverwrites)
+		}
+	}
+
+	t.Fatalf("channel %v is missing", channel.Name)
+	return 0
+}
+
+func TestOpenAndClose(t *testing.T) {
+	rooms, guild, text, voice := setup(t)
+	ctx := context.Background()
+
+	report, err := rooms.Open(ctx, 1)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+
+	if report.Rooms != 1 || report.Participants != 11 || len(report.Missing) != 0 {
+		t.Errorf("got report %+v", report)
+	}
+
+	if got := overwrites(t, guild, text); got != 11 {
+		t.Errorf("got %v overwrites on the text channel, want 11", got)
+	}
+
+	if revoked, err := rooms.Close(ctx, 1); err != nil || revoked != 22 {
+		t.Errorf("Close revoked %v (%v), want 22", revoked, err)
+	}
+
+	if got := overwrites(t, guild, voice); got != 0 {
+		t.Errorf("got %v overwrites on the voice channel after closing, want none", got)
+	}
+}
+
+// Everyone is in Open 1 in both rounds, so closing the first round mustn't
+// lock them out of the second.
+func TestCloseKeepsAccessForOtherRounds(t *testing.T) {
+	rooms, guild, text, voice := setup(t)
+	ctx := context.Background()
+
+	for _, round := range []uint64{1, 2} {
+		if _, err := rooms.Open(ctx, round); err != nil {
+			t.Fatalf("Open(%v): %v", round, err)
+		}
+	}
+
+	if revoked, err := rooms.Close(ctx, 1); err != nil || revoked != 0 {
+		t.Errorf("closing round 1 revoked %v (%v), want 0", revoked, err)
+	}
+
+	if got := overwrites(t, guild, text); got != 11 {
+		t.Errorf("got %v overwrites on the text channel, want 11", got)
+	}
+
+	if revoked, err := rooms.Close(ctx, 2); err != nil || revoked != 22 {
+		t.Errorf("closing round 2 revoked %v (%v), want 22", revoked, err)
+	}
+
+	if got := overwrites(t, guild, voice); got != 0 {
+		t.Errorf("got %v overwrites on the voice channel after closing, want none", got)
+	}
+}
+
+func TestCloseAfterOverwritesDeletedByHand(t *testing.T) {
+	rooms, guild, text, voice := setup(t)
+	ctx := context.Background()
+
+	if _, err := rooms.Open(ctx, 1); err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+
+	channels, err := guild.GetGuildChannels(ctx, guild.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, channel := range channels {
+		if channel.ID != voice.ID {
+			continue
+		}
+
+		for _, overwrite := range channel.PermissionOverwrites {
+			if err := guild.DeletePermissions(ctx, voice.ID, overwrite.ID); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	if revoked, err := rooms.Close(ctx, 1); err != nil || revoked != 22 {
+		t.Errorf("Close revoked %v (%v), want 22", revoked, err)
+	}
+
+	if got := overwrites(t, guild, text); got != 0 {
+		t.Errorf("got %v overwrites on the text channel after closing, want none", got)
+	}
+}
+
+// If the bot can't take access away, the grant has to stay so that closing the
+// rooms again can retry.
+func TestCloseKeepsGrantsItCannotRevoke(t *testing.T) {
+	rooms, guild, text, _ := setup(t)
+	ctx := context.Background()
+
+	if _, err := rooms.Open(ctx, 1); err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+
+	guild.LockChannel(text)
+
+	if _, err := rooms.Close(ctx, 1); err == nil {
+		t.Fatalf("Close succeeded on a channel the bot can't change")
+	}
+
+	if got := overwrites(t, guild, text); got != 11 {
+		t.Errorf("got %v overwrites on the text channel, want 11", got)
+	}
+
+	grants, err := rooms.database.RoomAccessFor(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	remaining := 0
+	for _, access := range grants {
+		if access.ChannelId == text.ID.String() {
+			remaining += 1
+		}
+	}
+
+	if remaining != 11 {
+		t.Errorf("%v grants for the text channel remain, want 11", remaining)
+	}
+}
@@ -0,0 +1,288 @@
+package guildsetup
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/andersfylling/disgord"
+	"github.com/hitecherik/Tabulatron/internal/chat"
+	"github.com/hitecherik/Tabulatron/internal/layout"
+	"github.com/hitecherik/Tabulatron/internal/multiroom"
+	"github.com/hitecherik/Tabulatron/pkg/tabbycat"
+)
+
+const defaultFolder string = "Debate rooms"
+
+const (
+	venueText  disgord.PermissionBits = disgord.PermissionReadMessages | disgord.PermissionSendMessages
+	venueVoice disgord.PermissionBits = disgord.PermissionReadMessages | disgord.PermissionVoiceConnect | disgord.PermissionVoiceSpeak
+)
+
+var (
+	snowflake *regexp.Regexp = regexp.MustCompile(`^\d+$`)
+	spaces    *regexp.Regexp = regexp.MustCompile(`\s+`)
+)
+
+// Builder creates whatever is missing from a tournament's guild: the roles and
+// channels in the layout, and a text and voice channel for every venue, in a
+// folder for each multiroom category. Venue channels are hidden from everyone
+// but the tab team, so participants can be let into their own room each round.
+// Running it again only changes what differs.
+type Builder struct {
+	platform chat.Platform
+	guild    disgord.Snowflake
+	layout   *layout.Layout
+	dryRun   bool
+	report   func(format string, a ...interface{})
+	roles    []*disgord.Role
+	channels []*disgord.Channel
+}
+
+func NewBuilder(platform chat.Platform, guild disgord.Snowflake, l *layout.Layout, dryRun bool, report func(format string, a ...interface{})) *Builder {
+	return &Builder{
+		platform: platform,
+		guild:    guild,
+		layout:   l,
+		dryRun:   dryRun,
+		report:   report,
+	}
+}
+
+func (b *Builder) Setup(ctx context.Context, venues []tabbycat.Venue, categories multiroom.Categories) error {
+	var err error
+
+	if b.roles, err = b.platform.GetGuildRoles(ctx, b.guild); err != nil {
+		return err
+	}
+
+	if b.channels, err = b.platform.GetGuildChannels(ctx, b.guild); err != nil {
+		return err
+	}
+
+	for _, name := range []string{layout.SpeakerRole, layout.JudgeRole} {
+		if _, err := b.role(ctx, name); err != nil {
+			return err
+		}
+	}
+
+	tab, err := b.role(ctx, layout.TabRole)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range []string{layout.RegistrationChannel, layout.RegistrationHelpChannel, layout.CheckinChannel, layout.AvailabilityChannel, layout.HelpChannel} {
+		if _, err := b.layoutChannel(ctx, name, disgord.ChannelTypeGuildText, nil); err != nil {
+			return err
+		}
+	}
+
+	// Only the tab team posts in the draw channel.
+	readOnly := []disgord.PermissionOverwrite{b.everyone(0, disgord.PermissionSendMessages)}
+	if tab != nil {
+		readOnly = append(readOnly, roleOverwrite(tab.ID, disgord.PermissionSendMessages, 0))
+	}
+
+	if _, err := b.layoutChannel(ctx, layout.DrawChannel, disgord.ChannelTypeGuildText, readOnly); err != nil {
+		return err
+	}
+
+	// Only the tab team can see the tab channel, where the bot reports to them.
+	tabOnly := []disgord.PermissionOverwrite{b.everyone(0, disgord.PermissionReadMessages)}
+	if tab != nil {
+		tabOnly = append(tabOnly, roleOverwrite(tab.ID, disgord.PermissionReadMessages|disgord.PermissionSendMessages, 0))
+	}
+
+	if _, err := b.layoutChannel(ctx, layout.TabChannel, disgord.ChannelTypeGuildText, tabOnly); err != nil {
+		return err
+	}
+
+	if _, err := b.layoutChannel(ctx, layout.WaitingRoomChannel, disgord.ChannelTypeGuildVoice, nil); err != nil {
+		return err
+	}
+
+	for _, venue := range venues {
+		folder := defaultFolder
+		if category, err := categories.Lookup(venue.Name); err != nil {
+			b.report("%v, so putting it in %v\n", err.Error(), folder)
+		} else if category.Name != "" {
+			folder = category.Name
+		}
+
+		if err := b.venue(ctx, venue.Name, folder, tab); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (b *Builder) venue(ctx context.Context, name string, folderName string, tab *disgord.Role) error {
+	private := func(allowed disgord.PermissionBits) []disgord.PermissionOverwrite {
+		overwrites := []disgord.PermissionOverwrite{b.everyone(0, allowed)}
+		if tab != nil {
+			overwrites = append(overwrites, roleOverwrite(tab.ID, allowed|disgord.PermissionVoiceMoveMembers, 0))
+		}
+
+		return overwrites
+	}
+
+	folder, err := b.channel(ctx, folderName, disgord.ChannelTypeGuildCategory, nil, private(venueText|venueVoice))
+	if err != nil {
+		return err
+	}
+
+	if _, err := b.channel(ctx, TextChannelName(name), disgord.ChannelTypeGuildText, folder, private(venueText)); err != nil {
+		return err
+	}
+
+	_, err = b.channel(ctx, name, disgord.ChannelTypeGuildVoice, folder, private(venueVoice))
+	return err
+}
+
+func (b *Builder) role(ctx context.Context, name string) (*disgord.Role, error) {
+	for _, role := range b.roles {
+		if b.layout.IsRole(name, role) {
+			return role, nil
+		}
+	}
+
+	configured := b.layout.Role(name)
+	if snowflake.MatchString(configured) {
+		return nil, fmt.Errorf("the %v role is set to ID %v, which doesn't exist", name, configured)
+	}
+
+	if b.dryRun {
+		b.report("Would create role %v\n", configured)
+		return nil, nil
+	}
+
+	role, err := b.platform.CreateRole(ctx, b.guild, configured)
+	if err != nil {
+		return nil, fmt.Errorf("creating role %v: %w", configured, err)
+	}
+
+	b.report("Created role %v\n", configured)
+	b.roles = append(b.roles, role)
+
+	return role, nil
+}
+
+func (b *Builder) layoutChannel(ctx context.Context, name string, kind uint, overwrites []disgord.PermissionOverwrite) (*disgord.Channel, error) {
+	for _, channel := range b.channels {
+		if b.layout.IsChannel(name, channel) {
+			return channel, b.permit(ctx, channel, overwrites)
+		}
+	}
+
+	configured := b.layout.Channel(name)
+	if snowflake.MatchString(configured) {
+		return nil, fmt.Errorf("the %v channel is set to ID %v, which doesn't exist", name, configured)
+	}
+
+	return b.channel(ctx, configured, kind, nil, overwrites)
+}
+
+// channel finds or creates a channel, and then makes sure it has overwrites. A
+// nil folder means the top level, or a folder that the dry run would create.
+func (b *Builder) channel(ctx context.Context, name string, kind uint, folder *disgord.Channel, overwrites []disgord.PermissionOverwrite) (*disgord.Channel, error) {
+	var parent disgord.Snowflake
+	if folder != nil {
+		parent = folder.ID
+	}
+
+	for _, channel := range b.channels {
+		if channel.Type == kind && channel.Name == name && (folder == nil || channel.ParentID == parent) {
+			return channel, b.permit(ctx, channel, overwrites)
+		}
+	}
+
+	if b.dryRun {
+		b.report("Would create %v %v\n", describeKind(kind), name)
+		return nil, nil
+	}
+
+	channel, err := b.platform.CreateChannel(ctx, b.guild, &disgord.CreateGuildChannelParams{
+		Name:                 name,
+		Type:                 kind,
+		ParentID:             parent,
+		PermissionOverwrites: overwrites,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("creating %v %v: %w", describeKind(kind), name, err)
+	}
+
+	b.report("Created %v %v\n", describeKind(kind), name)
+	b.channels = append(b.channels, channel)
+
+	return channel, nil
+}
+
+func (b *Builder) permit(ctx context.Context, channel *disgord.Channel, overwrites []disgord.PermissionOverwrite) error {
+	for _, overwrite := range overwrites {
+		found := false
+		for _, existing := range channel.PermissionOverwrites {
+			if existing.ID == overwrite.ID {
+				found = existing.Allow == overwrite.Allow && existing.Deny == overwrite.Deny
+			}
+		}
+
+		if found {
+			continue
+		}
+
+		if b.dryRun {
+			b.report("Would update permissions on %v %v for %v\n", describeKind(channel.Type), channel.Name, b.describeRole(overwrite.ID))
+			continue
+		}
+
+		if err := b.platform.SetPermissions(ctx, channel.ID, overwrite); err != nil {
+			return fmt.Errorf("updating permissions on %v: %w", channel.Name, err)
+		}
+
+		b.report("Updated permissions on %v %v for %v\n", describeKind(channel.Type), channel.Name, b.describeRole(overwrite.ID))
+	}
+
+	return nil
+}
+
+// everyone overwrites the @everyone role, which shares the guild's ID.
+func (b *Builder) everyone(allow disgord.PermissionBits, deny disgord.PermissionBits) disgord.PermissionOverwrite {
+	return roleOverwrite(b.guild, allow, deny)
+}
+
+func (b *Builder) describeRole(id disgord.Snowflake) string {
+	if id == b.guild {
+		return "@everyone"
+	}
+
+	for _, role := range b.roles {
+		if role.ID == id {
+			return role.Name
+		}
+	}
+
+	return id.String()
+}
+
+func roleOverwrite(id disgord.Snowflake, allow disgord.PermissionBits, deny disgord.PermissionBits) disgord.PermissionOverwrite {
+	return disgord.PermissionOverwrite{ID: id, Type: "role", Allow: allow, Deny: deny}
+}
+
+func describeKind(kind uint) string {
+	switch kind {
+	case disgord.ChannelTypeGuildCategory:
+		return "category"
+	case disgord.ChannelTypeGuildVoice:
+		return "voice channel"
+	default:
+		return "text channel"
+	}
+}
+
+// TextChannelName is what Discord turns a venue's name into for a text
+// channel, e.g. "Room 1" becomes "room-1".
+func TextChannelName(venue string) string {
+	return spaces.ReplaceAllString(strings.ToLower(strings.TrimSpace(venue)), "-")
+}
@@ -0,0 +1,153 @@
+package guildsetup
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/andersfylling/disgord"
+	"github.com/hitecherik/Tabulatron/internal/chat/simguild"
+	"github.com/hitecherik/Tabulatron/internal/layout"
+	"github.com/hitecherik/Tabulatron/internal/multiroom"
+	"github.com/hitecherik/Tabulatron/pkg/tabbycat"
+)
+
+var (
+	venues     = []tabbycat.Venue{{Id: 1, Name: "Open 1"}, {Id: 2, Name: "Novice 1"}}
+	categories = multiroom.Categories{{Name: "Open", Prefix: "Open"}, {Name: "Novice", Prefix: "Novice"}}
+)
+
+func setup(t *testing.T, guild *simguild.Guild, dryRun bool) []string {
+	t.Helper()
+
+	var reports []string
+	builder := NewBuilder(guild, guild.ID, &layout.Layout{}, dryRun, func(format string, a ...interface{}) {
+		reports = append(reports, strings.TrimSpace(fmt.Sprintf(format, a...)))
+	})
+
+	if err := builder.Setup(context.Background(), venues, categories); err != nil {
+		t.Fatalf("Setup: %v", err)
+	}
+
+	return reports
+}
+
+func find(t *testing.T, guild *simguild.Guild, kind uint, name string) *disgord.Channel {
+	t.Helper()
+
+	channels, err := guild.GetGuildChannels(context.Background(), guild.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, channel := range channels {
+		if channel.Type == kind && channel.Name == name {
+			return channel
+		}
+	}
+
+	return nil
+}
+
+func TestSetup(t *testing.T) {
+	guild := simguild.New()
+
+	if reports := setup(t, guild, true); len(reports) == 0 || !strings.HasPrefix(reports[0], "Would create") {
+		t.Errorf("the dry run reported %v", reports)
+	}
+
+	if channels, _ := guild.GetGuildChannels(context.Background(), guild.ID); len(channels) != 0 {
+		t.Fatalf("the dry run created %v channels", len(channels))
+	}
+
+	setup(t, guild, false)
+
+	tests := []struct {
+		kind   uint
+		name   string
+		folder string
+	}{
+		{disgord.ChannelTypeGuildText, "motions-and-draw", ""},
+		{disgord.ChannelTypeGuildText, "tab", ""},
+		{disgord.ChannelTypeGuildVoice, "Waiting room", ""},
+		{disgord.ChannelTypeGuildText, "open-1", "Open"},
+		{disgord.ChannelTypeGuildVoice, "Open 1", "Open"},
+		{disgord.ChannelTypeGuildText, "novice-1", "Novice"},
+		{disgord.ChannelTypeGuildVoice, "Novice 1", "Novice"},
+	}
+
+	for _, test := range tests {
+		channel := find(t, guild, test.kind, test.name)
+		if channel == nil {
+			t.Errorf("%v wasn't created", test.name)
+			continue
+		}
+
+		if test.folder == "" {
+			continue
+		}
+
+		folder := find(t, guild, disgord.ChannelTypeGuildCategory, test.folder)
+		if folder == nil || channel.ParentID != folder.ID {
+			t.Errorf("%v isn't in the %v folder", test.name, test.folder)
+		}
+	}
+
+	if reports := setup(t, guild, false); len(reports) != 0 {
+		t.Errorf("running again changed %v", reports)
+	}
+}
+
+func TestPermit(t *testing.T) {
+	guild := simguild.New()
+	tab := guild.AddRole("Tab/Tech")
+	everyone := roleOverwrite(guild.ID, 0, disgord.PermissionSendMessages)
+	tabCanSend := roleOverwrite(tab.ID, disgord.PermissionSendMessages, 0)
+
+	tests := []struct {
+		name     string
+		existing []disgord.PermissionOverwrite
+		dryRun   bool
+		reports  int
+		want     []disgord.PermissionOverwrite
+	}{
+		{"missing", nil, false, 2, []disgord.PermissionOverwrite{everyone, tabCanSend}},
+		{"matching", []disgord.PermissionOverwrite{everyone, tabCanSend}, false, 0, []disgord.PermissionOverwrite{everyone, tabCanSend}},
+		{
+			"different",
+			[]disgord.PermissionOverwrite{everyone, roleOverwrite(tab.ID, 0, disgord.PermissionSendMessages)},
+			false,
+			1,
+			[]disgord.PermissionOverwrite{everyone, tabCanSend},
+		},
+		{"dry run", []disgord.PermissionOverwrite{everyone}, true, 1, []disgord.PermissionOverwrite{everyone}},
+	}
+
+	for _, test := range tests {
+		channel := guild.AddChannel(test.name)
+		for _, overwrite := range test.existing {
+			if err := guild.SetPermissions(context.Background(), channel.ID, overwrite); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		reports := 0
+		builder := NewBuilder(guild, guild.ID, &layout.Layout{}, test.dryRun, func(string, ...interface{}) { reports++ })
+		builder.roles = []*disgord.Role{tab}
+
+		if err := builder.permit(context.Background(), channel, []disgord.PermissionOverwrite{everyone, tabCanSend}); err != nil {
+			t.Errorf("%v: %v", test.name, err)
+			continue
+		}
+
+		if reports != test.reports {
+			t.Errorf("%v: reported %v changes, want %v", test.name, reports, test.reports)
+		}
+
+		got := find(t, guild, disgord.ChannelTypeGuildText, test.name).PermissionOverwrites
+		if fmt.Sprint(got) != fmt.Sprint(test.want) {
+			t.Errorf("%v: got overwrites %v, want %v", test.name, got, test.want)
+		}
+	}
+}
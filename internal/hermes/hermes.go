@@ -2,51 +2,337 @@ package hermes
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
+	"sync"
+	"time"
 
 	"github.com/andersfylling/disgord"
+	"github.com/hitecherik/Tabulatron/internal/chat"
+	"github.com/hitecherik/Tabulatron/internal/db"
+	"github.com/hitecherik/Tabulatron/internal/scheduler"
 )
 
-const bufferSize int = 16
+const (
+	bufferSize     int           = 16
+	defaultRetries int           = 3
+	defaultBackoff time.Duration = time.Second
+)
+
+// ErrClosed is returned for messages sent after Wait, which are never
+// delivered.
+var ErrClosed = errors.New("the messenger has stopped sending messages")
 
 type Hermes struct {
-	client   *disgord.Client
-	queue    chan message
-	finished chan struct{}
+	scheduler *scheduler.Scheduler
+	queue     chan message
+	finished  chan struct{}
+	retries   int
+	backoff   time.Duration
+	outbox    *db.Database
+	languages *db.Database
+	mutex     sync.Mutex
+	report    Report
+	closing   sync.RWMutex
+	closed    bool
 }
 
 type message struct {
+	key     string
 	to      disgord.Snowflake
 	content string
+	done    func(Delivery)
+}
+
+// Localised renders a message in the recipient's chosen language, which is
+// empty if they haven't chosen one.
+type Localised func(language string) string
+
+type Delivery struct {
+	To       disgord.Snowflake
+	Sent     bool
+	Skipped  bool
+	Attempts int
+	Err      error
+}
+
+type Report struct {
+	Sent    int
+	Skipped int
+	Retried int
+	Failed  []Delivery
+}
+
+func New(s *scheduler.Scheduler) *Hermes {
+	return &Hermes{
+		scheduler: s,
+		queue:     make(chan message, bufferSize),
+		finished:  make(chan struct{}, 1),
+		retries:   defaultRetries,
+		backoff:   defaultBackoff,
+	}
+}
+
+// SetOutbox records messages sent with SendOnce in the database, so that they
+// aren't sent again if a command is re-run. Messages that failed are tried
+// again, unless Discord refused them for good (e.g. because the recipient has
+// closed their DMs).
+func (h *Hermes) SetOutbox(database *db.Database) {
+	h.outbox = database
 }
 
-func New(client *disgord.Client) *Hermes {
-	return &Hermes{client, make(chan message, bufferSize), make(chan struct{}, 1)}
+// SetLanguages looks up each recipient's language for SendLocalised.
+func (h *Hermes) SetLanguages(database *db.Database) {
+	h.languages = database
 }
 
+func (h *Hermes) SetRetries(retries int, backoff time.Duration) {
+	h.retries = retries
+	h.backoff = backoff
+}
+
+// Listen delivers queued messages with one worker per bulk client in the
+// scheduler, so all clients should be added to it beforehand. If there are no
+// bulk clients, a single worker fails every message so that Wait still returns.
 func (h *Hermes) Listen() {
+	var wg sync.WaitGroup
+
+	workers := h.scheduler.BulkLen()
+	if workers == 0 {
+		workers = 1
+	}
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			h.work()
+			wg.Done()
+		}()
+	}
+
+	wg.Wait()
+	h.finished <- struct{}{}
+}
+
+func (h *Hermes) work() {
 	for message := range h.queue {
-		channel, err := h.client.CreateDM(context.Background(), message.to)
-		if err != nil {
-			log.Printf("error creating DM: %v", err.Error())
-			continue
+		delivery := h.deliver(message)
+
+		if message.key != "" && h.outbox != nil {
+			status := db.OutboxSent
+			if chat.IsPermanent(delivery.Err) {
+				status = db.OutboxUndeliverable
+			} else if !delivery.Sent {
+				status = db.OutboxFailed
+			}
+
+			if err := h.outbox.UpdateMessage(message.key, status, delivery.Attempts, delivery.Err); err != nil {
+				log.Printf("error updating outbox: %v", err.Error())
+			}
+		}
+
+		h.complete(message, delivery)
+	}
+}
+
+func (h *Hermes) complete(message message, delivery Delivery) {
+	h.mutex.Lock()
+	h.report.Add(delivery)
+	h.mutex.Unlock()
+
+	if message.done != nil {
+		message.done(delivery)
+	}
+}
+
+func (h *Hermes) deliver(message message) Delivery {
+	delivery := Delivery{To: message.to}
+	backoff := h.backoff
+
+	for {
+		delivery.Attempts += 1
+		delivery.Err = h.send(message)
+
+		if delivery.Err == nil {
+			delivery.Sent = true
+			return delivery
 		}
 
-		_, err = channel.SendMsgString(context.Background(), h.client, message.content)
+		if chat.IsPermanent(delivery.Err) || errors.Is(delivery.Err, scheduler.ErrNoBulkClients) || delivery.Attempts > h.retries {
+			log.Printf("error messaging %v: %v", message.to, delivery.Err.Error())
+			return delivery
+		}
+
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+func (h *Hermes) send(message message) (err error) {
+	index, client, err := h.scheduler.AcquireBulk()
+	if err != nil {
+		return err
+	}
+
+	started := time.Now()
+	defer func() {
+		h.scheduler.Release(index, started, err)
+	}()
+
+	channel, err := client.CreateDM(context.Background(), message.to)
+	if err != nil {
+		return err
+	}
+
+	_, err = client.SendMessage(context.Background(), channel.ID, message.content)
+	return err
+}
+
+func (h *Hermes) SendMessage(to disgord.Snowflake, content string) error {
+	return h.enqueue(message{"", to, content, nil})
+}
+
+// Send is like SendMessage, but calls done with the outcome once the message
+// has been delivered or given up on.
+func (h *Hermes) Send(to disgord.Snowflake, content string, done func(Delivery)) error {
+	return h.enqueue(message{"", to, content, done})
+}
+
+// SendOnce is like Send, but skips the message if the outbox says it has
+// already been delivered or can't ever be.
+func (h *Hermes) SendOnce(key string, to disgord.Snowflake, content string, done func(Delivery)) error {
+	message := message{key, to, content, done}
+
+	if h.outbox != nil {
+		status, err := h.outbox.QueueMessage(key, to.String(), content)
 		if err != nil {
-			log.Printf("error sending message: %v", err.Error())
-			continue
+			log.Printf("error queueing message in outbox: %v", err.Error())
+		} else if status != db.OutboxPending {
+			h.complete(message, Delivery{To: to, Sent: status == db.OutboxSent, Skipped: true})
+			return nil
 		}
 	}
 
-	h.finished <- struct{}{}
+	return h.enqueue(message)
+}
+
+func (h *Hermes) SendLocalised(to disgord.Snowflake, content Localised, done func(Delivery)) error {
+	return h.Send(to, content(h.language(to)), done)
+}
+
+func (h *Hermes) SendLocalisedOnce(key string, to disgord.Snowflake, content Localised, done func(Delivery)) error {
+	return h.SendOnce(key, to, content(h.language(to)), done)
+}
+
+// enqueue hands message to the workers, or fails it with ErrClosed once Wait
+// has been called.
+func (h *Hermes) enqueue(message message) error {
+	h.closing.RLock()
+	if !h.closed {
+		h.queue <- message
+		h.closing.RUnlock()
+		return nil
+	}
+	h.closing.RUnlock()
+
+	if message.done != nil {
+		message.done(Delivery{To: message.to, Err: ErrClosed})
+	}
+
+	return ErrClosed
+}
+
+func (h *Hermes) language(to disgord.Snowflake) string {
+	if h.languages == nil {
+		return ""
+	}
+
+	language, err := h.languages.LanguageFromDiscord(to.String())
+	if err != nil {
+		log.Printf("error finding language for %v: %v", to, err.Error())
+	}
+
+	return language
 }
 
-func (h *Hermes) SendMessage(to disgord.Snowflake, content string) {
-	h.queue <- message{to, content}
+// Wait stops accepting messages and returns the report once everything already
+// queued has been delivered or given up on.
+func (h *Hermes) Wait() Report {
+	h.closing.Lock()
+	closed := h.closed
+	h.closed = true
+	h.closing.Unlock()
+
+	if !closed {
+		close(h.queue)
+		<-h.finished
+	}
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	return h.report
+}
+
+func (r *Report) Add(delivery Delivery) {
+	if delivery.Attempts > 1 {
+		r.Retried += 1
+	}
+
+	if delivery.Skipped {
+		r.Skipped += 1
+	} else if delivery.Sent {
+		r.Sent += 1
+	} else {
+		r.Failed = append(r.Failed, delivery)
+	}
 }
 
-func (h *Hermes) Wait() {
-	close(h.queue)
-	<-h.finished
+func (r *Report) Merge(other Report) {
+	r.Sent += other.Sent
+	r.Skipped += other.Skipped
+	r.Retried += other.Retried
+	r.Failed = append(r.Failed, other.Failed...)
+}
+
+func (r Report) Summary() string {
+	return fmt.Sprintf(
+		"Sent %v messages (%v needed retrying), skipped %v already sent or undeliverable, %v failed",
+		r.Sent,
+		r.Retried,
+		r.Skipped,
+		len(r.Failed),
+	)
+}
+
+// DescribeFailures lists who didn't get their message and why, so that they can
+// be contacted manually.
+func DescribeFailures(database *db.Database, failed []Delivery) []string {
+	discords := make([]string, 0, len(failed))
+	for _, delivery := range failed {
+		discords = append(discords, delivery.To.String())
+	}
+
+	names, err := database.NamesFromDiscords(discords)
+	if err != nil {
+		log.Printf("error fetching names: %v", err.Error())
+	}
+
+	lines := make([]string, 0, len(failed))
+	for _, delivery := range failed {
+		name, ok := names[delivery.To.String()]
+		if !ok {
+			name = "unknown participant"
+		}
+
+		reason := "couldn't be reached"
+		if chat.IsPermanent(delivery.Err) {
+			reason = "has closed their DMs"
+		}
+
+		lines = append(lines, fmt.Sprintf("• %v (<@%v>) %v", name, delivery.To, reason))
+	}
+
+	return lines
 }
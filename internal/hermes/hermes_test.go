@@ -0,0 +1,108 @@
+package hermes
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/andersfylling/disgord"
+	"github.com/hitecherik/Tabulatron/internal/chat/simguild"
+	"github.com/hitecherik/Tabulatron/internal/db"
+	"github.com/hitecherik/Tabulatron/internal/scheduler"
+)
+
+func TestWaitReturnsWithoutBulkClients(t *testing.T) {
+	guild := simguild.New()
+	member := guild.AddMember("speaker")
+
+	bots := scheduler.New()
+	bots.AddReservedClient(guild)
+
+	h := New(bots)
+	go h.Listen()
+
+	h.SendMessage(member.User.ID, "hello")
+	report := h.Wait()
+
+	if len(report.Failed) != 1 || !errors.Is(report.Failed[0].Err, scheduler.ErrNoBulkClients) {
+		t.Errorf("expected one delivery to fail with ErrNoBulkClients, got %+v", report)
+	}
+
+	if len(guild.DMs(member)) != 0 {
+		t.Errorf("the reserved client sent a DM")
+	}
+}
+
+func TestSendOnceSkipsUndeliverable(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hermes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	database, err := db.New(filepath.Join(dir, "test.db"))
+	if err != nil {
+		t.Fatalf("creating database: %v", err)
+	}
+
+	guild := simguild.New()
+	open := guild.AddMember("open")
+	closed := guild.AddMember("closed")
+	guild.BlockDMs(closed)
+
+	tests := []struct {
+		sent    int
+		skipped int
+		failed  int
+	}{
+		{1, 0, 1},
+		{0, 2, 0},
+	}
+
+	for i, test := range tests {
+		bots := scheduler.New()
+		bots.AddClient(guild)
+
+		h := New(bots)
+		h.SetOutbox(database)
+		go h.Listen()
+
+		for _, member := range []*disgord.Member{open, closed} {
+			h.SendOnce(fmt.Sprintf("test:%v", member.User.ID), member.User.ID, "hello", nil)
+		}
+
+		if report := h.Wait(); report.Sent != test.sent || report.Skipped != test.skipped || len(report.Failed) != test.failed {
+			t.Errorf("run %v got %+v, want %v sent, %v skipped and %v failed", i+1, report, test.sent, test.skipped, test.failed)
+		}
+	}
+
+	if dms := guild.DMs(open); len(dms) != 1 {
+		t.Errorf("got %v DMs, want the message sent once", len(dms))
+	}
+}
+
+func TestSendAfterWait(t *testing.T) {
+	guild := simguild.New()
+	member := guild.AddMember("speaker")
+
+	bots := scheduler.New()
+	bots.AddClient(guild)
+
+	h := New(bots)
+	go h.Listen()
+	h.Wait()
+
+	var delivery Delivery
+	if err := h.Send(member.User.ID, "hello", func(d Delivery) { delivery = d }); !errors.Is(err, ErrClosed) {
+		t.Errorf("got %v sending after Wait, want ErrClosed", err)
+	}
+
+	if !errors.Is(delivery.Err, ErrClosed) || delivery.Sent {
+		t.Errorf("done got %+v, want the delivery failed with ErrClosed", delivery)
+	}
+
+	h.Wait()
+}
@@ -0,0 +1,112 @@
+package interactions
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"time"
+)
+
+const (
+	maxBodySize  int64         = 1 << 20
+	replyTimeout time.Duration = time.Minute
+)
+
+type Handler func(*Interaction) string
+
+// Endpoint receives the interactions Discord sends to the application's
+// interactions endpoint URL. Every command or button press is answered with
+// an ephemeral placeholder straight away, because Discord only waits three
+// seconds, and then edited to the handler's reply.
+type Endpoint struct {
+	client    *Client
+	publicKey ed25519.PublicKey
+	handle    Handler
+}
+
+func NewEndpoint(client *Client, publicKey ed25519.PublicKey, handle Handler) *Endpoint {
+	return &Endpoint{
+		client:    client,
+		publicKey: publicKey,
+		handle:    handle,
+	}
+}
+
+func (e *Endpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxBodySize))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if !e.verify(r.Header.Get("X-Signature-Ed25519"), r.Header.Get("X-Signature-Timestamp"), body) {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	var interaction Interaction
+	if err := json.Unmarshal(body, &interaction); err != nil {
+		log.Printf("error decoding interaction: %v", err.Error())
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if interaction.Type == TypePing {
+		e.respond(w, response{Type: responsePong})
+		return
+	}
+
+	e.respond(w, response{Type: responseDeferredMessage, Data: &message{Flags: flagEphemeral}})
+
+	go func() {
+		reply := e.handle(&interaction)
+
+		ctx, cancel := context.WithTimeout(context.Background(), replyTimeout)
+		defer cancel()
+
+		if err := e.client.EditResponse(ctx, interaction.Token, reply); err != nil {
+			log.Printf("error replying to interaction %v: %v", interaction.Id, err.Error())
+		}
+	}()
+}
+
+func (e *Endpoint) verify(signature string, timestamp string, body []byte) bool {
+	decoded, err := hex.DecodeString(signature)
+	if err != nil || len(decoded) != ed25519.SignatureSize {
+		return false
+	}
+
+	return ed25519.Verify(e.publicKey, append([]byte(timestamp), body...), decoded)
+}
+
+func (e *Endpoint) respond(w http.ResponseWriter, r response) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(r); err != nil {
+		log.Printf("error responding to interaction: %v", err.Error())
+	}
+}
+
+// ParsePublicKey decodes the hex-encoded public key from the Discord developer
+// portal.
+func ParsePublicKey(key string) (ed25519.PublicKey, error) {
+	decoded, err := hex.DecodeString(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(decoded) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("public key should be %v bytes long, but is %v", ed25519.PublicKeySize, len(decoded))
+	}
+
+	return ed25519.PublicKey(decoded), nil
+}
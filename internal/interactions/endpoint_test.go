@@ -0,0 +1,118 @@
+package interactions
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEndpoint(t *testing.T) {
+	public, private, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	edits := make(chan string, 1)
+	discord := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var edited message
+		body, _ := ioutil.ReadAll(r.Body)
+		if err := json.Unmarshal(body, &edited); err != nil {
+			t.Errorf("decoding edit: %v", err)
+		}
+
+		edits <- r.Method + " " + r.URL.Path + " " + edited.Content
+	}))
+	t.Cleanup(discord.Close)
+
+	client := NewClient("token", 42)
+	client.SetApiUrl(discord.URL)
+
+	endpoint := NewEndpoint(client, public, func(interaction *Interaction) string {
+		return "hello " + interaction.Data.Name
+	})
+
+	sign := func(timestamp string, body string) string {
+		return hex.EncodeToString(ed25519.Sign(private, []byte(timestamp+body)))
+	}
+
+	const (
+		command   = `{"id":"1","type":2,"data":{"name":"language"},"token":"abc"}`
+		ping      = `{"type":1}`
+		timestamp = "1600000000"
+	)
+
+	tests := []struct {
+		name      string
+		body      string
+		signature string
+		status    int
+		response  int
+		edit      string
+	}{
+		{"valid signature", command, sign(timestamp, command), http.StatusOK, responseDeferredMessage, "PATCH /webhooks/42/abc/messages/@original hello language"},
+		{"ping", ping, sign(timestamp, ping), http.StatusOK, responsePong, ""},
+		{"bad signature", command, sign(timestamp, ping), http.StatusUnauthorized, 0, ""},
+		{"wrong timestamp", command, sign("1600000001", command), http.StatusUnauthorized, 0, ""},
+		{"bad hex", command, "not hex", http.StatusUnauthorized, 0, ""},
+		{"short signature", command, sign(timestamp, command)[:10], http.StatusUnauthorized, 0, ""},
+	}
+
+	for _, test := range tests {
+		request := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(test.body))
+		request.Header.Set("X-Signature-Ed25519", test.signature)
+		request.Header.Set("X-Signature-Timestamp", timestamp)
+
+		recorder := httptest.NewRecorder()
+		endpoint.ServeHTTP(recorder, request)
+
+		if recorder.Code != test.status {
+			t.Errorf("%v: got status %v, want %v", test.name, recorder.Code, test.status)
+			continue
+		}
+
+		if test.status != http.StatusOK {
+			continue
+		}
+
+		var got response
+		if err := json.Unmarshal(recorder.Body.Bytes(), &got); err != nil || got.Type != test.response {
+			t.Errorf("%v: got response %v (%v), want type %v", test.name, recorder.Body.String(), err, test.response)
+		}
+
+		if test.edit == "" {
+			continue
+		}
+
+		select {
+		case edit := <-edits:
+			if edit != test.edit {
+				t.Errorf("%v: got edit %q, want %q", test.name, edit, test.edit)
+			}
+		case <-time.After(2 * time.Second):
+			t.Errorf("%v: the placeholder was never edited", test.name)
+		}
+	}
+
+	select {
+	case edit := <-edits:
+		t.Errorf("got unexpected edit %q", edit)
+	default:
+	}
+}
+
+func TestEndpointRejectsOtherMethods(t *testing.T) {
+	endpoint := NewEndpoint(NewClient("token", 42), nil, nil)
+
+	recorder := httptest.NewRecorder()
+	endpoint.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if recorder.Code != http.StatusMethodNotAllowed {
+		t.Errorf("got status %v for a GET, want %v", recorder.Code, http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,179 @@
+package interactions
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/andersfylling/disgord"
+)
+
+const defaultApiUrl string = "https://discord.com/api/v8"
+
+const (
+	TypePing               int = 1
+	TypeApplicationCommand int = 2
+	TypeMessageComponent   int = 3
+
+	OptionString int = 3
+
+	responsePong            int = 1
+	responseDeferredMessage int = 5
+	flagEphemeral           int = 1 << 6
+	componentActionRow      int = 1
+	componentButton         int = 2
+	buttonPrimary           int = 1
+)
+
+// Client makes the REST requests for application commands and components,
+// which this version of disgord doesn't know about.
+type Client struct {
+	token         string
+	applicationId disgord.Snowflake
+	apiUrl        string
+	client        *http.Client
+}
+
+type Command struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Options     []Option `json:"options,omitempty"`
+}
+
+type Option struct {
+	Type        int    `json:"type"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Required    bool   `json:"required"`
+}
+
+type Button struct {
+	Label    string
+	CustomId string
+}
+
+type Interaction struct {
+	Id        disgord.Snowflake `json:"id"`
+	Type      int               `json:"type"`
+	Data      Data              `json:"data"`
+	GuildID   disgord.Snowflake `json:"guild_id"`
+	ChannelID disgord.Snowflake `json:"channel_id"`
+	Member    *disgord.Member   `json:"member"`
+	Token     string            `json:"token"`
+}
+
+type Data struct {
+	Name     string        `json:"name"`
+	CustomId string        `json:"custom_id"`
+	Options  []OptionValue `json:"options"`
+}
+
+type OptionValue struct {
+	Name  string      `json:"name"`
+	Value interface{} `json:"value"`
+}
+
+type component struct {
+	Type       int         `json:"type"`
+	Style      int         `json:"style,omitempty"`
+	Label      string      `json:"label,omitempty"`
+	CustomId   string      `json:"custom_id,omitempty"`
+	Components []component `json:"components,omitempty"`
+}
+
+type allowedMentions struct {
+	Parse []string `json:"parse"`
+}
+
+type message struct {
+	Content         string           `json:"content,omitempty"`
+	Components      []component      `json:"components,omitempty"`
+	Flags           int              `json:"flags,omitempty"`
+	AllowedMentions *allowedMentions `json:"allowed_mentions,omitempty"`
+}
+
+type response struct {
+	Type int      `json:"type"`
+	Data *message `json:"data,omitempty"`
+}
+
+func NewClient(token string, applicationId disgord.Snowflake) *Client {
+	return &Client{
+		token:         token,
+		applicationId: applicationId,
+		apiUrl:        defaultApiUrl,
+		client:        &http.Client{},
+	}
+}
+
+func (c *Client) SetApiUrl(url string) {
+	c.apiUrl = url
+}
+
+// RegisterCommands replaces the guild's application commands with commands.
+// Guild commands are available straight away, unlike global ones.
+func (c *Client) RegisterCommands(ctx context.Context, guild disgord.Snowflake, commands []Command) error {
+	return c.request(ctx, http.MethodPut, fmt.Sprintf("applications/%v/guilds/%v/commands", c.applicationId, guild), commands)
+}
+
+func (c *Client) SendButton(ctx context.Context, channel disgord.Snowflake, content string, button Button) error {
+	return c.request(ctx, http.MethodPost, fmt.Sprintf("channels/%v/messages", channel), message{
+		Content: content,
+		Components: []component{{
+			Type:       componentActionRow,
+			Components: []component{{Type: componentButton, Style: buttonPrimary, Label: button.Label, CustomId: button.CustomId}},
+		}},
+	})
+}
+
+// EditResponse replaces the placeholder the endpoint sent for an interaction
+// with the actual reply.
+func (c *Client) EditResponse(ctx context.Context, token string, content string) error {
+	return c.request(ctx, http.MethodPatch, fmt.Sprintf("webhooks/%v/%v/messages/@original", c.applicationId, token), message{
+		Content:         content,
+		AllowedMentions: &allowedMentions{Parse: []string{}},
+	})
+}
+
+func (c *Client) request(ctx context.Context, method string, path string, body interface{}) error {
+	encoded, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, method, fmt.Sprintf("%v/%v", c.apiUrl, path), bytes.NewReader(encoded))
+	if err != nil {
+		return err
+	}
+
+	request.Header.Set("Authorization", fmt.Sprintf("Bot %v", c.token))
+	request.Header.Set("Content-Type", "application/json")
+
+	response, err := c.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode >= 300 {
+		text, _ := ioutil.ReadAll(response.Body)
+		return fmt.Errorf("discord responded to %v %v with status %v: %v", method, path, response.StatusCode, string(text))
+	}
+
+	return nil
+}
+
+// Option returns the value of the named option as a string, or an empty
+// string if it wasn't given.
+func (d Data) Option(name string) string {
+	for _, option := range d.Options {
+		if option.Name == name {
+			return fmt.Sprint(option.Value)
+		}
+	}
+
+	return ""
+}
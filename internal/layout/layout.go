@@ -0,0 +1,172 @@
+package layout
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/andersfylling/disgord"
+	"github.com/pelletier/go-toml"
+)
+
+const (
+	SpeakerRole string = "speaker"
+	JudgeRole   string = "judge"
+	TabRole     string = "tab"
+
+	RegistrationChannel     string = "registration"
+	RegistrationHelpChannel string = "registration-help"
+	CheckinChannel          string = "checkin"
+	AvailabilityChannel     string = "availability"
+	HelpChannel             string = "help"
+	DrawChannel             string = "draw"
+	TabChannel              string = "tab"
+	WaitingRoomChannel      string = "waiting-room"
+)
+
+var (
+	defaultRoles map[string]string = map[string]string{
+		SpeakerRole: "Speaker",
+		JudgeRole:   "Judge",
+		TabRole:     "Tab/Tech",
+	}
+	defaultChannels map[string]string = map[string]string{
+		RegistrationChannel:     "registration",
+		RegistrationHelpChannel: "registration-help",
+		CheckinChannel:          "checkin",
+		AvailabilityChannel:     "adjudicator-availability",
+		HelpChannel:             "tab-and-tech-help",
+		DrawChannel:             "motions-and-draw",
+		TabChannel:              "tab",
+		WaitingRoomChannel:      "Waiting room",
+	}
+)
+
+// Layout maps the roles and channels the bot relies on to their names or IDs
+// in the guild, e.g.
+//
+//	guild = "123456789012345678"
+//
+//	[roles]
+//	tab = "Tab Team"
+//
+//	[channels]
+//	draw = "987654321098765432"
+//
+// Anything left out keeps its usual name.
+type Layout struct {
+	path     string
+	guild    string
+	roles    map[string]string
+	channels map[string]string
+}
+
+type rawLayout struct {
+	Guild    string            `toml:"guild"`
+	Roles    map[string]string `toml:"roles"`
+	Channels map[string]string `toml:"channels"`
+}
+
+func (l *Layout) String() string {
+	return l.path
+}
+
+func (l *Layout) Set(path string) error {
+	tree, err := toml.LoadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var raw rawLayout
+	if err := tree.Unmarshal(&raw); err != nil {
+		return err
+	}
+
+	for _, key := range tree.Keys() {
+		if key != "guild" && key != "roles" && key != "channels" {
+			return fmt.Errorf("unknown setting \"%v\" in %v", key, path)
+		}
+	}
+
+	for name := range raw.Roles {
+		if _, ok := defaultRoles[name]; !ok {
+			return fmt.Errorf("unknown role \"%v\" in %v", name, path)
+		}
+	}
+
+	for name := range raw.Channels {
+		if _, ok := defaultChannels[name]; !ok {
+			return fmt.Errorf("unknown channel \"%v\" in %v", name, path)
+		}
+	}
+
+	l.path = path
+	l.guild = raw.Guild
+	l.roles = raw.Roles
+	l.channels = raw.Channels
+	return nil
+}
+
+// Guild is empty unless the layout pins the bot to one guild.
+func (l *Layout) Guild() string {
+	if l == nil {
+		return ""
+	}
+
+	return l.guild
+}
+
+func (l *Layout) Role(name string) string {
+	if l != nil && l.roles[name] != "" {
+		return l.roles[name]
+	}
+
+	return defaultRoles[name]
+}
+
+func (l *Layout) Channel(name string) string {
+	if l != nil && l.channels[name] != "" {
+		return l.channels[name]
+	}
+
+	return defaultChannels[name]
+}
+
+func (l *Layout) IsRole(name string, role *disgord.Role) bool {
+	configured := l.Role(name)
+	return configured == role.Name || configured == role.ID.String()
+}
+
+func (l *Layout) IsChannel(name string, channel *disgord.Channel) bool {
+	configured := l.Channel(name)
+	return configured == channel.Name || configured == channel.ID.String()
+}
+
+// Check lists every role and channel that can't be found in the guild.
+func (l *Layout) Check(roles []*disgord.Role, channels []*disgord.Channel) []string {
+	missing := []string{}
+
+	for name := range defaultRoles {
+		found := false
+		for _, role := range roles {
+			found = found || l.IsRole(name, role)
+		}
+
+		if !found {
+			missing = append(missing, fmt.Sprintf("%v role \"%v\"", name, l.Role(name)))
+		}
+	}
+
+	for name := range defaultChannels {
+		found := false
+		for _, channel := range channels {
+			found = found || l.IsChannel(name, channel)
+		}
+
+		if !found {
+			missing = append(missing, fmt.Sprintf("%v channel \"%v\"", name, l.Channel(name)))
+		}
+	}
+
+	sort.Strings(missing)
+	return missing
+}
@@ -0,0 +1,112 @@
+package layout
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/andersfylling/disgord"
+)
+
+func load(t *testing.T, contents string) (*Layout, error) {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "layout")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	file := filepath.Join(dir, "layout.toml")
+	if err := ioutil.WriteFile(file, []byte(contents), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var l Layout
+	return &l, l.Set(file)
+}
+
+func TestSet(t *testing.T) {
+	tests := []struct {
+		name     string
+		contents string
+		fails    bool
+	}{
+		{"overrides", "guild = \"1\"\n[roles]\ntab = \"Adjudication Core\"\n[channels]\ndraw = \"42\"\n", false},
+		{"empty", "", false},
+		{"unknown setting", "server = \"1\"\n", true},
+		{"unknown role", "[roles]\nchief = \"Chief\"\n", true},
+		{"unknown channel", "[channels]\nlobby = \"lobby\"\n", true},
+	}
+
+	for _, test := range tests {
+		if _, err := load(t, test.contents); (err != nil) != test.fails {
+			t.Errorf("%v: got %v, want an error: %v", test.name, err, test.fails)
+		}
+	}
+}
+
+func TestIsRoleAndIsChannel(t *testing.T) {
+	l, err := load(t, "[roles]\ntab = \"Adjudication Core\"\njudge = \"500\"\n[channels]\ndraw = \"42\"\n")
+	if err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+
+	roles := []struct {
+		layout *Layout
+		name   string
+		role   *disgord.Role
+		want   bool
+	}{
+		{l, TabRole, &disgord.Role{ID: 1, Name: "Adjudication Core"}, true},
+		{l, TabRole, &disgord.Role{ID: 2, Name: "Tab/Tech"}, false},
+		{l, JudgeRole, &disgord.Role{ID: 500, Name: "Adjudicator"}, true},
+		{l, JudgeRole, &disgord.Role{ID: 3, Name: "500"}, true},
+		{l, SpeakerRole, &disgord.Role{ID: 4, Name: "Speaker"}, true},
+		{l, SpeakerRole, &disgord.Role{ID: 5, Name: "speaker"}, false},
+		{nil, TabRole, &disgord.Role{ID: 6, Name: "Tab/Tech"}, true},
+	}
+
+	for _, test := range roles {
+		if got := test.layout.IsRole(test.name, test.role); got != test.want {
+			t.Errorf("IsRole(%q, %v %q) = %v, want %v", test.name, test.role.ID, test.role.Name, got, test.want)
+		}
+	}
+
+	channels := []struct {
+		layout  *Layout
+		name    string
+		channel *disgord.Channel
+		want    bool
+	}{
+		{l, DrawChannel, &disgord.Channel{ID: 42, Name: "announcements"}, true},
+		{l, DrawChannel, &disgord.Channel{ID: 43, Name: "motions-and-draw"}, false},
+		{l, WaitingRoomChannel, &disgord.Channel{ID: 44, Name: "Waiting room"}, true},
+		{nil, HelpChannel, &disgord.Channel{ID: 45, Name: "tab-and-tech-help"}, true},
+	}
+
+	for _, test := range channels {
+		if got := test.layout.IsChannel(test.name, test.channel); got != test.want {
+			t.Errorf("IsChannel(%q, %v %q) = %v, want %v", test.name, test.channel.ID, test.channel.Name, got, test.want)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	var l *Layout
+
+	roles := []*disgord.Role{{ID: 1, Name: "Speaker"}, {ID: 2, Name: "Judge"}, {ID: 3, Name: "Tab/Tech"}}
+	channels := []*disgord.Channel{}
+	for name, channel := range defaultChannels {
+		if name != DrawChannel {
+			channels = append(channels, &disgord.Channel{ID: disgord.Snowflake(len(channels) + 10), Name: channel})
+		}
+	}
+
+	want := []string{"draw channel \"motions-and-draw\""}
+	if got := l.Check(roles, channels); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v missing, want %v", got, want)
+	}
+}
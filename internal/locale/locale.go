@@ -0,0 +1,124 @@
+package locale
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pelletier/go-toml"
+)
+
+const Default string = "en"
+
+// Catalogue translates the bot's fixed replies, keyed by their English format
+// strings, e.g.
+//
+//	[fr]
+//	"you can't ask me to do that." = "vous ne pouvez pas me demander ça."
+//
+// Anything missing from it is sent in English.
+type Catalogue struct {
+	path         string
+	translations map[string]map[string]string
+}
+
+func (c *Catalogue) String() string {
+	return c.path
+}
+
+func (c *Catalogue) Set(path string) error {
+	tree, err := toml.LoadFile(path)
+	if err != nil {
+		return err
+	}
+
+	translations := make(map[string]map[string]string)
+	for language, value := range tree.ToMap() {
+		table, ok := value.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("expected a table of translations for \"%v\" in %v", language, path)
+		}
+
+		translations[Normalise(language)] = make(map[string]string, len(table))
+		for msgid, translation := range table {
+			text, ok := translation.(string)
+			if !ok {
+				return fmt.Errorf("translation of \"%v\" into %v isn't a string", msgid, language)
+			}
+
+			translations[Normalise(language)][msgid] = text
+		}
+	}
+
+	c.path = path
+	c.translations = translations
+	return nil
+}
+
+func (c *Catalogue) Translate(language string, msgid string) string {
+	if c == nil {
+		return msgid
+	}
+
+	if translation := c.translations[Normalise(language)][msgid]; translation != "" {
+		return translation
+	}
+
+	return msgid
+}
+
+func (c *Catalogue) Has(language string) bool {
+	if c == nil {
+		return false
+	}
+
+	_, ok := c.translations[Normalise(language)]
+	return ok
+}
+
+func (c *Catalogue) Languages() []string {
+	languages := []string{}
+	if c == nil {
+		return languages
+	}
+
+	for language := range c.translations {
+		languages = append(languages, language)
+	}
+	sort.Strings(languages)
+
+	return languages
+}
+
+func Normalise(language string) string {
+	return strings.ToLower(strings.TrimSpace(language))
+}
+
+var verb *regexp.Regexp = regexp.MustCompile(`%[-+# 0]*[0-9]*(?:\.[0-9]+)?[a-zA-Z%]`)
+
+// Validate checks that every translation has the same formatting verbs as the
+// reply it translates, since they're filled in with the same arguments.
+func (c *Catalogue) Validate() error {
+	var problems []string
+
+	for _, language := range c.Languages() {
+		for msgid, translation := range c.translations[language] {
+			if translation == "" {
+				continue
+			}
+
+			expected := strings.Join(verb.FindAllString(msgid, -1), " ")
+			if actual := strings.Join(verb.FindAllString(translation, -1), " "); actual != expected {
+				problems = append(problems, fmt.Sprintf("%v: \"%v\" uses [%v] but its translation uses [%v]", language, msgid, expected, actual))
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return fmt.Errorf("%v translations don't match:\n%v", len(problems), strings.Join(problems, "\n"))
+	}
+
+	return nil
+}
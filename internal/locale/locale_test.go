@@ -0,0 +1,135 @@
+package locale
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func load(t *testing.T, contents string) (*Catalogue, error) {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "locale")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	file := filepath.Join(dir, "locale.toml")
+	if err := ioutil.WriteFile(file, []byte(contents), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var catalogue Catalogue
+	return &catalogue, catalogue.Set(file)
+}
+
+func TestNormalise(t *testing.T) {
+	tests := []struct {
+		language string
+		want     string
+	}{
+		{"fr", "fr"},
+		{"FR", "fr"},
+		{"  pt-BR\t", "pt-br"},
+		{"", ""},
+	}
+
+	for _, test := range tests {
+		if got := Normalise(test.language); got != test.want {
+			t.Errorf("Normalise(%q) = %q, want %q", test.language, got, test.want)
+		}
+	}
+}
+
+func TestTranslate(t *testing.T) {
+	catalogue, err := load(t, `
+[FR]
+"you're now checked %v." = "vous êtes maintenant %v."
+"there was an error." = ""
+
+[de]
+"you're now checked %v." = "du bist jetzt %v."
+`)
+	if err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+
+	tests := []struct {
+		language string
+		msgid    string
+		want     string
+	}{
+		{"fr", "you're now checked %v.", "vous êtes maintenant %v."},
+		{" Fr ", "you're now checked %v.", "vous êtes maintenant %v."},
+		{"de", "you're now checked %v.", "du bist jetzt %v."},
+		{"fr", "there was an error.", "there was an error."},
+		{"fr", "something untranslated.", "something untranslated."},
+		{"es", "you're now checked %v.", "you're now checked %v."},
+		{"", "you're now checked %v.", "you're now checked %v."},
+	}
+
+	for _, test := range tests {
+		if got := catalogue.Translate(test.language, test.msgid); got != test.want {
+			t.Errorf("Translate(%q, %q) = %q, want %q", test.language, test.msgid, got, test.want)
+		}
+	}
+
+	if languages := catalogue.Languages(); !reflect.DeepEqual(languages, []string{"de", "fr"}) {
+		t.Errorf("got languages %v", languages)
+	}
+
+	if !catalogue.Has("FR") || catalogue.Has("es") {
+		t.Errorf("Has got the wrong languages")
+	}
+
+	var none *Catalogue
+	if got := none.Translate("fr", "hello"); got != "hello" || none.Has("fr") || len(none.Languages()) != 0 {
+		t.Errorf("a nil Catalogue translated %q", got)
+	}
+}
+
+func TestSet(t *testing.T) {
+	tests := []struct {
+		name     string
+		contents string
+		fails    bool
+	}{
+		{"tables", "[fr]\n\"hello\" = \"bonjour\"\n", false},
+		{"not a table", "fr = \"bonjour\"\n", true},
+		{"not a string", "[fr]\n\"hello\" = 1\n", true},
+		{"not TOML", "[fr\n", true},
+	}
+
+	for _, test := range tests {
+		if _, err := load(t, test.contents); (err != nil) != test.fails {
+			t.Errorf("%v: got %v, want an error: %v", test.name, err, test.fails)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		contents string
+		fails    bool
+	}{
+		{"matching verbs", "[fr]\n\"%v of %v rooms\" = \"%v sur %v salles\"\n", false},
+		{"untranslated", "[fr]\n\"%v rooms\" = \"\"\n", false},
+		{"missing verb", "[fr]\n\"%v rooms\" = \"des salles\"\n", true},
+		{"different verb", "[fr]\n\"%v rooms\" = \"%d salles\"\n", true},
+	}
+
+	for _, test := range tests {
+		catalogue, err := load(t, test.contents)
+		if err != nil {
+			t.Fatalf("%v: Set: %v", test.name, err)
+		}
+
+		if err := catalogue.Validate(); (err != nil) != test.fails {
+			t.Errorf("%v: got %v, want an error: %v", test.name, err, test.fails)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package mailer
+
+import (
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+)
+
+type Mailer struct {
+	addr string
+	auth smtp.Auth
+	from string
+}
+
+func New(host string, port string, username string, password string, from string) *Mailer {
+	var auth smtp.Auth
+	if username != "" {
+		auth = smtp.PlainAuth("", username, password, host)
+	}
+
+	return &Mailer{net.JoinHostPort(host, port), auth, from}
+}
+
+func (m *Mailer) Send(to string, subject string, body string) error {
+	headers := []string{
+		fmt.Sprintf("From: %v", m.from),
+		fmt.Sprintf("To: %v", to),
+		fmt.Sprintf("Subject: %v", strings.ReplaceAll(subject, "\n", " ")),
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=UTF-8",
+	}
+
+	message := fmt.Sprintf("%v\r\n\r\n%v\r\n", strings.Join(headers, "\r\n"), strings.ReplaceAll(body, "\n", "\r\n"))
+	return smtp.SendMail(m.addr, m.auth, m.from, []string{to}, []byte(message))
+}
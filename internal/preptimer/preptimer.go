@@ -0,0 +1,402 @@
+package preptimer
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/andersfylling/disgord"
+	"github.com/hitecherik/Tabulatron/internal/chat"
+	"github.com/hitecherik/Tabulatron/internal/db"
+	"github.com/hitecherik/Tabulatron/internal/templates"
+	"github.com/hitecherik/Tabulatron/internal/util"
+)
+
+const (
+	tick         time.Duration = time.Second
+	staleTimeout time.Duration = time.Minute
+)
+
+var (
+	ErrRunning    = errors.New("a prep timer is already running for that round")
+	ErrNotRunning = errors.New("there is no prep timer running for that round")
+	ErrPaused     = errors.New("the prep timer for that round is paused")
+	ErrNotPaused  = errors.New("the prep timer for that round isn't paused")
+)
+
+type Registry struct {
+	discord   chat.Platform
+	database  *db.Database
+	length    time.Duration
+	location  *time.Location
+	templates *templates.Templates
+	mutex     sync.Mutex
+	timers    map[Key]*timer
+	starting  map[Key]bool
+}
+
+// Key identifies a prep timer. Tracks which get their motions at different
+// times each have their own timer, and Track is empty when a round's motions
+// are announced together.
+type Key struct {
+	Round uint64
+	Track string
+}
+
+func NewKey(round uint64, track string) Key {
+	return Key{round, strings.ToLower(track)}
+}
+
+// timer's fields are guarded by the registry's mutex, apart from edited, which
+// is guarded by editing. Discord is only called without the registry's mutex,
+// so version orders the edits and a slow one can't overwrite a newer one.
+type timer struct {
+	state   db.PrepTimer
+	channel disgord.Snowflake
+	message disgord.Snowflake
+	shown   int
+	stop    chan struct{}
+	version int
+	editing sync.Mutex
+	edited  int
+}
+
+func New(discord chat.Platform, database *db.Database, length time.Duration) *Registry {
+	return &Registry{
+		discord:   discord,
+		database:  database,
+		length:    length,
+		location:  time.Local,
+		templates: &templates.Templates{},
+		timers:    make(map[Key]*timer),
+		starting:  make(map[Key]bool),
+	}
+}
+
+func (r *Registry) SetLength(length time.Duration) {
+	r.length = length
+}
+
+func (r *Registry) Length() time.Duration {
+	return r.length
+}
+
+func (r *Registry) SetLocation(location *time.Location) {
+	r.location = location
+}
+
+func (r *Registry) SetTemplates(t *templates.Templates) {
+	r.templates = t
+}
+
+func (r *Registry) Start(key Key, roundName string, channel disgord.Snowflake, length time.Duration) error {
+	if length == 0 {
+		length = r.length
+	}
+
+	r.mutex.Lock()
+	if _, ok := r.timers[key]; ok || r.starting[key] {
+		r.mutex.Unlock()
+		return ErrRunning
+	}
+	r.starting[key] = true
+	r.mutex.Unlock()
+
+	ends := time.Now().Add(length)
+	shown := minutesLeft(length)
+
+	msg, err := r.discord.SendMessage(context.Background(), channel, r.generatePrepTimeMessage(shown, ends))
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	delete(r.starting, key)
+
+	if err != nil {
+		return err
+	}
+
+	t := &timer{
+		state: db.PrepTimer{
+			Round:     key.Round,
+			Track:     key.Track,
+			RoundName: roundName,
+			ChannelId: msg.ChannelID.String(),
+			MessageId: msg.ID.String(),
+			Ends:      ends,
+		},
+		channel: msg.ChannelID,
+		message: msg.ID,
+		shown:   shown,
+		stop:    make(chan struct{}),
+	}
+
+	r.save(t)
+	r.timers[key] = t
+	go r.run(t)
+
+	return nil
+}
+
+func (r *Registry) Pause(key Key) error {
+	r.mutex.Lock()
+
+	t, ok := r.timers[key]
+	if !ok {
+		r.mutex.Unlock()
+		return ErrNotRunning
+	}
+
+	if t.state.Paused {
+		r.mutex.Unlock()
+		return ErrPaused
+	}
+
+	t.state.Remaining = time.Until(t.state.Ends)
+	t.state.Paused = true
+	t.shown = minutesLeft(t.state.Remaining)
+
+	r.save(t)
+	edit := r.prepareEdit(t, r.templates.Render("prep.paused", templates.Prep{Round: t.state.RoundName, Minutes: t.shown}))
+	r.mutex.Unlock()
+
+	edit()
+	return nil
+}
+
+func (r *Registry) Resume(key Key) error {
+	r.mutex.Lock()
+
+	t, ok := r.timers[key]
+	if !ok {
+		r.mutex.Unlock()
+		return ErrNotRunning
+	}
+
+	if !t.state.Paused {
+		r.mutex.Unlock()
+		return ErrNotPaused
+	}
+
+	t.state.Ends = time.Now().Add(t.state.Remaining)
+	t.state.Remaining = 0
+	t.state.Paused = false
+
+	r.save(t)
+	edit := r.prepareEdit(t, r.generatePrepTimeMessage(t.shown, t.state.Ends))
+	r.mutex.Unlock()
+
+	edit()
+	return nil
+}
+
+func (r *Registry) Extend(key Key, by time.Duration) error {
+	r.mutex.Lock()
+
+	t, ok := r.timers[key]
+	if !ok {
+		r.mutex.Unlock()
+		return ErrNotRunning
+	}
+
+	var content string
+	if t.state.Paused {
+		t.state.Remaining += by
+		t.shown = minutesLeft(t.state.Remaining)
+		content = r.templates.Render("prep.paused", templates.Prep{Round: t.state.RoundName, Minutes: t.shown})
+	} else {
+		t.state.Ends = t.state.Ends.Add(by)
+		t.shown = minutesLeft(time.Until(t.state.Ends))
+		content = r.generatePrepTimeMessage(t.shown, t.state.Ends)
+	}
+
+	r.save(t)
+	edit := r.prepareEdit(t, content)
+	r.mutex.Unlock()
+
+	edit()
+	return nil
+}
+
+func (r *Registry) Cancel(key Key) error {
+	r.mutex.Lock()
+
+	t, ok := r.timers[key]
+	if !ok {
+		r.mutex.Unlock()
+		return ErrNotRunning
+	}
+
+	r.remove(t)
+	edit := r.prepareEdit(t, r.templates.Render("prep.cancelled", templates.Prep{Round: t.state.RoundName}))
+	r.mutex.Unlock()
+
+	edit()
+	return nil
+}
+
+func (r *Registry) Restore() error {
+	timers, err := r.database.PrepTimers()
+	if err != nil {
+		return err
+	}
+
+	stale := make([]*timer, 0)
+	defer func() {
+		for _, t := range stale {
+			r.edit(t, r.templates.Render("prep.over", templates.Prep{Round: t.state.RoundName}))
+		}
+	}()
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for _, state := range timers {
+		if _, ok := r.timers[NewKey(state.Round, state.Track)]; ok {
+			continue
+		}
+
+		channel, err := util.StringToSnowflake(state.ChannelId)
+		if err != nil {
+			return err
+		}
+
+		message, err := util.StringToSnowflake(state.MessageId)
+		if err != nil {
+			return err
+		}
+
+		t := &timer{
+			state:   state,
+			channel: channel,
+			message: message,
+			shown:   -1,
+			stop:    make(chan struct{}),
+		}
+
+		if !state.Paused && time.Since(state.Ends) > staleTimeout {
+			log.Printf("dropping prep timer for %v which ended while offline", state.RoundName)
+			if err := r.database.DeletePrepTimer(state.Round, state.Track); err != nil {
+				log.Printf("error deleting prep timer: %v", err.Error())
+			}
+			stale = append(stale, t)
+			continue
+		}
+
+		r.timers[NewKey(state.Round, state.Track)] = t
+		go r.run(t)
+	}
+
+	return nil
+}
+
+func (r *Registry) run(t *timer) {
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-t.stop:
+			return
+		case <-ticker.C:
+		}
+
+		r.mutex.Lock()
+
+		if t.state.Paused {
+			r.mutex.Unlock()
+			continue
+		}
+
+		left := time.Until(t.state.Ends)
+		if left <= 0 {
+			r.remove(t)
+			r.mutex.Unlock()
+			r.finish(t)
+			return
+		}
+
+		shown := minutesLeft(left)
+		if shown == t.shown {
+			r.mutex.Unlock()
+			continue
+		}
+
+		t.shown = shown
+		edit := r.prepareEdit(t, r.generatePrepTimeMessage(shown, t.state.Ends))
+		r.mutex.Unlock()
+
+		edit()
+	}
+}
+
+func (r *Registry) finish(t *timer) {
+	err := r.discord.DeleteMessage(context.Background(), t.channel, t.message)
+	if err != nil {
+		log.Printf("error deleting message: %v", err.Error())
+	}
+
+	_, err = r.discord.SendMessage(
+		context.Background(),
+		t.channel,
+		r.templates.Render("prep.announcement", templates.Prep{Round: t.state.RoundName}),
+	)
+	if err != nil {
+		log.Printf("error sending message: %v", err.Error())
+	}
+}
+
+func (r *Registry) remove(t *timer) {
+	delete(r.timers, NewKey(t.state.Round, t.state.Track))
+	close(t.stop)
+
+	if err := r.database.DeletePrepTimer(t.state.Round, t.state.Track); err != nil {
+		log.Printf("error deleting prep timer: %v", err.Error())
+	}
+}
+
+func (r *Registry) save(t *timer) {
+	if err := r.database.SavePrepTimer(t.state); err != nil {
+		log.Printf("error saving prep timer: %v", err.Error())
+	}
+}
+
+// prepareEdit must be called with the registry's mutex held, and the edit it
+// returns without.
+func (r *Registry) prepareEdit(t *timer, content string) func() {
+	t.version += 1
+	version := t.version
+
+	return func() {
+		t.editing.Lock()
+		defer t.editing.Unlock()
+
+		if version < t.edited {
+			return
+		}
+		t.edited = version
+
+		r.edit(t, content)
+	}
+}
+
+func (r *Registry) edit(t *timer, content string) {
+	_, err := r.discord.EditMessage(context.Background(), t.channel, t.message, content)
+	if err != nil {
+		log.Printf("error updating message: %v", err.Error())
+	}
+}
+
+func minutesLeft(left time.Duration) int {
+	return int((left + time.Minute - 1) / time.Minute)
+}
+
+func (r *Registry) generatePrepTimeMessage(timeLeft int, ends time.Time) string {
+	return r.templates.Render("prep.running", templates.Prep{
+		Minutes:   timeLeft,
+		Starts:    ends.In(r.location).Format("15:04 MST"),
+		Timestamp: util.DiscordTimestamp(ends),
+	})
+}
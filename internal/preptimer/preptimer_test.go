@@ -0,0 +1,122 @@
+package preptimer
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andersfylling/disgord"
+	"github.com/hitecherik/Tabulatron/internal/chat/simguild"
+	"github.com/hitecherik/Tabulatron/internal/db"
+)
+
+// stalling holds up edits to one channel until it's released, the way a
+// rate-limited edit would.
+type stalling struct {
+	*simguild.Guild
+	channel disgord.Snowflake
+	stalled chan struct{}
+	release chan struct{}
+}
+
+func (s *stalling) EditMessage(ctx context.Context, channelId, messageId disgord.Snowflake, content string) (*disgord.Message, error) {
+	if channelId == s.channel {
+		s.stalled <- struct{}{}
+		<-s.release
+	}
+
+	return s.Guild.EditMessage(ctx, channelId, messageId, content)
+}
+
+func newRegistry(t *testing.T) (*Registry, *stalling) {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "preptimer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	database, err := db.New(filepath.Join(dir, "test.db"))
+	if err != nil {
+		t.Fatalf("creating database: %v", err)
+	}
+
+	discord := &stalling{Guild: simguild.New(), stalled: make(chan struct{}, 1), release: make(chan struct{})}
+	return New(discord, database, 15*time.Minute), discord
+}
+
+func TestSlowEditDoesNotStallOtherTimers(t *testing.T) {
+	registry, discord := newRegistry(t)
+	open := discord.AddChannel("open")
+	novice := discord.AddChannel("novice")
+	discord.channel = open.ID
+
+	if err := registry.Start(NewKey(1, "open"), "Round 1 (Open)", open.ID, 0); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+
+	if err := registry.Start(NewKey(1, "novice"), "Round 1 (Novice)", novice.ID, 0); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+
+	paused := make(chan error)
+	go func() { paused <- registry.Pause(NewKey(1, "Open")) }()
+	<-discord.stalled
+
+	done := make(chan error)
+	go func() { done <- registry.Cancel(NewKey(1, "novice")) }()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Cancel: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("cancelling the novice timer waited for the open timer's edit")
+	}
+
+	close(discord.release)
+	if err := <-paused; err != nil {
+		t.Errorf("Pause: %v", err)
+	}
+
+	if err := registry.Pause(NewKey(1, "open")); err != ErrPaused {
+		t.Errorf("pausing twice got %v, want ErrPaused", err)
+	}
+
+	if messages := discord.Messages(open); len(messages) != 1 || !strings.Contains(messages[0].Content, "paused") {
+		t.Errorf("got %v in the open channel", messages)
+	}
+
+	if err := registry.Cancel(NewKey(1, "open")); err != nil {
+		t.Errorf("Cancel: %v", err)
+	}
+}
+
+func TestPrepTimeMessageUsesLocation(t *testing.T) {
+	registry, _ := newRegistry(t)
+	ends := time.Date(2026, time.March, 7, 12, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		location *time.Location
+		want     string
+	}{
+		{time.UTC, "**12:30 UTC**"},
+		{time.FixedZone("EST", -5*60*60), "**07:30 EST**"},
+		{time.FixedZone("IST", 5*60*60+30*60), "**18:00 IST**"},
+	}
+
+	for _, test := range tests {
+		registry.SetLocation(test.location)
+
+		message := registry.generatePrepTimeMessage(15, ends)
+		if !strings.Contains(message, test.want) || !strings.Contains(message, "<t:1772886600:t>") {
+			t.Errorf("in %v, got %q, want the start shown as %v", test.location, message, test.want)
+		}
+	}
+}
@@ -4,18 +4,18 @@ import (
 	"context"
 	"log"
 	"sync"
-	"sync/atomic"
+	"time"
 
 	"github.com/andersfylling/disgord"
+	"github.com/hitecherik/Tabulatron/internal/scheduler"
 )
 
 const bufferSize int = 16
 
 type Pundit struct {
-	clients  []*disgord.Client
-	channels []chan reaction
-	counter  uint64
-	wg       sync.WaitGroup
+	scheduler *scheduler.Scheduler
+	reactions chan reaction
+	wg        sync.WaitGroup
 }
 
 type reaction struct {
@@ -24,35 +24,43 @@ type reaction struct {
 	emoji     string
 }
 
-func (p *Pundit) AddClient(client *disgord.Client) {
-	p.clients = append(p.clients, client)
-	p.channels = append(p.channels, make(chan reaction, bufferSize))
+// New starts one worker per client in the scheduler, so all clients should be
+// added to it beforehand.
+func New(s *scheduler.Scheduler) *Pundit {
+	p := &Pundit{
+		scheduler: s,
+		reactions: make(chan reaction, bufferSize),
+	}
+
+	for i := 0; i < s.Len(); i++ {
+		p.wg.Add(1)
+		go p.listen()
+	}
 
-	p.wg.Add(1)
-	go p.listen(len(p.clients) - 1)
+	return p
 }
 
-func (p *Pundit) listen(client int) {
-	for r := range p.channels[client] {
-		err := p.clients[client].CreateReaction(context.Background(), r.channelId, r.messageId, r.emoji)
+func (p *Pundit) listen() {
+	for r := range p.reactions {
+		index, client := p.scheduler.Acquire()
+		started := time.Now()
+
+		err := client.CreateReaction(context.Background(), r.channelId, r.messageId, r.emoji)
 		if err != nil {
 			log.Printf("Error sending reaction: %+v\n", r)
 		}
+
+		p.scheduler.Release(index, started, err)
 	}
 
 	p.wg.Done()
 }
 
 func (p *Pundit) SendReaction(channelId, messageId disgord.Snowflake, emoji string) {
-	channel := atomic.AddUint64(&p.counter, 1) % uint64(len(p.channels))
-
-	p.channels[channel] <- reaction{channelId, messageId, emoji}
+	p.reactions <- reaction{channelId, messageId, emoji}
 }
 
 func (p *Pundit) Wait() {
-	for _, channel := range p.channels {
-		close(channel)
-	}
-
+	close(p.reactions)
 	p.wg.Wait()
 }
@@ -0,0 +1,213 @@
+package roundmessenger
+
+import (
+	"context"
+	"crypto/sha1"
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/andersfylling/disgord"
+	"github.com/hitecherik/Tabulatron/internal/db"
+	"github.com/hitecherik/Tabulatron/internal/hermes"
+	"github.com/hitecherik/Tabulatron/internal/multiroom"
+	"github.com/hitecherik/Tabulatron/internal/roundrunner"
+	"github.com/hitecherik/Tabulatron/internal/templates"
+	"github.com/hitecherik/Tabulatron/internal/util"
+	"github.com/hitecherik/Tabulatron/pkg/tabbycat"
+)
+
+type Progress struct {
+	Rooms        int
+	TotalRooms   int
+	Messages     int
+	Delivered    int
+	Skipped      int
+	Unregistered []string
+	Failed       []hermes.Delivery
+}
+
+type Messenger struct {
+	tabbycat   *tabbycat.Tabbycat
+	database   *db.Database
+	messenger  *hermes.Hermes
+	categories multiroom.Categories
+	templates  *templates.Templates
+	pending    sync.WaitGroup
+	mutex      sync.Mutex
+	deliveries []hermes.Delivery
+}
+
+func New(t *tabbycat.Tabbycat, database *db.Database, messenger *hermes.Hermes, categories multiroom.Categories) *Messenger {
+	return &Messenger{
+		tabbycat:   t,
+		database:   database,
+		messenger:  messenger,
+		categories: categories,
+		templates:  &templates.Templates{},
+	}
+}
+
+func (m *Messenger) SetTemplates(t *templates.Templates) {
+	m.templates = t
+}
+
+func (m *Messenger) Release(ctx context.Context, rounds []uint64, report func(Progress)) (Progress, error) {
+	var (
+		progress Progress
+		rooms    []tabbycat.Room
+		roundIds []uint64
+	)
+
+	for _, round := range rounds {
+		r, err := m.tabbycat.GetDrawContext(ctx, round)
+		if err != nil {
+			return progress, err
+		}
+		rooms = append(rooms, r...)
+
+		for range r {
+			roundIds = append(roundIds, round)
+		}
+	}
+
+	venues, err := m.tabbycat.GetVenuesContext(ctx)
+	if err != nil {
+		return progress, err
+	}
+	venueMap := roundrunner.BuildVenueMap(venues)
+
+	progress.TotalRooms = len(rooms)
+	report(progress)
+
+	for k, room := range rooms {
+		round := roundIds[k]
+
+		if err := ctx.Err(); err != nil {
+			return progress, err
+		}
+
+		venueName := venueMap[room.VenueId]
+		category, err := m.categories.Lookup(venueName)
+		if err != nil {
+			log.Print(err.Error())
+		}
+
+		for i, team := range room.TeamIds {
+			discords, urlKeys, err := m.database.ParticipantsFromTeamId(team)
+			if err != nil {
+				return progress, err
+			}
+
+			snowflakes, err := util.StringsToSnowflakes(discords)
+			if err != nil {
+				return progress, err
+			}
+
+			for j, snowflake := range snowflakes {
+				m.send(&progress, round, snowflake, "draw.speaker", templates.Draw{
+					Side:       room.SideNames[i],
+					Venue:      venueName,
+					ZoomUrl:    category.Url,
+					PrivateUrl: m.privateUrl(urlKeys[j]),
+				})
+			}
+		}
+
+		judgeIds := append([]string{room.ChairId}, append(room.PanellistIds, room.TraineeIds...)...)
+		discords, urlKeys, err := m.database.DiscordFromParticipantIds(judgeIds)
+		if err != nil {
+			return progress, err
+		}
+
+		for j, discord := range discords {
+			if discord == "" {
+				log.Printf("Adjudicator %v has no discord ID.\n", judgeIds[j])
+				progress.Unregistered = append(progress.Unregistered, judgeIds[j])
+				continue
+			}
+
+			snowflake, err := util.StringToSnowflake(discord)
+			if err != nil {
+				return progress, err
+			}
+
+			position := "panellist"
+			if j == 0 {
+				position = "chair"
+			} else if j > len(room.PanellistIds) {
+				position = "trainee"
+			}
+
+			m.send(&progress, round, snowflake, "draw.judge", templates.Draw{
+				Venue:      venueName,
+				Position:   position,
+				ZoomUrl:    category.Url,
+				PrivateUrl: m.privateUrl(urlKeys[j]),
+			})
+		}
+
+		progress.Rooms += 1
+		report(progress)
+	}
+
+	m.pending.Wait()
+
+	m.mutex.Lock()
+	for _, delivery := range m.deliveries {
+		if delivery.Skipped {
+			progress.Skipped += 1
+		} else if delivery.Sent {
+			progress.Delivered += 1
+		} else {
+			progress.Failed = append(progress.Failed, delivery)
+		}
+	}
+	m.mutex.Unlock()
+
+	report(progress)
+
+	return progress, nil
+}
+
+// Forget clears the record of draw messages sent for rounds, so that releasing
+// them again messages everyone afresh, not just those whose allocation changed.
+func (m *Messenger) Forget(rounds []uint64) error {
+	for _, round := range rounds {
+		if err := m.database.ForgetMessages(outboxPrefix(round)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// send keys each message by what it says as well as who it's for, so that after
+// a redraw anyone whose allocation changed is messaged again.
+func (m *Messenger) send(progress *Progress, round uint64, to disgord.Snowflake, template string, data templates.Draw) {
+	key := fmt.Sprintf("%v%x:%v", outboxPrefix(round), sha1.Sum([]byte(fmt.Sprintf("%v %+v", template, data))), to)
+	message := func(language string) string {
+		return m.templates.RenderIn(language, template, data)
+	}
+
+	m.pending.Add(1)
+	m.messenger.SendLocalisedOnce(key, to, message, func(delivery hermes.Delivery) {
+		m.mutex.Lock()
+		m.deliveries = append(m.deliveries, delivery)
+		m.mutex.Unlock()
+		m.pending.Done()
+	})
+	progress.Messages += 1
+}
+
+func (m *Messenger) privateUrl(urlKey string) string {
+	if urlKey == "" {
+		return ""
+	}
+
+	return m.tabbycat.PrivateUrlFromKey(urlKey)
+}
+
+func outboxPrefix(round uint64) string {
+	return fmt.Sprintf("draw:%v:", round)
+}
@@ -0,0 +1,192 @@
+package roundmessenger
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andersfylling/disgord"
+	"github.com/hitecherik/Tabulatron/internal/chat/simguild"
+	"github.com/hitecherik/Tabulatron/internal/db"
+	"github.com/hitecherik/Tabulatron/internal/hermes"
+	"github.com/hitecherik/Tabulatron/internal/multiroom"
+	"github.com/hitecherik/Tabulatron/internal/scheduler"
+	"github.com/hitecherik/Tabulatron/pkg/tabbycat"
+	"github.com/hitecherik/Tabulatron/pkg/tabbycat/tabbycattest"
+)
+
+type tournament struct {
+	fake     *tabbycattest.Server
+	tabbycat *tabbycat.Tabbycat
+	database *db.Database
+	guild    *simguild.Guild
+	members  map[string]*disgord.Member
+}
+
+func setup(t *testing.T) *tournament {
+	t.Helper()
+
+	client, fake, database := tabbycattest.NewTournament(t)
+
+	teams, err := client.GetTeams()
+	if err != nil {
+		t.Fatalf("GetTeams: %v", err)
+	}
+
+	tr := &tournament{fake, client, database, simguild.New(), make(map[string]*disgord.Member)}
+	for _, team := range teams {
+		for _, speaker := range team.Speakers {
+			member := tr.guild.AddMember(speaker.Name)
+			if _, _, _, err := database.ParticipantFromBarcode(speaker.Barcode, member.User.ID.String()); err != nil {
+				t.Fatalf("registering %v: %v", speaker.Name, err)
+			}
+
+			tr.members[speaker.Name] = member
+		}
+	}
+
+	return tr
+}
+
+func (tr *tournament) release(t *testing.T, round uint64) Progress {
+	t.Helper()
+
+	bots := scheduler.New()
+	bots.AddClient(tr.guild)
+	messenger := hermes.New(bots)
+	messenger.SetOutbox(tr.database)
+	go messenger.Listen()
+
+	progress, err := New(tr.tabbycat, tr.database, messenger, multiroom.Categories{}).Release(context.Background(), []uint64{round}, func(Progress) {})
+	if err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+	messenger.Wait()
+
+	return progress
+}
+
+func TestReleaseSkipsThoseAlreadyMessaged(t *testing.T) {
+	tr := setup(t)
+
+	if progress := tr.release(t, 1); progress.Delivered != 8 || progress.Skipped != 0 {
+		t.Errorf("first release got %+v, want 8 delivered", progress)
+	}
+
+	if progress := tr.release(t, 1); progress.Delivered != 0 || progress.Skipped != 8 {
+		t.Errorf("second release got %+v, want 8 skipped", progress)
+	}
+
+	dms := tr.guild.DMs(tr.members["Ada Lovelace"])
+	if len(dms) != 1 || !strings.Contains(dms[0].Content, "Open 1") {
+		t.Errorf("got DMs %v", dms)
+	}
+}
+
+func TestReleaseAfterRedraw(t *testing.T) {
+	tr := setup(t)
+	tr.release(t, 1)
+
+	// Move the debate to Novice 1 and swap the opening and closing teams.
+	redraw := `{
+		"id": 1,
+		"url": "http://localhost:8000/api/v1/tournaments/example/rounds/1/pairings/1",
+		"venue": "http://localhost:8000/api/v1/tournaments/example/venues/2",
+		"adjudicators": {"chair": "http://localhost:8000/api/v1/tournaments/example/adjudicators/9", "panellists": [], "trainees": []},
+		"teams": [
+			{"side": "og", "team": "http://localhost:8000/api/v1/tournaments/example/teams/3"},
+			{"side": "oo", "team": "http://localhost:8000/api/v1/tournaments/example/teams/4"},
+			{"side": "cg", "team": "http://localhost:8000/api/v1/tournaments/example/teams/1"},
+			{"side": "co", "team": "http://localhost:8000/api/v1/tournaments/example/teams/2"}
+		]
+	}`
+	request := httptest.NewRequest(http.MethodPut, "/api/v1/tournaments/example/rounds/1/pairings/1", bytes.NewBufferString(redraw))
+	recorder := httptest.NewRecorder()
+	tr.fake.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("redrawing: got status %v", recorder.Code)
+	}
+
+	if progress := tr.release(t, 1); progress.Delivered != 8 || progress.Skipped != 0 {
+		t.Errorf("release after redraw got %+v, want 8 delivered", progress)
+	}
+
+	dms := tr.guild.DMs(tr.members["Ada Lovelace"])
+	if len(dms) != 2 || !strings.Contains(dms[1].Content, "Novice 1") {
+		t.Errorf("got DMs %v", dms)
+	}
+}
+
+func TestReleaseWithMissingAdjudicator(t *testing.T) {
+	tr := setup(t)
+
+	adjudicators, err := tr.tabbycat.GetAdjudicators()
+	if err != nil {
+		t.Fatalf("GetAdjudicators: %v", err)
+	}
+
+	for _, adjudicator := range adjudicators {
+		if adjudicator.Id == 9 {
+			continue
+		}
+
+		member := tr.guild.AddMember(adjudicator.Name)
+		if _, _, _, err := tr.database.ParticipantFromBarcode(adjudicator.Barcode, member.User.ID.String()); err != nil {
+			t.Fatalf("registering %v: %v", adjudicator.Name, err)
+		}
+		tr.members[adjudicator.Name] = member
+	}
+
+	// Adjudicator 99 was never pulled into the database.
+	redraw := `{
+		"id": 1,
+		"url": "http://localhost:8000/api/v1/tournaments/example/rounds/1/pairings/1",
+		"venue": "http://localhost:8000/api/v1/tournaments/example/venues/1",
+		"adjudicators": {
+			"chair": "http://localhost:8000/api/v1/tournaments/example/adjudicators/9",
+			"panellists": ["http://localhost:8000/api/v1/tournaments/example/adjudicators/99", "http://localhost:8000/api/v1/tournaments/example/adjudicators/10"],
+			"trainees": ["http://localhost:8000/api/v1/tournaments/example/adjudicators/11"]
+		},
+		"teams": [
+			{"side": "og", "team": "http://localhost:8000/api/v1/tournaments/example/teams/1"},
+			{"side": "oo", "team": "http://localhost:8000/api/v1/tournaments/example/teams/2"},
+			{"side": "cg", "team": "http://localhost:8000/api/v1/tournaments/example/teams/3"},
+			{"side": "co", "team": "http://localhost:8000/api/v1/tournaments/example/teams/4"}
+		]
+	}`
+	request := httptest.NewRequest(http.MethodPut, "/api/v1/tournaments/example/rounds/1/pairings/1", bytes.NewBufferString(redraw))
+	recorder := httptest.NewRecorder()
+	tr.fake.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("redrawing: got status %v", recorder.Code)
+	}
+
+	progress := tr.release(t, 1)
+	if strings.Join(progress.Unregistered, ",") != "9,99" {
+		t.Errorf("got unregistered %v, want the chair and the missing panellist", progress.Unregistered)
+	}
+
+	tests := []struct {
+		id       uint
+		position string
+	}{
+		{10, "panellist"},
+		{11, "trainee"},
+	}
+
+	for _, test := range tests {
+		for _, adjudicator := range adjudicators {
+			if adjudicator.Id != test.id {
+				continue
+			}
+
+			dms := tr.guild.DMs(tr.members[adjudicator.Name])
+			if len(dms) != 1 || !strings.Contains(dms[0].Content, test.position) {
+				t.Errorf("%v got DMs %v, want to be told they're the %v", adjudicator.Name, dms, test.position)
+			}
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package roundrunner
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/hitecherik/Tabulatron/internal/db"
+	"github.com/hitecherik/Tabulatron/internal/multiroom"
+	"github.com/hitecherik/Tabulatron/pkg/tabbycat"
+	"github.com/hitecherik/Tabulatron/pkg/tabbycat/tabbycattest"
+)
+
+var categories = multiroom.Categories{
+	{Name: "Open", Prefix: "Open"},
+	{Name: "Novice", Prefix: "Novice"},
+}
+
+func allocate(t *testing.T, client *tabbycat.Tabbycat, database *db.Database, round uint64, adjsOnly bool) map[string][][]string {
+	t.Helper()
+
+	venues, err := client.GetVenues()
+	if err != nil {
+		t.Fatalf("GetVenues: %v", err)
+	}
+
+	rooms, err := client.GetDraw(round)
+	if err != nil {
+		t.Fatalf("GetDraw: %v", err)
+	}
+
+	allocations, err := Allocate(*database, venues, rooms, categories, adjsOnly)
+	if err != nil {
+		t.Fatalf("Allocate: %v", err)
+	}
+
+	byCategory := make(map[string][][]string)
+	for _, allocation := range allocations {
+		byCategory[allocation.Category.Name] = allocation.Allocation
+	}
+
+	return byCategory
+}
+
+func TestAllocate(t *testing.T) {
+	client, fake, database := tabbycattest.NewTournament(t)
+
+	allocations := allocate(t, client, database, 1, false)
+
+	open := allocations["Open"]
+	if len(open) != 11 {
+		t.Fatalf("got %v allocations in Open, want 11", len(open))
+	}
+
+	emails := make([]string, 0, len(open))
+	for _, row := range open {
+		if row[0] != "Open 1" {
+			t.Errorf("got venue %v, want Open 1", row[0])
+		}
+
+		emails = append(emails, row[1])
+	}
+
+	sort.Strings(emails)
+	if i := sort.SearchStrings(emails, "hedy@example.com"); i == len(emails) || emails[i] != "hedy@example.com" {
+		t.Errorf("chair is missing from %v", emails)
+	}
+
+	if len(allocations["Novice"]) != 0 {
+		t.Errorf("got %v allocations in Novice, want none", allocations["Novice"])
+	}
+
+	if requests := fake.Requests(); len(requests) != 0 {
+		t.Errorf("allocating wrote to Tabbycat: %+v", requests)
+	}
+}
+
+func TestAllocateAdjudicatorsOnly(t *testing.T) {
+	client, _, database := tabbycattest.NewTournament(t)
+
+	if open := allocate(t, client, database, 2, true)["Open"]; len(open) != 3 {
+		t.Errorf("got %v allocations in Open, want 3", len(open))
+	}
+}
+
+func TestBuildVenueMap(t *testing.T) {
+	venueMap := BuildVenueMap([]tabbycat.Venue{{Id: 1, Name: "Open 1"}, {Id: 2, Name: "Novice 1"}})
+
+	if venueMap["1"] != "Open 1" || venueMap["2"] != "Novice 1" || len(venueMap) != 2 {
+		t.Errorf("got %v", venueMap)
+	}
+}
@@ -0,0 +1,265 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/andersfylling/disgord"
+	"github.com/hitecherik/Tabulatron/internal/chat"
+)
+
+const (
+	rateLimitBackoff time.Duration = time.Second * 5
+	latencyWeight    float64       = 0.2
+)
+
+var ErrNoBulkClients = errors.New("no clients available for bulk messages")
+
+// Scheduler hands work to whichever bot is least busy, so that one bot that
+// has hit Discord's rate limits doesn't hold everything else up.
+type Scheduler struct {
+	mutex   sync.Mutex
+	clients []*client
+	started time.Time
+	freed   chan struct{}
+}
+
+type client struct {
+	platform     chat.Platform
+	reserved     bool
+	inFlight     int
+	limitedUntil time.Time
+	latency      time.Duration
+	busy         time.Duration
+	sent         int
+	failed       int
+	rateLimited  int
+}
+
+type ClientMetrics struct {
+	InFlight    int
+	Sent        int
+	Failed      int
+	RateLimited int
+	Latency     time.Duration
+	Utilisation float64
+}
+
+type Metrics struct {
+	Elapsed time.Duration
+	Clients []ClientMetrics
+}
+
+func New() *Scheduler {
+	return &Scheduler{
+		started: time.Now(),
+		freed:   make(chan struct{}, 1),
+	}
+}
+
+func (s *Scheduler) AddClient(platform chat.Platform) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.clients = append(s.clients, &client{platform: platform})
+}
+
+// AddReservedClient adds a client that AcquireBulk never hands out, so that
+// bulk messages can't use up its rate limit.
+func (s *Scheduler) AddReservedClient(platform chat.Platform) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.clients = append(s.clients, &client{platform: platform, reserved: true})
+}
+
+func (s *Scheduler) Len() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return len(s.clients)
+}
+
+// BulkLen counts the clients that AcquireBulk can hand out.
+func (s *Scheduler) BulkLen() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	count := 0
+	for _, c := range s.clients {
+		if !c.reserved {
+			count += 1
+		}
+	}
+
+	return count
+}
+
+// Acquire picks the least-loaded client that isn't rate limited, waiting for
+// one to become available if they all are. The caller must pass the returned
+// index to Release once it's done.
+func (s *Scheduler) Acquire() (int, chat.Platform) {
+	return s.acquire(false)
+}
+
+// AcquireBulk is like Acquire, but leaves out reserved clients. It returns
+// ErrNoBulkClients rather than waiting forever if every client is reserved.
+func (s *Scheduler) AcquireBulk() (int, chat.Platform, error) {
+	if s.BulkLen() == 0 {
+		return -1, nil, ErrNoBulkClients
+	}
+
+	index, client := s.acquire(true)
+	return index, client, nil
+}
+
+func (s *Scheduler) acquire(bulk bool) (int, chat.Platform) {
+	for {
+		s.mutex.Lock()
+		best, wait := s.pick(time.Now(), bulk)
+
+		if best >= 0 {
+			s.clients[best].inFlight += 1
+			s.mutex.Unlock()
+			return best, s.clients[best].platform
+		}
+		s.mutex.Unlock()
+
+		select {
+		case <-time.After(wait):
+		case <-s.freed:
+		}
+	}
+}
+
+func (s *Scheduler) pick(now time.Time, bulk bool) (int, time.Duration) {
+	best := -1
+	wait := rateLimitBackoff
+
+	for i, c := range s.clients {
+		if bulk && c.reserved {
+			continue
+		}
+
+		if now.Before(c.limitedUntil) {
+			if until := c.limitedUntil.Sub(now); until < wait {
+				wait = until
+			}
+			continue
+		}
+
+		if best < 0 || c.less(s.clients[best]) {
+			best = i
+		}
+	}
+
+	return best, wait
+}
+
+func (c *client) less(other *client) bool {
+	if c.inFlight != other.inFlight {
+		return c.inFlight < other.inFlight
+	}
+
+	if c.latency != other.latency {
+		return c.latency < other.latency
+	}
+
+	return c.sent < other.sent
+}
+
+func (s *Scheduler) Release(index int, started time.Time, err error) {
+	took := time.Since(started)
+
+	s.mutex.Lock()
+	c := s.clients[index]
+	c.inFlight -= 1
+	c.busy += took
+
+	if c.latency == 0 {
+		c.latency = took
+	} else {
+		c.latency = time.Duration(latencyWeight*float64(took) + (1-latencyWeight)*float64(c.latency))
+	}
+
+	if err == nil {
+		c.sent += 1
+	} else {
+		c.failed += 1
+
+		if isRateLimited(err) {
+			c.rateLimited += 1
+			c.limitedUntil = time.Now().Add(rateLimitBackoff)
+		}
+	}
+	s.mutex.Unlock()
+
+	select {
+	case s.freed <- struct{}{}:
+	default:
+	}
+}
+
+func (s *Scheduler) Metrics() Metrics {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	elapsed := time.Since(s.started)
+	metrics := Metrics{Elapsed: elapsed, Clients: make([]ClientMetrics, 0, len(s.clients))}
+
+	for _, c := range s.clients {
+		metrics.Clients = append(metrics.Clients, ClientMetrics{
+			InFlight:    c.inFlight,
+			Sent:        c.sent,
+			Failed:      c.failed,
+			RateLimited: c.rateLimited,
+			Latency:     c.latency,
+			Utilisation: float64(c.busy) / float64(elapsed),
+		})
+	}
+
+	return metrics
+}
+
+func (m Metrics) Sent() int {
+	sent := 0
+	for _, c := range m.Clients {
+		sent += c.Sent
+	}
+
+	return sent
+}
+
+func (m Metrics) String() string {
+	lines := []string{fmt.Sprintf(
+		"%v requests in %v (%.2f per minute) across %v bots",
+		m.Sent(),
+		m.Elapsed.Round(time.Second),
+		float64(m.Sent())/m.Elapsed.Minutes(),
+		len(m.Clients),
+	)}
+
+	for i, c := range m.Clients {
+		lines = append(lines, fmt.Sprintf(
+			"• Bot %v: %v sent, %v failed, %v rate limited, %v in flight, %v average latency, %.0f%% busy",
+			i+1,
+			c.Sent,
+			c.Failed,
+			c.RateLimited,
+			c.InFlight,
+			c.Latency.Round(time.Millisecond),
+			c.Utilisation*100,
+		))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func isRateLimited(err error) bool {
+	var rest *disgord.ErrRest
+	return errors.As(err, &rest) && rest.HTTPCode == http.StatusTooManyRequests
+}
@@ -0,0 +1,46 @@
+package scheduler
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hitecherik/Tabulatron/internal/chat/simguild"
+)
+
+func TestAcquireBulkSkipsReservedClients(t *testing.T) {
+	helper := simguild.New()
+	mainBot := simguild.New()
+
+	s := New()
+	s.AddReservedClient(mainBot)
+	s.AddClient(helper)
+
+	for i := 0; i < 3; i++ {
+		index, client, err := s.AcquireBulk()
+		if err != nil {
+			t.Fatalf("AcquireBulk failed: %v", err)
+		}
+		if client != helper {
+			t.Errorf("AcquireBulk handed out the reserved client")
+		}
+		defer s.Release(index, time.Now(), nil)
+	}
+
+	if _, client := s.Acquire(); client != mainBot {
+		t.Errorf("Acquire didn't hand out the idle reserved client")
+	}
+}
+
+func TestAcquireBulkFailsWithoutBulkClients(t *testing.T) {
+	s := New()
+	s.AddReservedClient(simguild.New())
+
+	if s.BulkLen() != 0 {
+		t.Errorf("expected no bulk clients, got %v", s.BulkLen())
+	}
+
+	if _, _, err := s.AcquireBulk(); !errors.Is(err, ErrNoBulkClients) {
+		t.Errorf("expected ErrNoBulkClients, got %v", err)
+	}
+}
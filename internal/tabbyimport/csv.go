@@ -0,0 +1,305 @@
+package tabbyimport
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/hitecherik/Tabulatron/pkg/tabbycat"
+)
+
+var speakerColumn *regexp.Regexp = regexp.MustCompile(`^speaker\s*(\d+)(\s*name|\s*email)?$`)
+
+type Team struct {
+	Details     tabbycat.TeamDetails
+	Institution string
+}
+
+type Adjudicator struct {
+	Details     tabbycat.AdjudicatorDetails
+	Institution string
+}
+
+type Venue struct {
+	Details    tabbycat.VenueDetails
+	Categories []string
+}
+
+type row struct {
+	line    int
+	columns map[string]string
+}
+
+func ReadTeams(in io.Reader) ([]Team, error) {
+	rows, headers, err := readRows(in, "team")
+	if err != nil {
+		return nil, err
+	}
+
+	teams := make([]Team, 0, len(rows))
+	for _, r := range rows {
+		team := Team{
+			Details: tabbycat.TeamDetails{
+				Reference:      r.get("team", "reference"),
+				ShortReference: r.get("short reference"),
+				Emoji:          r.get("emoji"),
+				Speakers:       make([]tabbycat.SpeakerDetails, 0),
+			},
+			Institution: r.get("institution"),
+		}
+
+		speakers := make(map[int]*tabbycat.SpeakerDetails)
+		order := make([]int, 0)
+
+		for _, header := range headers {
+			matches := speakerColumn.FindStringSubmatch(header)
+			if matches == nil || r.columns[header] == "" {
+				continue
+			}
+
+			number, _ := strconv.Atoi(matches[1])
+			if _, ok := speakers[number]; !ok {
+				speakers[number] = &tabbycat.SpeakerDetails{}
+				order = append(order, number)
+			}
+
+			if strings.TrimSpace(matches[2]) == "email" {
+				speakers[number].Email = r.columns[header]
+			} else {
+				speakers[number].Name = r.columns[header]
+			}
+		}
+
+		for _, number := range order {
+			if speakers[number].Name == "" {
+				return nil, fmt.Errorf("line %v: speaker %v has an email but no name", r.line, number)
+			}
+
+			team.Details.Speakers = append(team.Details.Speakers, *speakers[number])
+		}
+
+		if team.Details.Reference == "" {
+			return nil, fmt.Errorf("line %v: team has no name", r.line)
+		}
+
+		team.Details.UseInstitutionPrefix = r.get("use institution prefix") == "yes"
+		teams = append(teams, team)
+	}
+
+	return teams, nil
+}
+
+func ReadAdjudicators(in io.Reader) ([]Adjudicator, error) {
+	rows, _, err := readRows(in, "name")
+	if err != nil {
+		return nil, err
+	}
+
+	adjudicators := make([]Adjudicator, 0, len(rows))
+	for _, r := range rows {
+		baseScore := 0.0
+		if raw := r.get("base score"); raw != "" {
+			baseScore, err = strconv.ParseFloat(raw, 64)
+			if err != nil {
+				return nil, fmt.Errorf("line %v: invalid base score %q", r.line, raw)
+			}
+		}
+
+		if r.get("name") == "" {
+			return nil, fmt.Errorf("line %v: adjudicator has no name", r.line)
+		}
+
+		adjudicators = append(adjudicators, Adjudicator{
+			Details: tabbycat.AdjudicatorDetails{
+				Name:      r.get("name"),
+				Email:     r.get("email"),
+				BaseScore: baseScore,
+			},
+			Institution: r.get("institution"),
+		})
+	}
+
+	return adjudicators, nil
+}
+
+func ReadVenues(in io.Reader) ([]Venue, error) {
+	rows, _, err := readRows(in, "name")
+	if err != nil {
+		return nil, err
+	}
+
+	venues := make([]Venue, 0, len(rows))
+	for _, r := range rows {
+		priority := 100
+		if raw := r.get("priority"); raw != "" {
+			priority, err = strconv.Atoi(raw)
+			if err != nil {
+				return nil, fmt.Errorf("line %v: invalid priority %q", r.line, raw)
+			}
+		}
+
+		categories := make([]string, 0)
+		for _, category := range strings.Split(r.get("categories", "category"), ";") {
+			if category = strings.TrimSpace(category); category != "" {
+				categories = append(categories, category)
+			}
+		}
+
+		if r.get("name") == "" {
+			return nil, fmt.Errorf("line %v: venue has no name", r.line)
+		}
+
+		venues = append(venues, Venue{
+			Details: tabbycat.VenueDetails{
+				Name:       r.get("name"),
+				Priority:   priority,
+				Categories: []string{},
+			},
+			Categories: categories,
+		})
+	}
+
+	return venues, nil
+}
+
+func ReadRounds(in io.Reader) ([]tabbycat.RoundDetails, error) {
+	rows, _, err := readRows(in, "name")
+	if err != nil {
+		return nil, err
+	}
+
+	stages := map[string]string{
+		"":            tabbycat.StagePreliminary,
+		"p":           tabbycat.StagePreliminary,
+		"preliminary": tabbycat.StagePreliminary,
+		"e":           tabbycat.StageElimination,
+		"elimination": tabbycat.StageElimination,
+	}
+
+	drawTypes := map[string]string{
+		"r":            tabbycat.DrawTypeRandom,
+		"random":       tabbycat.DrawTypeRandom,
+		"m":            tabbycat.DrawTypeManual,
+		"manual":       tabbycat.DrawTypeManual,
+		"d":            tabbycat.DrawTypeRoundRobin,
+		"round robin":  tabbycat.DrawTypeRoundRobin,
+		"p":            tabbycat.DrawTypePowerPaired,
+		"power paired": tabbycat.DrawTypePowerPaired,
+		"e":            tabbycat.DrawTypeElimination,
+		"elimination":  tabbycat.DrawTypeElimination,
+	}
+
+	rounds := make([]tabbycat.RoundDetails, 0, len(rows))
+	for i, r := range rows {
+		seq := uint64(i + 1)
+		if raw := r.get("seq", "sequence"); raw != "" {
+			seq, err = strconv.ParseUint(raw, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("line %v: invalid sequence number %q", r.line, raw)
+			}
+		}
+
+		if r.get("name") == "" {
+			return nil, fmt.Errorf("line %v: round has no name", r.line)
+		}
+
+		stage, ok := stages[strings.ToLower(r.get("stage"))]
+		if !ok {
+			return nil, fmt.Errorf("line %v: unknown stage %q", r.line, r.get("stage"))
+		}
+
+		rawDrawType := strings.ToLower(r.get("draw type"))
+		if rawDrawType == "" {
+			rawDrawType = "random"
+			if seq > 1 {
+				rawDrawType = "power paired"
+			}
+			if stage == tabbycat.StageElimination {
+				rawDrawType = "elimination"
+			}
+		}
+
+		drawType, ok := drawTypes[rawDrawType]
+		if !ok {
+			return nil, fmt.Errorf("line %v: unknown draw type %q", r.line, r.get("draw type"))
+		}
+
+		abbreviation := r.get("abbreviation")
+		if abbreviation == "" {
+			abbreviation = fmt.Sprintf("R%v", seq)
+		}
+
+		rounds = append(rounds, tabbycat.RoundDetails{
+			Seq:          uint(seq),
+			Name:         r.get("name"),
+			Abbreviation: abbreviation,
+			Stage:        stage,
+			DrawType:     drawType,
+		})
+	}
+
+	return rounds, nil
+}
+
+func readRows(in io.Reader, required string) ([]row, []string, error) {
+	reader := csv.NewReader(in)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(records) == 0 {
+		return nil, nil, fmt.Errorf("the CSV file is empty")
+	}
+
+	headers := make([]string, 0, len(records[0]))
+	for _, header := range records[0] {
+		headers = append(headers, strings.Join(strings.Fields(strings.ToLower(header)), " "))
+	}
+
+	hasRequired := false
+	for _, header := range headers {
+		if header == required || (required == "team" && header == "reference") {
+			hasRequired = true
+		}
+	}
+
+	if !hasRequired {
+		return nil, nil, fmt.Errorf("the CSV file has no %q column", required)
+	}
+
+	rows := make([]row, 0, len(records)-1)
+	for i, record := range records[1:] {
+		r := row{i + 2, make(map[string]string)}
+		empty := true
+
+		for j, value := range record {
+			if j < len(headers) {
+				r.columns[headers[j]] = strings.TrimSpace(value)
+				empty = empty && strings.TrimSpace(value) == ""
+			}
+		}
+
+		if !empty {
+			rows = append(rows, r)
+		}
+	}
+
+	return rows, headers, nil
+}
+
+func (r *row) get(names ...string) string {
+	for _, name := range names {
+		if value := r.columns[name]; value != "" {
+			return value
+		}
+	}
+
+	return ""
+}
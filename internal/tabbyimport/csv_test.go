@@ -0,0 +1,190 @@
+package tabbyimport
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hitecherik/Tabulatron/pkg/tabbycat"
+)
+
+func TestReadTeams(t *testing.T) {
+	tests := []struct {
+		name  string
+		csv   string
+		want  []Team
+		fails bool
+	}{
+		{
+			"speakers grouped by number",
+			"Team,Speaker 1,Speaker 1 Email,Speaker 2 Name,Speaker 2 Email\nTeam 1,Ada,ada@example.com,Hedy,\n",
+			[]Team{{Details: tabbycat.TeamDetails{
+				Reference: "Team 1",
+				Speakers:  []tabbycat.SpeakerDetails{{Name: "Ada", Email: "ada@example.com"}, {Name: "Hedy"}},
+			}}},
+			false,
+		},
+		{
+			"headers normalised",
+			"  REFERENCE , Short   Reference,Institution,Use Institution Prefix,speaker1\nTeam 1,T1,Oxford,yes,Ada\n",
+			[]Team{{
+				Details: tabbycat.TeamDetails{
+					Reference:            "Team 1",
+					ShortReference:       "T1",
+					UseInstitutionPrefix: true,
+					Speakers:             []tabbycat.SpeakerDetails{{Name: "Ada"}},
+				},
+				Institution: "Oxford",
+			}},
+			false,
+		},
+		{
+			"blank rows skipped",
+			"team,speaker 1\n,\nTeam 1,Ada\n",
+			[]Team{{Details: tabbycat.TeamDetails{Reference: "Team 1", Speakers: []tabbycat.SpeakerDetails{{Name: "Ada"}}}}},
+			false,
+		},
+		{"no team column", "name,speaker 1\nTeam 1,Ada\n", nil, true},
+		{"no team name", "team,speaker 1\n,Ada\n", nil, true},
+		{"speaker email without a name", "team,speaker 1,speaker 1 email\nTeam 1,,ada@example.com\n", nil, true},
+		{"empty file", "", nil, true},
+	}
+
+	for _, test := range tests {
+		got, err := ReadTeams(strings.NewReader(test.csv))
+
+		if test.fails {
+			if err == nil {
+				t.Errorf("%v: got %+v, want an error", test.name, got)
+			}
+			continue
+		}
+
+		if err != nil || !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%v: got %+v (%v), want %+v", test.name, got, err, test.want)
+		}
+	}
+}
+
+func TestReadAdjudicators(t *testing.T) {
+	tests := []struct {
+		name  string
+		csv   string
+		want  []Adjudicator
+		fails bool
+	}{
+		{
+			"details",
+			"Name,Email,Institution,Base Score\nHedy,hedy@example.com,Oxford,3.5\n",
+			[]Adjudicator{{Details: tabbycat.AdjudicatorDetails{Name: "Hedy", Email: "hedy@example.com", BaseScore: 3.5}, Institution: "Oxford"}},
+			false,
+		},
+		{"no base score", "name\nHedy\n", []Adjudicator{{Details: tabbycat.AdjudicatorDetails{Name: "Hedy"}}}, false},
+		{"invalid base score", "name,base score\nHedy,high\n", nil, true},
+		{"no name", "name,email\n,hedy@example.com\n", nil, true},
+	}
+
+	for _, test := range tests {
+		got, err := ReadAdjudicators(strings.NewReader(test.csv))
+
+		if test.fails {
+			if err == nil {
+				t.Errorf("%v: got %+v, want an error", test.name, got)
+			}
+			continue
+		}
+
+		if err != nil || !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%v: got %+v (%v), want %+v", test.name, got, err, test.want)
+		}
+	}
+}
+
+func TestReadVenues(t *testing.T) {
+	tests := []struct {
+		name  string
+		csv   string
+		want  []Venue
+		fails bool
+	}{
+		{
+			"categories split",
+			"name,priority,categories\nOpen 1,20,Open; Accessible;\n",
+			[]Venue{{Details: tabbycat.VenueDetails{Name: "Open 1", Priority: 20, Categories: []string{}}, Categories: []string{"Open", "Accessible"}}},
+			false,
+		},
+		{
+			"default priority",
+			"name,category\nOpen 1,\n",
+			[]Venue{{Details: tabbycat.VenueDetails{Name: "Open 1", Priority: 100, Categories: []string{}}, Categories: []string{}}},
+			false,
+		},
+		{"invalid priority", "name,priority\nOpen 1,first\n", nil, true},
+		{"no name", "name,priority\n,20\n", nil, true},
+	}
+
+	for _, test := range tests {
+		got, err := ReadVenues(strings.NewReader(test.csv))
+
+		if test.fails {
+			if err == nil {
+				t.Errorf("%v: got %+v, want an error", test.name, got)
+			}
+			continue
+		}
+
+		if err != nil || !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%v: got %+v (%v), want %+v", test.name, got, err, test.want)
+		}
+	}
+}
+
+func TestReadRounds(t *testing.T) {
+	tests := []struct {
+		name  string
+		csv   string
+		want  []tabbycat.RoundDetails
+		fails bool
+	}{
+		{
+			"defaults",
+			"name\nRound 1\nRound 2\n",
+			[]tabbycat.RoundDetails{
+				{Seq: 1, Name: "Round 1", Abbreviation: "R1", Stage: tabbycat.StagePreliminary, DrawType: tabbycat.DrawTypeRandom},
+				{Seq: 2, Name: "Round 2", Abbreviation: "R2", Stage: tabbycat.StagePreliminary, DrawType: tabbycat.DrawTypePowerPaired},
+			},
+			false,
+		},
+		{
+			"elimination defaults",
+			"name,seq,abbreviation,stage\nGrand Final,6,GF,Elimination\n",
+			[]tabbycat.RoundDetails{{Seq: 6, Name: "Grand Final", Abbreviation: "GF", Stage: tabbycat.StageElimination, DrawType: tabbycat.DrawTypeElimination}},
+			false,
+		},
+		{
+			"abbreviated stage and draw type",
+			"name,sequence,stage,draw type\nRound 3,3,p,m\n",
+			[]tabbycat.RoundDetails{{Seq: 3, Name: "Round 3", Abbreviation: "R3", Stage: tabbycat.StagePreliminary, DrawType: tabbycat.DrawTypeManual}},
+			false,
+		},
+		{"unknown stage", "name,stage\nRound 1,final\n", nil, true},
+		{"unknown draw type", "name,draw type\nRound 1,swiss\n", nil, true},
+		{"invalid sequence", "name,seq\nRound 1,one\n", nil, true},
+		{"no name", "name,seq\n,1\n", nil, true},
+	}
+
+	for _, test := range tests {
+		got, err := ReadRounds(strings.NewReader(test.csv))
+
+		if test.fails {
+			if err == nil {
+				t.Errorf("%v: got %+v, want an error", test.name, got)
+			}
+			continue
+		}
+
+		if err != nil || !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%v: got %+v (%v), want %+v", test.name, got, err, test.want)
+		}
+	}
+}
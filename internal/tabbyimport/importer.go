@@ -0,0 +1,274 @@
+package tabbyimport
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/hitecherik/Tabulatron/pkg/tabbycat"
+)
+
+// nameSet matches names regardless of case, so that running an import again
+// skips whatever the last run created.
+type nameSet map[string]bool
+
+func (n nameSet) add(name string) {
+	n[strings.ToLower(name)] = true
+}
+
+func (n nameSet) has(name string) bool {
+	return n[strings.ToLower(name)]
+}
+
+type Importer struct {
+	tabbycat     *tabbycat.Tabbycat
+	dryRun       bool
+	report       func(format string, a ...interface{})
+	institutions map[string]string
+	categories   map[string]string
+}
+
+func NewImporter(t *tabbycat.Tabbycat, dryRun bool, report func(format string, a ...interface{})) *Importer {
+	return &Importer{
+		tabbycat: t,
+		dryRun:   dryRun,
+		report:   report,
+	}
+}
+
+func (i *Importer) ImportTeams(ctx context.Context, teams []Team) error {
+	references, err := i.tabbycat.GetTeamReferencesContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	existing := make(nameSet)
+	for _, reference := range references {
+		existing.add(reference)
+	}
+
+	for _, team := range teams {
+		if existing.has(team.Details.Reference) {
+			i.report("Skipping team %v, which already exists\n", team.Details.Reference)
+			continue
+		}
+		existing.add(team.Details.Reference)
+
+		institution, err := i.institution(ctx, team.Institution)
+		if err != nil {
+			return err
+		}
+
+		team.Details.Institution = institution
+
+		names := make([]string, 0, len(team.Details.Speakers))
+		for _, speaker := range team.Details.Speakers {
+			names = append(names, speaker.Name)
+		}
+
+		if i.dryRun {
+			i.report("Would create team %v with speakers %v\n", team.Details.Reference, strings.Join(names, ", "))
+			continue
+		}
+
+		created, err := i.tabbycat.CreateTeamContext(ctx, team.Details)
+		if err != nil {
+			return fmt.Errorf("creating team %v: %w", team.Details.Reference, err)
+		}
+
+		i.report("Created team %v (%v)\n", team.Details.Reference, created.Id)
+	}
+
+	return nil
+}
+
+func (i *Importer) ImportAdjudicators(ctx context.Context, adjudicators []Adjudicator) error {
+	names, err := i.tabbycat.GetAdjudicatorNamesContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	existing := make(nameSet)
+	for _, name := range names {
+		existing.add(name)
+	}
+
+	for _, adjudicator := range adjudicators {
+		if existing.has(adjudicator.Details.Name) {
+			i.report("Skipping adjudicator %v, who already exists\n", adjudicator.Details.Name)
+			continue
+		}
+		existing.add(adjudicator.Details.Name)
+
+		institution, err := i.institution(ctx, adjudicator.Institution)
+		if err != nil {
+			return err
+		}
+
+		adjudicator.Details.Institution = institution
+
+		if i.dryRun {
+			i.report("Would create adjudicator %v with base score %v\n", adjudicator.Details.Name, adjudicator.Details.BaseScore)
+			continue
+		}
+
+		created, err := i.tabbycat.CreateAdjudicatorContext(ctx, adjudicator.Details)
+		if err != nil {
+			return fmt.Errorf("creating adjudicator %v: %w", adjudicator.Details.Name, err)
+		}
+
+		i.report("Created adjudicator %v (%v)\n", adjudicator.Details.Name, created.Id)
+	}
+
+	return nil
+}
+
+func (i *Importer) ImportVenues(ctx context.Context, venues []Venue) error {
+	current, err := i.tabbycat.GetVenuesContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	existing := make(nameSet)
+	for _, venue := range current {
+		existing.add(venue.Name)
+	}
+
+	for _, venue := range venues {
+		if existing.has(venue.Details.Name) {
+			i.report("Skipping venue %v, which already exists\n", venue.Details.Name)
+			continue
+		}
+		existing.add(venue.Details.Name)
+
+		for _, name := range venue.Categories {
+			category, err := i.category(ctx, name)
+			if err != nil {
+				return err
+			}
+
+			venue.Details.Categories = append(venue.Details.Categories, category)
+		}
+
+		if i.dryRun {
+			i.report("Would create venue %v with priority %v\n", venue.Details.Name, venue.Details.Priority)
+			continue
+		}
+
+		created, err := i.tabbycat.CreateVenueContext(ctx, venue.Details)
+		if err != nil {
+			return fmt.Errorf("creating venue %v: %w", venue.Details.Name, err)
+		}
+
+		i.report("Created venue %v (%v)\n", venue.Details.Name, created.Id)
+	}
+
+	return nil
+}
+
+func (i *Importer) ImportRounds(ctx context.Context, rounds []tabbycat.RoundDetails) error {
+	current, err := i.tabbycat.GetRoundsContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	existing := make(nameSet)
+	for _, round := range current {
+		existing.add(round.Name)
+	}
+
+	for _, round := range rounds {
+		if existing.has(round.Name) {
+			i.report("Skipping round %v, which already exists\n", round.Name)
+			continue
+		}
+		existing.add(round.Name)
+
+		if i.dryRun {
+			i.report("Would create round %v (%v)\n", round.Name, round.Abbreviation)
+			continue
+		}
+
+		created, err := i.tabbycat.CreateRoundContext(ctx, round)
+		if err != nil {
+			return fmt.Errorf("creating round %v: %w", round.Name, err)
+		}
+
+		i.report("Created round %v (%v)\n", round.Name, created.Id)
+	}
+
+	return nil
+}
+
+func (i *Importer) institution(ctx context.Context, name string) (string, error) {
+	if name == "" {
+		return "", nil
+	}
+
+	if i.institutions == nil {
+		institutions, err := i.tabbycat.GetInstitutionsContext(ctx)
+		if err != nil {
+			return "", err
+		}
+
+		i.institutions = make(map[string]string)
+		for _, institution := range institutions {
+			i.institutions[strings.ToLower(institution.Name)] = institution.Url
+			i.institutions[strings.ToLower(institution.Code)] = institution.Url
+		}
+	}
+
+	if url, ok := i.institutions[strings.ToLower(name)]; ok {
+		return url, nil
+	}
+
+	if i.dryRun {
+		i.report("Would create institution %v\n", name)
+		i.institutions[strings.ToLower(name)] = ""
+		return "", nil
+	}
+
+	institution, err := i.tabbycat.CreateInstitutionContext(ctx, name, name)
+	if err != nil {
+		return "", fmt.Errorf("creating institution %v: %w", name, err)
+	}
+
+	i.report("Created institution %v\n", name)
+	i.institutions[strings.ToLower(name)] = institution.Url
+
+	return institution.Url, nil
+}
+
+func (i *Importer) category(ctx context.Context, name string) (string, error) {
+	if i.categories == nil {
+		categories, err := i.tabbycat.GetVenueCategoriesContext(ctx)
+		if err != nil {
+			return "", err
+		}
+
+		i.categories = make(map[string]string)
+		for _, category := range categories {
+			i.categories[strings.ToLower(category.Name)] = category.Url
+		}
+	}
+
+	if url, ok := i.categories[strings.ToLower(name)]; ok {
+		return url, nil
+	}
+
+	if i.dryRun {
+		i.report("Would create venue category %v\n", name)
+		i.categories[strings.ToLower(name)] = ""
+		return "", nil
+	}
+
+	category, err := i.tabbycat.CreateVenueCategoryContext(ctx, name)
+	if err != nil {
+		return "", fmt.Errorf("creating venue category %v: %w", name, err)
+	}
+
+	i.report("Created venue category %v\n", name)
+	i.categories[strings.ToLower(name)] = category.Url
+
+	return category.Url, nil
+}
@@ -0,0 +1,58 @@
+package tabbyimport
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/hitecherik/Tabulatron/pkg/tabbycat"
+	"github.com/hitecherik/Tabulatron/pkg/tabbycat/tabbycattest"
+)
+
+func TestImportSkipsExisting(t *testing.T) {
+	client, fake, _ := tabbycattest.NewTournament(t)
+
+	importer := NewImporter(client, false, func(string, ...interface{}) {})
+	ctx := context.Background()
+
+	teams := []Team{
+		{Details: tabbycat.TeamDetails{Reference: "team 1"}},
+		{Details: tabbycat.TeamDetails{Reference: "Team 5"}, Institution: "New University"},
+		{Details: tabbycat.TeamDetails{Reference: "Team 5"}},
+	}
+	if err := importer.ImportTeams(ctx, teams); err != nil {
+		t.Fatalf("ImportTeams: %v", err)
+	}
+
+	adjudicators := []Adjudicator{{Details: tabbycat.AdjudicatorDetails{Name: "Hedy Lamarr"}}}
+	if err := importer.ImportAdjudicators(ctx, adjudicators); err != nil {
+		t.Fatalf("ImportAdjudicators: %v", err)
+	}
+
+	venues := []Venue{{Details: tabbycat.VenueDetails{Name: "Open 1"}}, {Details: tabbycat.VenueDetails{Name: "Open 2"}}}
+	if err := importer.ImportVenues(ctx, venues); err != nil {
+		t.Fatalf("ImportVenues: %v", err)
+	}
+
+	rounds := []tabbycat.RoundDetails{{Seq: 1, Name: "Round 1"}}
+	if err := importer.ImportRounds(ctx, rounds); err != nil {
+		t.Fatalf("ImportRounds: %v", err)
+	}
+
+	tests := []struct {
+		path string
+		want int
+	}{
+		{"teams", 1},
+		{"institutions", 1},
+		{"adjudicators", 0},
+		{"venues", 1},
+		{"rounds", 0},
+	}
+
+	for _, test := range tests {
+		if got := len(fake.RequestsTo(http.MethodPost, test.path)); got != test.want {
+			t.Errorf("got %v POSTs to %v, want %v", got, test.path, test.want)
+		}
+	}
+}
@@ -0,0 +1,239 @@
+package tabulatron
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"sync"
+	"text/template"
+
+	"github.com/andersfylling/disgord"
+	"github.com/hitecherik/Tabulatron/internal/audience"
+	"github.com/hitecherik/Tabulatron/internal/db"
+	"github.com/hitecherik/Tabulatron/internal/hermes"
+	"github.com/hitecherik/Tabulatron/internal/templates"
+	"github.com/hitecherik/Tabulatron/internal/util"
+	"github.com/hitecherik/Tabulatron/pkg/tabbycat"
+)
+
+var announce *regexp.Regexp = regexp.MustCompile(`(?s)^!announce(?:[ \t]+([^\n]*))?(?:\n(.*))?$`)
+
+const announceSubject string = "A message from the tab team"
+
+type announcement struct {
+	filter     audience.Filter
+	message    string
+	template   *template.Template
+	recipients []db.Participant
+}
+
+// AnnounceHandler messages a filtered set of participants, e.g.
+//
+//	!announce speakers notcheckedin
+//	Please check in for round 3!
+//
+// It only sends once the same person replies "!announce confirm".
+type AnnounceHandler struct {
+	t       *Tabulatron
+	mutex   sync.Mutex
+	pending map[disgord.Snowflake]*announcement
+}
+
+func NewAnnounceHandler(t *Tabulatron) *AnnounceHandler {
+	return &AnnounceHandler{
+		t:       t,
+		pending: make(map[disgord.Snowflake]*announcement),
+	}
+}
+
+func (h *AnnounceHandler) CanHandle(evt *disgord.MessageCreate) bool {
+	return announce.MatchString(strings.TrimSpace(evt.Message.Content))
+}
+
+func (h *AnnounceHandler) Handle(evt *disgord.MessageCreate) {
+	if !h.t.fromTab(evt.Message) {
+		return
+	}
+
+	matches := announce.FindStringSubmatch(strings.TrimSpace(evt.Message.Content))
+	args, message := strings.TrimSpace(matches[1]), strings.TrimSpace(matches[2])
+
+	switch {
+	case args == "confirm" && message == "":
+		h.confirm(evt)
+	case args == "cancel" && message == "":
+		h.mutex.Lock()
+		delete(h.pending, evt.Message.Author.ID)
+		h.mutex.Unlock()
+
+		h.t.AcknowledgeMessage(evt.Message)
+	default:
+		h.preview(evt, args, message)
+	}
+}
+
+func (h *AnnounceHandler) preview(evt *disgord.MessageCreate, args string, message string) {
+	if message == "" {
+		h.t.ReplyMessage(evt.Message, "put the filters after `!announce` and the message to send on the lines below it.")
+		h.t.RejectMessage(evt.Message)
+		return
+	}
+
+	filter, err := audience.Parse(args)
+	if err != nil {
+		h.t.ReplyMessage(evt.Message, "I don't understand those filters: %v.", err.Error())
+		h.t.RejectMessage(evt.Message)
+		return
+	}
+
+	tmpl, err := template.New("announcement").Parse(message)
+	if err != nil {
+		h.t.ReplyMessage(evt.Message, "there's a mistake in your message: %v.", err.Error())
+		h.t.RejectMessage(evt.Message)
+		return
+	}
+
+	if filter.Unregistered && h.t.mailer == nil {
+		h.t.ReplyMessage(evt.Message, "I can't email anyone because I haven't been given a mail server.")
+		h.t.RejectMessage(evt.Message)
+		return
+	}
+
+	if !filter.Unregistered && h.t.messenger == nil {
+		h.t.ReplyMessage(evt.Message, "I don't have any bots to send messages with.")
+		h.t.RejectMessage(evt.Message)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), pullTimeout)
+	defer cancel()
+
+	recipients, err := audience.Select(ctx, h.t.tabbycat, h.t.database, filter)
+	var barcodeErr *tabbycat.BarcodeError
+	if errors.As(err, &barcodeErr) {
+		log.Printf("error fetching check-ins: %v", err.Error())
+		h.t.sendList(evt.Message.ChannelID, fmt.Sprintf("Could not find out whether %v participants are checked in, so they're left out", len(barcodeErr.Failures)), describeBarcodeFailures(barcodeErr.Failures))
+	} else if err != nil {
+		log.Printf("error selecting recipients: %v", err.Error())
+		h.t.ReplyMessage(evt.Message, "there was an error finding who to message: %v.", describeTabbycatError(err))
+		h.t.RejectMessage(evt.Message)
+		return
+	}
+
+	if len(recipients) == 0 {
+		h.t.ReplyMessage(evt.Message, "there aren't any %v.", filter.String())
+		h.t.RejectMessage(evt.Message)
+		return
+	}
+
+	h.mutex.Lock()
+	h.pending[evt.Message.Author.ID] = &announcement{filter, message, tmpl, recipients}
+	h.mutex.Unlock()
+
+	verb := "message"
+	if filter.Unregistered {
+		verb = "email"
+	}
+
+	h.t.ReplyMessage(
+		evt.Message,
+		"this will %v %v %v. Reply `!announce confirm` to send it, or `!announce cancel` to discard it.",
+		verb,
+		len(recipients),
+		filter.String(),
+	)
+}
+
+func (h *AnnounceHandler) confirm(evt *disgord.MessageCreate) {
+	h.mutex.Lock()
+	pending := h.pending[evt.Message.Author.ID]
+	delete(h.pending, evt.Message.Author.ID)
+	h.mutex.Unlock()
+
+	if pending == nil {
+		h.t.ReplyMessage(evt.Message, "you don't have an announcement waiting to be sent.")
+		h.t.RejectMessage(evt.Message)
+		return
+	}
+
+	if pending.filter.Unregistered {
+		h.email(evt, pending)
+		return
+	}
+
+	var (
+		wg     sync.WaitGroup
+		mutex  sync.Mutex
+		report hermes.Report
+	)
+
+	for _, recipient := range pending.recipients {
+		snowflake, err := util.StringToSnowflake(recipient.Discord)
+		if err != nil {
+			log.Printf("error parsing snowflake: %v", err.Error())
+			continue
+		}
+
+		wg.Add(1)
+		key := fmt.Sprintf("mass:%v:%v", evt.Message.ID, snowflake)
+		h.t.messenger.SendOnce(key, snowflake, pending.render(recipient), func(delivery hermes.Delivery) {
+			mutex.Lock()
+			report.Add(delivery)
+			mutex.Unlock()
+			wg.Done()
+		})
+	}
+
+	wg.Wait()
+
+	h.t.ReplyMessage(evt.Message, "%v.", report.Summary())
+	if len(report.Failed) > 0 {
+		h.t.sendList(evt.Message.ChannelID, fmt.Sprintf("Could not message %v participants, who need to be contacted manually", len(report.Failed)), hermes.DescribeFailures(h.t.database, report.Failed))
+	}
+
+	h.t.AcknowledgeMessage(evt.Message)
+}
+
+func (h *AnnounceHandler) email(evt *disgord.MessageCreate, pending *announcement) {
+	sent := 0
+	failed := make([]string, 0)
+
+	for _, recipient := range pending.recipients {
+		if recipient.Email == "" {
+			failed = append(failed, recipient.Name)
+			continue
+		}
+
+		if err := h.t.mailer.Send(recipient.Email, announceSubject, pending.render(recipient)); err != nil {
+			log.Printf("error emailing %v: %v", recipient.Email, err.Error())
+			failed = append(failed, recipient.Name)
+			continue
+		}
+
+		sent++
+	}
+
+	if len(failed) > 0 {
+		h.t.ReplyMessage(evt.Message, "I emailed %v participants, but couldn't email %v of them.", sent, len(failed))
+		h.t.sendList(evt.Message.ChannelID, fmt.Sprintf("Could not email %v participants", len(failed)), bullet(failed))
+		h.t.RejectMessage(evt.Message)
+		return
+	}
+
+	h.t.ReplyMessage(evt.Message, "I emailed %v participants.", sent)
+	h.t.AcknowledgeMessage(evt.Message)
+}
+
+func (a *announcement) render(recipient db.Participant) string {
+	var message bytes.Buffer
+	if err := a.template.Execute(&message, templates.Participant{Name: recipient.Name}); err != nil {
+		log.Printf("error rendering announcement: %v", err.Error())
+		return a.message
+	}
+
+	return message.String()
+}
@@ -0,0 +1,116 @@
+package tabulatron
+
+import (
+	"context"
+	"errors"
+	"log"
+	"regexp"
+	"strconv"
+	"sync"
+
+	"github.com/andersfylling/disgord"
+	"github.com/hitecherik/Tabulatron/internal/ballotchaser"
+	"github.com/hitecherik/Tabulatron/internal/layout"
+)
+
+var chaseballots *regexp.Regexp = regexp.MustCompile(`^!chaseballots\s*(stop)?\s*(\d+)$`)
+
+type BallotHandler struct {
+	t       *Tabulatron
+	mutex   sync.Mutex
+	chasers map[uint64]context.CancelFunc
+}
+
+func NewBallotHandler(t *Tabulatron) *BallotHandler {
+	return &BallotHandler{
+		t:       t,
+		chasers: make(map[uint64]context.CancelFunc),
+	}
+}
+
+func (h *BallotHandler) CanHandle(evt *disgord.MessageCreate) bool {
+	return chaseballots.MatchString(evt.Message.Content)
+}
+
+func (h *BallotHandler) Handle(evt *disgord.MessageCreate) {
+	if !h.t.fromTab(evt.Message) {
+		return
+	}
+
+	matches := chaseballots.FindStringSubmatch(evt.Message.Content)
+	round, err := strconv.ParseUint(matches[2], 10, 64)
+	if err != nil {
+		log.Printf("error extracting round: %v", err.Error())
+		h.t.ReplyMessage(evt.Message, "there was an error parsing your request.")
+		h.t.RejectMessage(evt.Message)
+		return
+	}
+
+	if matches[1] != "" {
+		h.mutex.Lock()
+		cancel, ok := h.chasers[round]
+		h.mutex.Unlock()
+
+		if !ok {
+			h.t.ReplyMessage(evt.Message, "I'm not chasing ballots for that round.")
+			h.t.RejectMessage(evt.Message)
+			return
+		}
+
+		cancel()
+		h.t.AcknowledgeMessage(evt.Message)
+		return
+	}
+
+	if h.t.messenger == nil {
+		h.t.ReplyMessage(evt.Message, "I don't have any bots to send reminders with.")
+		h.t.RejectMessage(evt.Message)
+		return
+	}
+
+	tab, err := h.t.channel(evt.Message.GuildID, layout.TabChannel)
+	if err != nil {
+		h.t.ReplyMessage(evt.Message, "%v.", err.Error())
+		h.t.RejectMessage(evt.Message)
+		return
+	}
+
+	h.mutex.Lock()
+	if _, ok := h.chasers[round]; ok {
+		h.mutex.Unlock()
+		h.t.ReplyMessage(evt.Message, "I'm already chasing ballots for that round.")
+		h.t.RejectMessage(evt.Message)
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	h.chasers[round] = cancel
+	h.mutex.Unlock()
+
+	h.t.AcknowledgeMessage(evt.Message)
+
+	go func() {
+		defer func() {
+			h.mutex.Lock()
+			delete(h.chasers, round)
+			h.mutex.Unlock()
+			cancel()
+		}()
+
+		board := ballotchaser.NewBoard(h.t.discord, tab.ID)
+		report := func(pages []string) {
+			if err := board.Show(pages); err != nil {
+				log.Printf("error updating ballot summary: %v", err.Error())
+			}
+		}
+
+		chaser := ballotchaser.New(h.t.tabbycat, h.t.database, h.t.messenger, h.t.ballotInterval)
+		chaser.SetTemplates(h.t.templates)
+		chaser.SetLocation(h.t.scheduler.location)
+		err := chaser.Chase(ctx, round, report)
+		if err != nil && !errors.Is(err, context.Canceled) {
+			log.Printf("error chasing ballots: %v", err.Error())
+			h.t.ReplyMessage(evt.Message, "I had to stop chasing ballots: %v.", describeTabbycatError(err))
+		}
+	}()
+}
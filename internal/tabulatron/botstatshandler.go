@@ -0,0 +1,38 @@
+package tabulatron
+
+import (
+	"regexp"
+
+	"github.com/andersfylling/disgord"
+)
+
+var botstats *regexp.Regexp = regexp.MustCompile(`^!botstats$`)
+
+type BotStatsHandler struct {
+	t *Tabulatron
+}
+
+func NewBotStatsHandler(t *Tabulatron) *BotStatsHandler {
+	return &BotStatsHandler{
+		t: t,
+	}
+}
+
+func (h *BotStatsHandler) CanHandle(evt *disgord.MessageCreate) bool {
+	return botstats.MatchString(evt.Message.Content)
+}
+
+func (h *BotStatsHandler) Handle(evt *disgord.MessageCreate) {
+	if !h.t.fromTab(evt.Message) {
+		return
+	}
+
+	if h.t.bots == nil {
+		h.t.ReplyMessage(evt.Message, "I'm not keeping track of my bots.")
+		h.t.RejectMessage(evt.Message)
+		return
+	}
+
+	h.t.ReplyMessage(evt.Message, "here's how my bots are doing:\n%v", h.t.bots.Metrics())
+	h.t.AcknowledgeMessage(evt.Message)
+}
@@ -2,12 +2,15 @@ package tabulatron
 
 import (
 	"context"
-	"fmt"
 	"log"
 	"regexp"
 	"strings"
+	"sync"
 
 	"github.com/andersfylling/disgord"
+	"github.com/hitecherik/Tabulatron/internal/interactions"
+	"github.com/hitecherik/Tabulatron/internal/layout"
+	"github.com/hitecherik/Tabulatron/internal/templates"
 )
 
 var (
@@ -18,206 +21,196 @@ var (
 	endcheckin   *regexp.Regexp = regexp.MustCompile(`^\s*!endcheckin\s*$`)
 )
 
+const checkinButton string = "checkin"
+
 type CheckinHandler struct {
-	t               *Tabulatron
-	checkinStarted  bool
-	checkinChannel  *disgord.Channel
-	checkoutChannel *disgord.Channel
-	techHelpChannel *disgord.Channel
-	tabRole         *disgord.Role
-	judgeRole       *disgord.Role
+	t       *Tabulatron
+	mutex   sync.Mutex
+	started bool
 }
 
 func NewCheckinHandler(t *Tabulatron) *CheckinHandler {
 	return &CheckinHandler{
-		t:              t,
-		checkinStarted: false,
+		t: t,
 	}
 }
 
-func (h *CheckinHandler) CanHandle(_ disgord.Session, evt *disgord.MessageCreate) bool {
+func (h *CheckinHandler) CanHandle(evt *disgord.MessageCreate) bool {
 	message := []byte(strings.ToLower(evt.Message.Content))
 
 	return checkin.Match(message) || checkout.Match(message) || startcheckin.Match(message) || endcheckin.Match(message)
 }
 
-func (h *CheckinHandler) Handle(s disgord.Session, evt *disgord.MessageCreate) {
+func (h *CheckinHandler) Handle(evt *disgord.MessageCreate) {
 	rawMessage := []byte(strings.ToLower(evt.Message.Content))
 
 	if startcheckin.Match(rawMessage) {
-		if h.checkinStarted {
+		if h.isStarted() {
 			h.t.ReplyMessage(evt.Message, "I can't do that. Check-in has already started.")
 			h.t.RejectMessage(evt.Message)
 			return
 		}
 
-		if err := h.populateChannels(evt); err != nil {
-			log.Printf("error populating channels: %v", err.Error())
-			return
-		}
-
-		if err := h.populateRoles(evt); err != nil {
-			log.Printf("error populating roles: %v", err.Error())
-			return
-		}
-
-		if h.hasTabRole(evt.Message.Member) {
+		if h.t.fromTab(evt.Message) {
 			h.t.AcknowledgeMessage(evt.Message)
-			h.checkinStarted = true
-			return
+			h.setStarted(true)
+			go h.postButton(evt.Message.GuildID)
 		}
 
-		h.t.ReplyMessage(evt.Message, "you can't ask me to do that.")
-		h.t.RejectMessage(evt.Message)
 		return
 	}
 
-	if !h.checkinStarted {
-		h.t.ReplyMessage(evt.Message, "I can't do that. Check-in hasn't started yet.")
+	guild := evt.Message.GuildID
+	judge := h.isJudge(guild, evt.Message.Member)
+
+	if !h.isStarted() {
+		h.t.ReplyMessage(evt.Message, "%v", h.t.render(evt.Message.Author.ID, "checkin.notstarted", h.templateData(guild, judge, "", "")))
 		h.t.RejectMessage(evt.Message)
 		return
 	}
 
 	if endcheckin.Match(rawMessage) {
-		if h.hasTabRole(evt.Message.Member) {
+		if h.t.fromTab(evt.Message) {
 			h.t.AcknowledgeMessage(evt.Message)
-			h.checkinStarted = false
-			return
+			h.setStarted(false)
 		}
 
-		h.t.ReplyMessage(evt.Message, "you can't ask me to do that.")
-		h.t.RejectMessage(evt.Message)
 		return
 	}
 
-	if (evt.Message.ChannelID != h.checkinChannel.ID && evt.Message.ChannelID != h.checkoutChannel.ID) ||
-		(evt.Message.ChannelID == h.checkoutChannel.ID && !h.hasJudgeRole(evt.Message.Member)) {
-		var message string
-
-		if h.hasJudgeRole(evt.Message.Member) {
-			message = fmt.Sprintf(
-				"Check-in and check-out can only happen in the %v or %v channels.",
-				h.checkinChannel.Mention(),
-				h.checkoutChannel.Mention(),
-			)
-		} else {
-			message = fmt.Sprintf("Check-in can only happen in the %v channel.", h.checkinChannel.Mention())
-		}
-
-		h.t.ReplyMessage(evt.Message, "you can't do that here. %v", message)
+	inCheckin := h.t.inChannel(evt.Message, layout.CheckinChannel)
+	inCheckout := h.t.inChannel(evt.Message, layout.AvailabilityChannel)
+	if !(inCheckin || (inCheckout && judge)) {
+		h.t.ReplyMessage(evt.Message, "%v", h.t.render(evt.Message.Author.ID, "checkin.wrongchannel", h.templateData(guild, judge, "", "")))
 		h.t.RejectMessage(evt.Message)
 		return
 	}
 
-	if !h.hasJudgeRole(evt.Message.Member) && checkout.Match(rawMessage) {
-		h.t.ReplyMessage(evt.Message, "you can't do that. Only judges can check out.")
+	if reply := h.check(guild, evt.Message.Author.ID, judge, checkout.Match(rawMessage)); reply != "" {
+		h.t.ReplyMessage(evt.Message, "%v", reply)
 		h.t.RejectMessage(evt.Message)
 		return
 	}
 
-	direction := "in"
-	if checkout.Match(rawMessage) {
-		direction = "out"
+	h.t.AcknowledgeMessage(evt.Message)
+	if chicken.Match(rawMessage) {
+		h.t.reactMessage(evt.Message, "🐓")
 	}
+}
 
-	id, speaker, err := h.t.database.ParticipantFromDiscord(evt.Message.Author.ID.String())
-	if err != nil {
-		log.Printf("error finding participant: %v", err.Error())
-
-		h.t.ReplyMessage(
-			evt.Message,
-			"there was an error checking you %v. Please ask for help in %v.",
-			direction,
-			h.techHelpChannel.Mention(),
-		)
-		h.t.RejectMessage(evt.Message)
+// postButton lets people check in without typing anything, if the bot can
+// receive interactions.
+func (h *CheckinHandler) postButton(guild disgord.Snowflake) {
+	if h.t.interactions == nil {
 		return
 	}
 
-	if checkout.Match(rawMessage) {
-		err = h.t.tabbycat.CheckOutAdjudicator(id)
-	} else {
-		err = h.t.tabbycat.CheckIn(id, speaker)
-	}
-
+	channel, err := h.t.channel(guild, layout.CheckinChannel)
 	if err != nil {
-		log.Printf("error checking %v participant: %v", direction, err.Error())
-		h.t.ReplyMessage(
-			evt.Message,
-			"there was an error checking you %v. Please ask for help in %v.",
-			direction,
-			h.techHelpChannel.Mention(),
-		)
-		h.t.RejectMessage(evt.Message)
+		log.Printf("error finding checkin channel: %v", err.Error())
 		return
 	}
 
-	h.t.AcknowledgeMessage(evt.Message)
-	if chicken.Match(rawMessage) {
-		h.t.reactMessage(evt.Message, "🐓")
+	ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+	defer cancel()
+
+	button := interactions.Button{Label: "Check in", CustomId: checkinButton}
+	if err := h.t.interactions.SendButton(ctx, channel.ID, "Check-in is open! Press the button below to check in.", button); err != nil {
+		log.Printf("error posting check-in button: %v", err.Error())
 	}
 }
 
-func (h *CheckinHandler) populateChannels(evt *disgord.MessageCreate) error {
-	if h.checkinChannel != nil && h.techHelpChannel != nil {
-		return nil
+// handleCommand checks member in or out from the /checkin and /checkout
+// commands or the check-in button, which work in any channel, and returns the
+// reply.
+func (h *CheckinHandler) handleCommand(guild disgord.Snowflake, member *disgord.Member, out bool) string {
+	judge := h.isJudge(guild, member)
+
+	if !h.isStarted() {
+		return h.t.render(member.User.ID, "checkin.notstarted", h.templateData(guild, judge, "", ""))
 	}
 
-	channels, err := h.t.discord.GetGuildChannels(context.Background(), evt.Message.GuildID)
-	if err != nil {
-		return err
+	direction := "in"
+	if out {
+		direction = "out"
 	}
 
-	for _, channel := range channels {
-		if channel.Name == "tab-and-tech-help" {
-			h.techHelpChannel = channel
-		} else if channel.Name == "checkin" {
-			h.checkinChannel = channel
-		} else if channel.Name == "adjudicator-availability" {
-			h.checkoutChannel = channel
-		}
+	if reply := h.check(guild, member.User.ID, judge, out); reply != "" {
+		return reply
 	}
 
-	return nil
+	return h.t.render(member.User.ID, "checkin.success", h.templateData(guild, judge, direction, ""))
 }
 
-func (h *CheckinHandler) populateRoles(evt *disgord.MessageCreate) error {
-	if h.tabRole != nil {
-		return nil
+// check tells Tabbycat that user has checked in or out, and returns the reply
+// if it couldn't.
+func (h *CheckinHandler) check(guild disgord.Snowflake, user disgord.Snowflake, judge bool, out bool) string {
+	if !judge && out {
+		return h.t.render(user, "checkin.judgesonly", h.templateData(guild, judge, "out", ""))
 	}
 
-	roles, err := h.t.discord.GetGuildRoles(context.Background(), evt.Message.GuildID)
+	direction := "in"
+	if out {
+		direction = "out"
+	}
+
+	id, speaker, err := h.t.database.ParticipantFromDiscord(user.String())
 	if err != nil {
-		return nil
+		log.Printf("error finding participant: %v", err.Error())
+		return h.t.render(user, "checkin.error", h.templateData(guild, judge, direction, ""))
 	}
 
-	for _, role := range roles {
-		if role.Name == "Tab/Tech" {
-			h.tabRole = role
-		} else if role.Name == "Judge" {
-			h.judgeRole = role
-		}
+	ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+	defer cancel()
+
+	if out {
+		err = h.t.tabbycat.CheckOutAdjudicatorContext(ctx, id)
+	} else {
+		err = h.t.tabbycat.CheckInContext(ctx, id, speaker)
+	}
+
+	if err != nil {
+		log.Printf("error checking %v participant: %v", direction, err.Error())
+		return h.t.render(user, "checkin.error", h.templateData(guild, judge, direction, h.t.translate(user, describeTabbycatError(err))))
 	}
 
-	return nil
+	return ""
 }
 
-func (h *CheckinHandler) hasTabRole(member *disgord.Member) bool {
-	for _, role := range member.Roles {
-		if role == h.tabRole.ID {
-			return true
-		}
+func (h *CheckinHandler) templateData(guild disgord.Snowflake, judge bool, direction string, reason string) templates.Checkin {
+	return templates.Checkin{
+		Direction:       direction,
+		Judge:           judge,
+		Error:           reason,
+		CheckinChannel:  h.t.mention(guild, layout.CheckinChannel),
+		CheckoutChannel: h.t.mention(guild, layout.AvailabilityChannel),
+		HelpChannel:     h.t.mention(guild, layout.HelpChannel),
 	}
-
-	return false
 }
 
-func (h *CheckinHandler) hasJudgeRole(member *disgord.Member) bool {
-	for _, role := range member.Roles {
-		if role == h.judgeRole.ID {
-			return true
-		}
+// isJudge treats anyone as a speaker if the judge role can't be found, so
+// that they can still check in.
+func (h *CheckinHandler) isJudge(guild disgord.Snowflake, member *disgord.Member) bool {
+	judge, err := h.t.hasRole(guild, layout.JudgeRole, member)
+	if err != nil {
+		log.Printf("error checking for judge role: %v", err.Error())
 	}
 
-	return false
+	return judge
+}
+
+// isStarted reports whether check-in is open. Slash commands arrive on the
+// interactions server's goroutine, so it's guarded by the mutex.
+func (h *CheckinHandler) isStarted() bool {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	return h.started
+}
+
+func (h *CheckinHandler) setStarted(started bool) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.started = started
 }
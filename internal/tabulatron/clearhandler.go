@@ -12,8 +12,7 @@ import (
 var clear *regexp.Regexp = regexp.MustCompile(`^!clear\s*(\d{6})$`)
 
 type ClearHandler struct {
-	t       *Tabulatron
-	tabRole *disgord.Role
+	t *Tabulatron
 }
 
 func NewClearHandler(t *Tabulatron) *ClearHandler {
@@ -22,23 +21,16 @@ func NewClearHandler(t *Tabulatron) *ClearHandler {
 	}
 }
 
-func (h *ClearHandler) CanHandle(_ disgord.Session, evt *disgord.MessageCreate) bool {
+func (h *ClearHandler) CanHandle(evt *disgord.MessageCreate) bool {
 	message := []byte(evt.Message.Content)
 
 	return clear.Match(message)
 }
 
-func (h *ClearHandler) Handle(s disgord.Session, evt *disgord.MessageCreate) {
+func (h *ClearHandler) Handle(evt *disgord.MessageCreate) {
 	rawMessage := []byte(evt.Message.Content)
 
-	if err := h.populateRoles(evt); err != nil {
-		log.Printf("error populating roles: %v", err.Error())
-		return
-	}
-
-	if !h.hasTabRole(evt.Message.Member) {
-		h.t.ReplyMessage(evt.Message, "you can't ask me to do that.")
-		h.t.RejectMessage(evt.Message)
+	if !h.t.fromTab(evt.Message) {
 		return
 	}
 
@@ -61,11 +53,7 @@ func (h *ClearHandler) Handle(s disgord.Session, evt *disgord.MessageCreate) {
 		return
 	}
 
-	err = h.t.discord.
-		UpdateGuildMember(context.Background(), evt.Message.GuildID, snowflake).
-		DeleteNick().
-		SetRoles([]disgord.Snowflake{}).
-		Execute()
+	err = h.t.discord.UpdateMember(context.Background(), evt.Message.GuildID, snowflake, "", []disgord.Snowflake{})
 	if err != nil {
 		log.Printf("error resetting user: %v", err.Error())
 		h.t.ReplyMessage(evt.Message, "there was an error resetting the user.")
@@ -75,32 +63,3 @@ func (h *ClearHandler) Handle(s disgord.Session, evt *disgord.MessageCreate) {
 
 	h.t.AcknowledgeMessage(evt.Message)
 }
-
-func (h *ClearHandler) populateRoles(evt *disgord.MessageCreate) error {
-	if h.tabRole != nil {
-		return nil
-	}
-
-	roles, err := h.t.discord.GetGuildRoles(context.Background(), evt.Message.GuildID)
-	if err != nil {
-		return nil
-	}
-
-	for _, role := range roles {
-		if role.Name == "Tab/Tech" {
-			h.tabRole = role
-		}
-	}
-
-	return nil
-}
-
-func (h *ClearHandler) hasTabRole(member *disgord.Member) bool {
-	for _, role := range member.Roles {
-		if role == h.tabRole.ID {
-			return true
-		}
-	}
-
-	return false
-}
@@ -0,0 +1,174 @@
+package tabulatron
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/andersfylling/disgord"
+	"github.com/hitecherik/Tabulatron/internal/layout"
+	"github.com/hitecherik/Tabulatron/internal/util"
+	"github.com/hitecherik/Tabulatron/pkg/tabbycat"
+)
+
+var draw *regexp.Regexp = regexp.MustCompile(`^!draw\s*(\d+)$`)
+
+type DrawHandler struct {
+	t *Tabulatron
+}
+
+func NewDrawHandler(t *Tabulatron) *DrawHandler {
+	return &DrawHandler{
+		t: t,
+	}
+}
+
+func (h *DrawHandler) CanHandle(evt *disgord.MessageCreate) bool {
+	return draw.MatchString(evt.Message.Content)
+}
+
+func (h *DrawHandler) Handle(evt *disgord.MessageCreate) {
+	if !h.t.fromTab(evt.Message) {
+		return
+	}
+
+	drawChannel, err := h.t.channel(evt.Message.GuildID, layout.DrawChannel)
+	if err != nil {
+		log.Printf("error finding draw channel: %v", err.Error())
+		h.t.ReplyMessage(evt.Message, "I can't do that: %v.", err.Error())
+		h.t.RejectMessage(evt.Message)
+		return
+	}
+
+	matches := draw.FindStringSubmatch(evt.Message.Content)
+	id, err := strconv.ParseUint(matches[1], 10, 64)
+	if err != nil {
+		log.Printf("error extracting round: %v", err.Error())
+		h.t.ReplyMessage(evt.Message, "there was an error parsing your request.")
+		h.t.RejectMessage(evt.Message)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+	defer cancel()
+
+	pages, err := h.buildDraw(ctx, id)
+	if err != nil {
+		log.Printf("error building draw: %v", err.Error())
+
+		if errors.Is(err, tabbycat.ErrNotFound) {
+			h.t.ReplyMessage(evt.Message, "I couldn't find a draw for that round.")
+		} else {
+			h.t.ReplyMessage(evt.Message, "there was an error fetching the draw: %v.", describeTabbycatError(err))
+		}
+
+		h.t.RejectMessage(evt.Message)
+		return
+	}
+
+	for _, page := range pages {
+		if _, err := h.t.discord.SendMessage(context.Background(), drawChannel.ID, page); err != nil {
+			log.Printf("error posting draw: %v", err.Error())
+			h.t.ReplyMessage(evt.Message, "there was an error posting the draw.")
+			h.t.RejectMessage(evt.Message)
+			return
+		}
+	}
+
+	h.t.AcknowledgeMessage(evt.Message)
+}
+
+func (h *DrawHandler) buildDraw(ctx context.Context, id uint64) ([]string, error) {
+	round, err := h.t.tabbycat.GetRoundContext(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	rooms, err := h.t.tabbycat.GetDrawContext(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rooms) == 0 {
+		return []string{fmt.Sprintf("There is no draw for **%v** yet.", round.Name)}, nil
+	}
+
+	venues, err := h.t.tabbycat.GetVenuesContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	categories, err := h.t.tabbycat.GetVenueCategoriesContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	teams, err := h.t.tabbycat.GetTeamNamesContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	adjudicators, err := h.t.tabbycat.GetAdjudicatorNamesContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	categoryNames := make(map[string]string, len(categories))
+	for _, category := range categories {
+		categoryNames[category.Url] = category.Name
+	}
+
+	venueNames := make(map[string]string, len(venues))
+	venueCategories := make(map[string]string, len(venues))
+	for _, venue := range venues {
+		venueId := strconv.FormatUint(uint64(venue.Id), 10)
+		venueNames[venueId] = venue.Name
+
+		names := make([]string, 0, len(venue.Categories))
+		for _, category := range venue.Categories {
+			if name, ok := categoryNames[category]; ok {
+				names = append(names, name)
+			}
+		}
+		venueCategories[venueId] = strings.Join(names, ", ")
+	}
+
+	sort.SliceStable(rooms, func(i, j int) bool {
+		return venueNames[rooms[i].VenueId] < venueNames[rooms[j].VenueId]
+	})
+
+	blocks := make([]string, 0, len(rooms))
+	for _, room := range rooms {
+		lines := make([]string, 0, 3)
+
+		venue := fmt.Sprintf("**%v**", venueNames[room.VenueId])
+		if category := venueCategories[room.VenueId]; category != "" {
+			venue = fmt.Sprintf("%v (%v)", venue, category)
+		}
+		lines = append(lines, venue)
+
+		sides := make([]string, 0, len(room.TeamIds))
+		for i, team := range room.TeamIds {
+			sides = append(sides, fmt.Sprintf("%v: %v", room.SideNames[i], teams[team]))
+		}
+		lines = append(lines, strings.Join(sides, " · "))
+
+		judges := []string{fmt.Sprintf("%v ©", adjudicators[room.ChairId])}
+		for _, panellist := range room.PanellistIds {
+			judges = append(judges, adjudicators[panellist])
+		}
+		for _, trainee := range room.TraineeIds {
+			judges = append(judges, fmt.Sprintf("%v (t)", adjudicators[trainee]))
+		}
+		lines = append(lines, fmt.Sprintf("Adjudicators: %v", strings.Join(judges, ", ")))
+
+		blocks = append(blocks, strings.Join(lines, "\n"))
+	}
+
+	return util.Paginate(fmt.Sprintf("The draw for **%v**", round.Name), blocks, util.MessageLimit), nil
+}
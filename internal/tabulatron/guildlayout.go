@@ -0,0 +1,135 @@
+package tabulatron
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/andersfylling/disgord"
+	"github.com/hitecherik/Tabulatron/internal/layout"
+)
+
+// guildLayout holds the layout's roles and channels once they've been found
+// in the guild, so that every handler shares one lookup. Anything missing is
+// looked up again next time, in case it's been created since.
+type guildLayout struct {
+	mutex    sync.Mutex
+	roles    map[string]*disgord.Role
+	channels map[string]*disgord.Channel
+}
+
+func newGuildLayout() *guildLayout {
+	return &guildLayout{
+		roles:    make(map[string]*disgord.Role),
+		channels: make(map[string]*disgord.Channel),
+	}
+}
+
+func (t *Tabulatron) role(guild disgord.Snowflake, name string) (*disgord.Role, error) {
+	t.resolved.mutex.Lock()
+	defer t.resolved.mutex.Unlock()
+
+	if role, ok := t.resolved.roles[name]; ok {
+		return role, nil
+	}
+
+	roles, err := t.discord.GetGuildRoles(context.Background(), guild)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, role := range roles {
+		if t.layout.IsRole(name, role) {
+			t.resolved.roles[name] = role
+			return role, nil
+		}
+	}
+
+	return nil, fmt.Errorf("the server has no %v role", t.layout.Role(name))
+}
+
+func (t *Tabulatron) channel(guild disgord.Snowflake, name string) (*disgord.Channel, error) {
+	t.resolved.mutex.Lock()
+	defer t.resolved.mutex.Unlock()
+
+	if channel, ok := t.resolved.channels[name]; ok {
+		return channel, nil
+	}
+
+	channels, err := t.discord.GetGuildChannels(context.Background(), guild)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, channel := range channels {
+		if t.layout.IsChannel(name, channel) {
+			t.resolved.channels[name] = channel
+			return channel, nil
+		}
+	}
+
+	return nil, fmt.Errorf("the server has no %v channel", t.layout.Channel(name))
+}
+
+func (t *Tabulatron) hasRole(guild disgord.Snowflake, name string, member *disgord.Member) (bool, error) {
+	if member == nil {
+		return false, nil
+	}
+
+	role, err := t.role(guild, name)
+	if err != nil {
+		return false, err
+	}
+
+	for _, id := range member.Roles {
+		if id == role.ID {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// inChannel is false when the channel can't be found, as well as when the
+// message was sent somewhere else.
+func (t *Tabulatron) inChannel(message *disgord.Message, name string) bool {
+	channel, err := t.channel(message.GuildID, name)
+	if err != nil {
+		log.Printf("error finding %v channel: %v", name, err.Error())
+		return false
+	}
+
+	return message.ChannelID == channel.ID
+}
+
+// mention is empty when the channel can't be found.
+func (t *Tabulatron) mention(guild disgord.Snowflake, name string) string {
+	channel, err := t.channel(guild, name)
+	if err != nil {
+		log.Printf("error finding %v channel: %v", name, err.Error())
+		return ""
+	}
+
+	return channel.Mention()
+}
+
+// fromTab rejects the message, with a reply saying why, unless its author is
+// on the tab team.
+func (t *Tabulatron) fromTab(message *disgord.Message) bool {
+	tab, err := t.hasRole(message.GuildID, layout.TabRole, message.Member)
+	if err != nil {
+		log.Printf("error checking for tab role: %v", err.Error())
+		t.ReplyMessage(message, "I couldn't check whether you're on the tab team: %v.", err.Error())
+		t.RejectMessage(message)
+		return false
+	}
+
+	if !tab {
+		t.ReplyMessage(message, "you can't ask me to do that.")
+		t.RejectMessage(message)
+		return false
+	}
+
+	return true
+}
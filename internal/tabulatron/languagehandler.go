@@ -0,0 +1,85 @@
+package tabulatron
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"regexp"
+	"strings"
+
+	"github.com/andersfylling/disgord"
+	"github.com/hitecherik/Tabulatron/internal/locale"
+)
+
+var setlanguage *regexp.Regexp = regexp.MustCompile(`^!language(?:\s+([A-Za-z_-]+))?$`)
+
+type LanguageHandler struct {
+	t *Tabulatron
+}
+
+func NewLanguageHandler(t *Tabulatron) *LanguageHandler {
+	return &LanguageHandler{
+		t: t,
+	}
+}
+
+func (h *LanguageHandler) CanHandle(evt *disgord.MessageCreate) bool {
+	return setlanguage.MatchString(strings.TrimSpace(evt.Message.Content))
+}
+
+func (h *LanguageHandler) Handle(evt *disgord.MessageCreate) {
+	matches := setlanguage.FindStringSubmatch(strings.TrimSpace(evt.Message.Content))
+	code := locale.Normalise(matches[1])
+	languages := h.languages()
+
+	if code == "" {
+		h.t.ReplyMessage(evt.Message, "you can choose from these languages: %v.", strings.Join(languages, ", "))
+		return
+	}
+
+	if !h.supported(code) {
+		h.t.ReplyMessage(evt.Message, "I don't speak that language. You can choose from: %v.", strings.Join(languages, ", "))
+		h.t.RejectMessage(evt.Message)
+		return
+	}
+
+	stored := code
+	if code == locale.Default {
+		stored = ""
+	}
+
+	if err := h.t.database.SetLanguage(evt.Message.Author.ID.String(), stored); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			h.t.ReplyMessage(evt.Message, "you need to register before you can choose a language.")
+		} else {
+			log.Printf("error setting language: %v", err.Error())
+			h.t.ReplyMessage(evt.Message, "there was an error setting your language.")
+		}
+
+		h.t.RejectMessage(evt.Message)
+		return
+	}
+
+	h.t.AcknowledgeMessage(evt.Message)
+}
+
+func (h *LanguageHandler) supported(code string) bool {
+	return code == locale.Default || h.t.catalogue.Has(code) || h.t.templates.Has(code)
+}
+
+func (h *LanguageHandler) languages() []string {
+	languages := []string{locale.Default}
+
+	for _, code := range append(h.t.catalogue.Languages(), h.t.templates.Languages()...) {
+		found := false
+		for _, existing := range languages {
+			found = found || existing == code
+		}
+
+		if !found {
+			languages = append(languages, code)
+		}
+	}
+
+	return languages
+}
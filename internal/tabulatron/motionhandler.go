@@ -2,6 +2,7 @@ package tabulatron
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"regexp"
@@ -10,23 +11,21 @@ import (
 	"time"
 
 	"github.com/andersfylling/disgord"
-)
-
-const (
-	prepMinutes int           = 15
-	minute      time.Duration = time.Second * 60
+	"github.com/hitecherik/Tabulatron/internal/layout"
+	"github.com/hitecherik/Tabulatron/internal/preptimer"
+	"github.com/hitecherik/Tabulatron/internal/templates"
+	"github.com/hitecherik/Tabulatron/pkg/tabbycat"
 )
 
 var (
-	infoslide *regexp.Regexp = regexp.MustCompile(`^!infoslide\s*\d+$`)
-	motion    *regexp.Regexp = regexp.MustCompile(`^!motion\s*\d+$`)
-	roundId   *regexp.Regexp = regexp.MustCompile(`\s*(\d+)$`)
+	infoslide *regexp.Regexp = regexp.MustCompile(`^!infoslide\s*\d+(\s+\S+)?$`)
+	motion    *regexp.Regexp = regexp.MustCompile(`^!motion\s*\d+(\s+\S+)?$`)
+	vetoes    *regexp.Regexp = regexp.MustCompile(`^!vetoes\s*\d+$`)
+	roundId   *regexp.Regexp = regexp.MustCompile(`^!\w+\s*(\d+)\s*(\S*)$`)
 )
 
 type MotionHandler struct {
-	t              *Tabulatron
-	tabRole        *disgord.Role
-	motionsChannel *disgord.Channel
+	t *Tabulatron
 }
 
 func NewMotionHandler(t *Tabulatron) *MotionHandler {
@@ -35,31 +34,27 @@ func NewMotionHandler(t *Tabulatron) *MotionHandler {
 	}
 }
 
-func (h *MotionHandler) CanHandle(_ disgord.Session, evt *disgord.MessageCreate) bool {
+func (h *MotionHandler) CanHandle(evt *disgord.MessageCreate) bool {
 	message := []byte(evt.Message.Content)
 
-	return infoslide.Match(message) || motion.Match(message)
+	return infoslide.Match(message) || motion.Match(message) || vetoes.Match(message)
 }
 
-func (h *MotionHandler) Handle(s disgord.Session, evt *disgord.MessageCreate) {
+func (h *MotionHandler) Handle(evt *disgord.MessageCreate) {
 	rawMessage := []byte(evt.Message.Content)
 
-	if err := h.populateRoles(evt); err != nil {
-		log.Printf("error populating roles: %v", err.Error())
+	if !h.t.fromTab(evt.Message) {
 		return
 	}
 
-	if !h.hasTabRole(evt.Message.Member) {
-		h.t.ReplyMessage(evt.Message, "you can't ask me to do that.")
+	drawChannel, err := h.t.channel(evt.Message.GuildID, layout.DrawChannel)
+	if err != nil {
+		log.Printf("error finding draw channel: %v", err.Error())
+		h.t.ReplyMessage(evt.Message, "I can't do that: %v.", err.Error())
 		h.t.RejectMessage(evt.Message)
 		return
 	}
 
-	if err := h.populateChannels(evt); err != nil {
-		log.Printf("error populating channels: %v", err.Error())
-		return
-	}
-
 	matches := roundId.FindSubmatch(rawMessage)
 	id, err := strconv.ParseUint(string(matches[1]), 10, 64)
 	if err != nil {
@@ -69,154 +64,137 @@ func (h *MotionHandler) Handle(s disgord.Session, evt *disgord.MessageCreate) {
 		return
 	}
 
-	round, err := h.t.tabbycat.GetRound(id)
+	ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+	defer cancel()
+
+	if vetoes.Match(rawMessage) {
+		h.countVetoes(ctx, evt, id)
+		return
+	}
+
+	// Claim any schedule for the round, so that it doesn't announce the
+	// motion again once its time comes.
+	key := preptimer.NewKey(id, string(matches[2]))
+	claim := h.t.database.ClaimMotion
+	if infoslide.Match(rawMessage) {
+		claim = h.t.database.ClaimInfoSlide
+	}
+
+	if _, err := claim(key.Round, key.Track); err != nil {
+		log.Printf("error claiming scheduled release: %v", err.Error())
+	}
+
+	err = h.t.announceMotion(ctx, drawChannel.ID, id, string(matches[2]), infoslide.Match(rawMessage))
 	if err != nil {
-		log.Printf("error fetching round: %v", err.Error())
-		h.t.ReplyMessage(evt.Message, "I couldn't find any information about that round.")
+		h.t.ReplyMessage(evt.Message, "%v.", err.Error())
 		h.t.RejectMessage(evt.Message)
 		return
 	}
 
-	message := ""
+	err = h.t.discord.DeleteMessage(context.Background(), evt.Message.ChannelID, evt.Message.ID)
+	if err != nil {
+		log.Printf("error deleting message: %v", err.Error())
+	}
+}
+
+func (t *Tabulatron) announceMotion(ctx context.Context, channel disgord.Snowflake, id uint64, track string, infoSlide bool) error {
+	round, err := t.tabbycat.GetRoundContext(ctx, id)
+	if err != nil {
+		log.Printf("error fetching round: %v", err.Error())
+
+		if errors.Is(err, tabbycat.ErrNotFound) {
+			return errors.New("I couldn't find any information about that round")
+		}
+
+		return fmt.Errorf("there was an error fetching that round: %v", describeTabbycatError(err))
+	}
+
 	roundName := round.Name
 
 	if !strings.HasPrefix(roundName, "Round ") {
 		roundName = fmt.Sprintf("The %v", roundName)
 	}
 
-	if infoslide.Match(rawMessage) {
-		if round.Motion.InfoSlide == "" {
-			message = fmt.Sprintf("There is no info slide for %v.", roundName)
-		} else {
-			message = fmt.Sprintf("@everyone\nThe info slide for **%v** is:\n\n%v", roundName, round.Motion.InfoSlide)
+	var seqs []uint
+	if track != "" {
+		var trackName string
+		if trackName, seqs, err = t.tracks.Lookup(track); err != nil {
+			return err
 		}
-	} else {
-		if round.Motion.Motion == "" {
-			message = fmt.Sprintf("There is no motion for %v.", roundName)
-		} else {
-			message = fmt.Sprintf("@everyone\nThe motion for **%v** is:\n\n%v", roundName, round.Motion.Motion)
-		}
-	}
 
-	if _, err := h.t.discord.SendMsg(context.Background(), h.motionsChannel.ID, message); err != nil {
-		log.Printf("error announcing round: %v", err.Error())
+		roundName = fmt.Sprintf("%v (%v)", roundName, trackName)
 	}
 
-	err = h.t.discord.DeleteMessage(context.Background(), evt.Message.ChannelID, evt.Message.ID)
-	if err != nil {
-		log.Printf("error deleting message: %v", err.Error())
+	motions := round.MotionsWithSeqs(seqs)
+	if track != "" && len(motions) == 0 {
+		return fmt.Errorf("%v has none of the motions for the %v track", roundName, track)
 	}
 
-	if motion.Match(rawMessage) {
-		err := h.t.tabbycat.ReleaseMotion(id, time.Now().Add(time.Duration(prepMinutes)*minute))
-		if err != nil {
-			log.Printf("error releasing motion on tabbycat: %v", err.Error())
-		}
+	var message string
+	if infoSlide {
+		message = t.announceMotions("motion.infoslide", roundName, motions, func(m tabbycat.Motion) string { return m.InfoSlide })
+	} else {
+		message = t.announceMotions("motion.motion", roundName, motions, func(m tabbycat.Motion) string { return m.Motion })
+	}
 
-		go func() {
-			timeLeft := prepMinutes
-
-			msg, err := h.t.discord.SendMsg(context.Background(), h.motionsChannel.ID, generatePrepTimeMessage(timeLeft))
-			if err != nil {
-				log.Printf("timer broke: %v", err.Error())
-				return
-			}
-
-			ticker := time.NewTicker(minute)
-
-			for {
-				<-ticker.C
-				timeLeft -= 1
-
-				if timeLeft == 0 {
-					ticker.Stop()
-					err = h.t.discord.DeleteMessage(context.Background(), msg.ChannelID, msg.ID)
-					if err != nil {
-						log.Printf("error deleting message: %v", err.Error())
-					}
-
-					_, err = h.t.discord.SendMsg(
-						context.Background(),
-						h.motionsChannel.ID,
-						fmt.Sprintf("@everyone Prep time for %v over!", roundName),
-					)
-					if err != nil {
-						log.Printf("error sending message: %v", err.Error())
-					}
-
-					return
-				} else {
-					msg, err = h.t.discord.UpdateMessage(context.Background(), msg.ChannelID, msg.ID).
-						SetContent(generatePrepTimeMessage(timeLeft)).
-						Execute()
-					if err != nil {
-						ticker.Stop()
-						log.Printf("error updating message: %v", err.Error())
-						return
-					}
-				}
-			}
-		}()
+	if _, err := t.discord.SendMessage(context.Background(), channel, message); err != nil {
+		log.Printf("error announcing round: %v", err.Error())
+		return fmt.Errorf("there was an error announcing %v", roundName)
 	}
-}
 
-func (h *MotionHandler) populateChannels(evt *disgord.MessageCreate) error {
-	if h.motionsChannel != nil {
+	if infoSlide {
 		return nil
 	}
 
-	channels, err := h.t.discord.GetGuildChannels(context.Background(), evt.Message.GuildID)
+	err = t.tabbycat.ReleaseMotionContext(ctx, id, time.Now().Add(t.timers.Length()))
 	if err != nil {
-		return err
+		log.Printf("error releasing motion on tabbycat: %v", err.Error())
+		return fmt.Errorf("the motion was announced, but releasing it on Tabbycat failed: %v", describeTabbycatError(err))
 	}
 
-	for _, channel := range channels {
-		if channel.Name == "motions-and-draw" {
-			h.motionsChannel = channel
-			break
-		}
+	err = t.timers.Start(preptimer.NewKey(id, track), roundName, channel, 0)
+	if err != nil {
+		log.Printf("error starting prep timer: %v", err.Error())
+		return fmt.Errorf("the motion was announced, but I couldn't start the prep timer: %v", err.Error())
 	}
 
 	return nil
 }
 
-func (h *MotionHandler) populateRoles(evt *disgord.MessageCreate) error {
-	if h.tabRole != nil {
-		return nil
+func (h *MotionHandler) countVetoes(ctx context.Context, evt *disgord.MessageCreate, id uint64) {
+	round, err := h.t.tabbycat.GetRoundContext(ctx, id)
+	if err != nil {
+		log.Printf("error fetching round: %v", err.Error())
+		h.t.ReplyMessage(evt.Message, "there was an error fetching that round: %v.", describeTabbycatError(err))
+		h.t.RejectMessage(evt.Message)
+		return
 	}
 
-	roles, err := h.t.discord.GetGuildRoles(context.Background(), evt.Message.GuildID)
+	ballots, err := h.t.tabbycat.GetRoundBallotsContext(ctx, id)
 	if err != nil {
-		return nil
+		log.Printf("error fetching ballots: %v", err.Error())
+		h.t.ReplyMessage(evt.Message, "there was an error fetching the ballots for that round: %v.", describeTabbycatError(err))
+		h.t.RejectMessage(evt.Message)
+		return
 	}
 
-	for _, role := range roles {
-		if role.Name == "Tab/Tech" {
-			h.tabRole = role
-		}
+	counts := tabbycat.CountVetoes(ballots)
+	lines := make([]string, 0, len(round.Motions))
+	for _, motion := range round.Motions {
+		lines = append(lines, fmt.Sprintf("**%v.** %v (%v): %v", motion.Seq, motion.Motion, motion.Reference, counts[motion.Id]))
 	}
 
-	return nil
+	h.t.ReplyMessage(evt.Message, "these are the vetoes on confirmed ballots for %v:\n%v", round.Name, strings.Join(lines, "\n"))
+	h.t.AcknowledgeMessage(evt.Message)
 }
 
-func (h *MotionHandler) hasTabRole(member *disgord.Member) bool {
-	for _, role := range member.Roles {
-		if role == h.tabRole.ID {
-			return true
+func (t *Tabulatron) announceMotions(template string, roundName string, motions []tabbycat.Motion, text func(tabbycat.Motion) string) string {
+	data := templates.Motions{Round: roundName, Motions: make([]templates.Motion, 0, len(motions))}
+	for _, motion := range motions {
+		if text(motion) != "" {
+			data.Motions = append(data.Motions, templates.Motion{Seq: motion.Seq, Text: text(motion)})
 		}
 	}
 
-	return false
-}
-
-func generatePrepTimeMessage(timeLeft int) string {
-	verb := "are"
-	noun := "minutes"
-
-	if timeLeft == 1 {
-		verb = "is"
-		noun = "minute"
-	}
-
-	return fmt.Sprintf("There %v **%v %v** of prep time left.", verb, timeLeft, noun)
+	return t.templates.Render(template, data)
 }
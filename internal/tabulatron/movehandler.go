@@ -0,0 +1,160 @@
+package tabulatron
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+
+	"github.com/andersfylling/disgord"
+	"github.com/hitecherik/Tabulatron/internal/layout"
+	"github.com/hitecherik/Tabulatron/internal/roundrunner"
+	"github.com/hitecherik/Tabulatron/internal/util"
+	"github.com/hitecherik/Tabulatron/pkg/tabbycat"
+)
+
+var moveRooms *regexp.Regexp = regexp.MustCompile(`^!moverooms\s+(\d+)$`)
+
+type MoveHandler struct {
+	t *Tabulatron
+}
+
+func NewMoveHandler(t *Tabulatron) *MoveHandler {
+	return &MoveHandler{
+		t: t,
+	}
+}
+
+func (h *MoveHandler) CanHandle(evt *disgord.MessageCreate) bool {
+	return moveRooms.MatchString(evt.Message.Content)
+}
+
+func (h *MoveHandler) Handle(evt *disgord.MessageCreate) {
+	if !h.t.fromTab(evt.Message) {
+		return
+	}
+
+	round, err := strconv.ParseUint(moveRooms.FindStringSubmatch(evt.Message.Content)[1], 10, 64)
+	if err != nil {
+		log.Printf("error extracting round: %v", err.Error())
+		h.t.ReplyMessage(evt.Message, "there was an error parsing your request.")
+		h.t.RejectMessage(evt.Message)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), pullTimeout)
+	defer cancel()
+
+	guild := evt.Message.GuildID
+	channels, err := h.t.discord.GetGuildChannels(ctx, guild)
+	if err != nil {
+		log.Printf("error getting channels: %v", err.Error())
+		h.t.ReplyMessage(evt.Message, "there was an error finding the waiting room.")
+		h.t.RejectMessage(evt.Message)
+		return
+	}
+
+	var waitingRoom *disgord.Channel
+	voiceChannels := make(map[string]*disgord.Channel)
+	for _, channel := range channels {
+		if channel.Type != disgord.ChannelTypeGuildVoice {
+			continue
+		}
+
+		if h.t.layout.IsChannel(layout.WaitingRoomChannel, channel) {
+			waitingRoom = channel
+		} else {
+			voiceChannels[channel.Name] = channel
+		}
+	}
+
+	if waitingRoom == nil {
+		h.t.ReplyMessage(evt.Message, "I couldn't find the waiting room.")
+		h.t.RejectMessage(evt.Message)
+		return
+	}
+
+	rooms, err := h.t.tabbycat.GetDrawContext(ctx, round)
+	if err != nil {
+		log.Printf("error getting draw: %v", err.Error())
+		h.t.ReplyMessage(evt.Message, "there was an error getting the draw: %v.", describeTabbycatError(err))
+		h.t.RejectMessage(evt.Message)
+		return
+	}
+
+	venues, err := h.t.tabbycat.GetVenuesContext(ctx)
+	if err != nil {
+		log.Printf("error getting venues: %v", err.Error())
+		h.t.ReplyMessage(evt.Message, "there was an error getting the venues: %v.", describeTabbycatError(err))
+		h.t.RejectMessage(evt.Message)
+		return
+	}
+	venueMap := roundrunner.BuildVenueMap(venues)
+
+	moved := 0
+	unplaced := make([]string, 0)
+	for _, user := range h.t.voice.in(guild, waitingRoom.ID) {
+		venue, reason := h.venueFor(user, rooms, venueMap)
+		if reason == "" {
+			if channel, ok := voiceChannels[venue]; !ok {
+				reason = fmt.Sprintf("there's no voice channel for %v", venue)
+			} else if err := h.t.discord.MoveMember(ctx, guild, user, channel.ID); err != nil {
+				log.Printf("error moving %v to %v: %v", user, venue, err.Error())
+				reason = fmt.Sprintf("I couldn't move them to %v", venue)
+			}
+		}
+
+		if reason != "" {
+			unplaced = append(unplaced, fmt.Sprintf("<@%v> (%v)", user, reason))
+			continue
+		}
+
+		moved += 1
+	}
+
+	if len(unplaced) > 0 {
+		// leave room for the mention ReplyMessage puts in front of each page
+		header := fmt.Sprintf("I moved %v people, but couldn't place these %v", moved, len(unplaced))
+		for _, page := range util.PaginateLines(header, unplaced, util.MessageLimit-len(evt.Message.Author.Mention())-2) {
+			h.t.ReplyMessage(evt.Message, "%v", page)
+		}
+		h.t.RejectMessage(evt.Message)
+		return
+	}
+
+	h.t.ReplyMessage(evt.Message, "I moved %v people.", moved)
+	h.t.AcknowledgeMessage(evt.Message)
+}
+
+// venueFor finds the venue a user is debating or judging in, or the reason why
+// they can't be placed.
+func (h *MoveHandler) venueFor(user disgord.Snowflake, rooms []tabbycat.Room, venueMap map[string]string) (string, string) {
+	id, speaker, err := h.t.database.ParticipantFromDiscord(user.String())
+	if err != nil {
+		return "", "they aren't registered"
+	}
+
+	participant := fmt.Sprint(id)
+	if speaker {
+		if participant, err = h.t.database.TeamFromParticipant(id); err != nil {
+			log.Printf("error finding team for participant %v: %v", id, err.Error())
+			return "", "I couldn't find their team"
+		}
+	}
+
+	for _, room := range rooms {
+		ids := room.TeamIds
+		if !speaker {
+			ids = append(append([]string{room.ChairId}, room.PanellistIds...), room.TraineeIds...)
+		}
+
+		for _, candidate := range ids {
+			if candidate == participant {
+				return venueMap[room.VenueId], ""
+			}
+		}
+	}
+
+	return "", "they aren't in the draw"
+}
@@ -0,0 +1,63 @@
+package tabulatron
+
+import (
+	"log"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/andersfylling/disgord"
+	"github.com/hitecherik/Tabulatron/internal/preptimer"
+)
+
+var prep *regexp.Regexp = regexp.MustCompile(`^!prep\s+(pause|resume|cancel|extend\s+(\d+))\s+(\d+)(?:\s+(\S+))?$`)
+
+type PrepHandler struct {
+	t *Tabulatron
+}
+
+func NewPrepHandler(t *Tabulatron) *PrepHandler {
+	return &PrepHandler{
+		t: t,
+	}
+}
+
+func (h *PrepHandler) CanHandle(evt *disgord.MessageCreate) bool {
+	return prep.MatchString(evt.Message.Content)
+}
+
+func (h *PrepHandler) Handle(evt *disgord.MessageCreate) {
+	if !h.t.fromTab(evt.Message) {
+		return
+	}
+
+	matches := prep.FindStringSubmatch(evt.Message.Content)
+	round, err := strconv.ParseUint(matches[3], 10, 64)
+	if err != nil {
+		log.Printf("error extracting round: %v", err.Error())
+		h.t.ReplyMessage(evt.Message, "there was an error parsing your request.")
+		h.t.RejectMessage(evt.Message)
+		return
+	}
+
+	key := preptimer.NewKey(round, matches[4])
+	switch matches[1] {
+	case "pause":
+		err = h.t.timers.Pause(key)
+	case "resume":
+		err = h.t.timers.Resume(key)
+	case "cancel":
+		err = h.t.timers.Cancel(key)
+	default:
+		minutes, _ := strconv.Atoi(matches[2])
+		err = h.t.timers.Extend(key, time.Duration(minutes)*time.Minute)
+	}
+
+	if err != nil {
+		h.t.ReplyMessage(evt.Message, "I can't do that: %v.", err.Error())
+		h.t.RejectMessage(evt.Message)
+		return
+	}
+
+	h.t.AcknowledgeMessage(evt.Message)
+}
@@ -2,15 +2,23 @@ package tabulatron
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"regexp"
+	"strings"
+	"sync"
 
 	"github.com/andersfylling/disgord"
+	"github.com/hitecherik/Tabulatron/pkg/tabbycat"
 )
 
+var pulltabbycat *regexp.Regexp = regexp.MustCompile(`^!pulltabbycat(\s+cancel)?$`)
+
 type PullTabbycatHandler struct {
-	t       *Tabulatron
-	tabRole *disgord.Role
+	t      *Tabulatron
+	mutex  sync.Mutex
+	cancel context.CancelFunc
 }
 
 func NewPullTabbycatHandler(t *Tabulatron) *PullTabbycatHandler {
@@ -19,50 +27,53 @@ func NewPullTabbycatHandler(t *Tabulatron) *PullTabbycatHandler {
 	}
 }
 
-func (h *PullTabbycatHandler) CanHandle(_ disgord.Session, evt *disgord.MessageCreate) bool {
-	return evt.Message.Content == "!pulltabbycat"
+func (h *PullTabbycatHandler) CanHandle(evt *disgord.MessageCreate) bool {
+	return pulltabbycat.MatchString(evt.Message.Content)
 }
 
-func (h *PullTabbycatHandler) Handle(s disgord.Session, evt *disgord.MessageCreate) {
-	if err := h.populateRoles(evt); err != nil {
-		log.Printf("error populating roles: %v", err.Error())
+func (h *PullTabbycatHandler) Handle(evt *disgord.MessageCreate) {
+	if !h.t.fromTab(evt.Message) {
 		return
 	}
 
-	if !h.hasTabRole(evt.Message.Member) {
-		h.t.RejectMessage(evt.Message)
-		h.t.ReplyMessage(evt.Message, "you can't ask me to do that.")
+	matches := pulltabbycat.FindStringSubmatch(evt.Message.Content)
+	if matches[1] != "" {
+		h.cancelPull(evt)
 		return
 	}
 
-	teams, err := h.t.tabbycat.GetTeams()
-	if err != nil {
+	ctx, ok := h.startPull()
+	if !ok {
 		h.t.RejectMessage(evt.Message)
-		h.t.ReplyMessage(evt.Message, "there was an error fetching teams")
-		log.Printf("error pulling teams: %v", err.Error())
+		h.t.ReplyMessage(evt.Message, "I'm already pulling from Tabbycat. Send `!pulltabbycat cancel` to stop.")
+		return
 	}
+	defer h.finishPull()
 
-	logMsg, err := h.t.discord.SendMsg(context.Background(), evt.Message.ChannelID, fmt.Sprintf("Fetched %v teams", len(teams)))
-	if err != nil {
-		log.Printf("error sending progress message: %v", err.Error())
+	progress := make([]string, 0)
+	failures := make([]tabbycat.BarcodeFailure, 0)
+
+	teams, err := h.t.tabbycat.GetTeamsContext(ctx)
+	if !collectBarcodeFailures(err, &failures) {
+		h.t.RejectMessage(evt.Message)
+		h.t.ReplyMessage(evt.Message, "there was an error fetching teams: %v.", describeTabbycatError(err))
+		log.Printf("error pulling teams: %v", err.Error())
 		return
 	}
 
-	adjudicators, err := h.t.tabbycat.GetAdjudicators()
-	if err != nil {
+	progress = append(progress, fmt.Sprintf("Fetched %v teams", len(teams)))
+	logMsg := h.showProgress(evt.Message.ChannelID, nil, progress)
+
+	adjudicators, err := h.t.tabbycat.GetAdjudicatorsContext(ctx)
+	if !collectBarcodeFailures(err, &failures) {
 		h.t.RejectMessage(evt.Message)
-		h.t.ReplyMessage(evt.Message, "there was an error fetching adjudicators")
+		h.t.ReplyMessage(evt.Message, "there was an error fetching adjudicators: %v.", describeTabbycatError(err))
 		log.Printf("error pulling adjudicators: %v", err.Error())
 		return
 	}
 
-	logMsg, err = h.t.discord.UpdateMessage(context.Background(), logMsg.ChannelID, logMsg.ID).
-		SetContent(fmt.Sprintf("Fetched %v teams\nFetched %v adjudicators", len(teams), len(adjudicators))).
-		Execute()
-	if err != nil {
-		log.Printf("error updating progress message: %v", err.Error())
-		return
-	}
+	progress = append(progress, fmt.Sprintf("Fetched %v adjudicators", len(adjudicators)))
+	logMsg = h.showProgress(evt.Message.ChannelID, logMsg, progress)
 
 	if err := h.t.database.AddTeams(teams); err != nil {
 		h.t.RejectMessage(evt.Message)
@@ -71,13 +82,8 @@ func (h *PullTabbycatHandler) Handle(s disgord.Session, evt *disgord.MessageCrea
 		return
 	}
 
-	logMsg, err = h.t.discord.UpdateMessage(context.Background(), logMsg.ChannelID, logMsg.ID).
-		SetContent(fmt.Sprintf("Fetched %v teams\nFetched %v adjudicators\nInserted %v teams into database", len(teams), len(adjudicators), len(teams))).
-		Execute()
-	if err != nil {
-		log.Printf("error updating progress message: %v", err.Error())
-		return
-	}
+	progress = append(progress, fmt.Sprintf("Inserted %v teams into database", len(teams)))
+	logMsg = h.showProgress(evt.Message.ChannelID, logMsg, progress)
 
 	if err := h.t.database.AddParticipants(false, adjudicators); err != nil {
 		h.t.RejectMessage(evt.Message)
@@ -86,42 +92,102 @@ func (h *PullTabbycatHandler) Handle(s disgord.Session, evt *disgord.MessageCrea
 		return
 	}
 
-	_, err = h.t.discord.UpdateMessage(context.Background(), logMsg.ChannelID, logMsg.ID).
-		SetContent(fmt.Sprintf("Fetched %v teams\nFetched %v adjudicators\nInserted %v teams into database\nInserted %v adjudicators into database", len(teams), len(adjudicators), len(teams), len(adjudicators))).
-		Execute()
-	if err != nil {
-		log.Printf("error updating progress message: %v", err.Error())
+	progress = append(progress, fmt.Sprintf("Inserted %v adjudicators into database", len(adjudicators)))
+	h.showProgress(evt.Message.ChannelID, logMsg, progress)
+
+	// The list can be long, so it goes in messages of its own rather than
+	// pushing the progress message over Discord's limit.
+	if len(failures) > 0 {
+		h.t.sendList(evt.Message.ChannelID, fmt.Sprintf("Could not fetch barcodes for %v participants", len(failures)), describeBarcodeFailures(failures))
+		h.t.RejectMessage(evt.Message)
 		return
 	}
 
 	h.t.AcknowledgeMessage(evt.Message)
 }
 
-func (h *PullTabbycatHandler) populateRoles(evt *disgord.MessageCreate) error {
-	if h.tabRole != nil {
-		return nil
+// showProgress posts the progress so far, or edits logMsg to show it if it has
+// already been posted. Failing to do so is only logged, since the pull itself
+// can carry on.
+func (h *PullTabbycatHandler) showProgress(channelId disgord.Snowflake, logMsg *disgord.Message, progress []string) *disgord.Message {
+	content := strings.Join(progress, "\n")
+
+	if logMsg == nil {
+		sent, err := h.t.discord.SendMessage(context.Background(), channelId, content)
+		if err != nil {
+			log.Printf("error sending progress message: %v", err.Error())
+		}
+
+		return sent
 	}
 
-	roles, err := h.t.discord.GetGuildRoles(context.Background(), evt.Message.GuildID)
+	edited, err := h.t.discord.EditMessage(context.Background(), logMsg.ChannelID, logMsg.ID, content)
 	if err != nil {
-		return nil
+		log.Printf("error updating progress message: %v", err.Error())
+		return logMsg
 	}
 
-	for _, role := range roles {
-		if role.Name == "Tab/Tech" {
-			h.tabRole = role
-		}
+	return edited
+}
+
+func (h *PullTabbycatHandler) startPull() (context.Context, bool) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if h.cancel != nil {
+		return nil, false
 	}
 
-	return nil
+	ctx, cancel := context.WithTimeout(context.Background(), pullTimeout)
+	h.cancel = cancel
+
+	return ctx, true
 }
 
-func (h *PullTabbycatHandler) hasTabRole(member *disgord.Member) bool {
-	for _, role := range member.Roles {
-		if role == h.tabRole.ID {
-			return true
-		}
+func (h *PullTabbycatHandler) finishPull() {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if h.cancel != nil {
+		h.cancel()
+		h.cancel = nil
+	}
+}
+
+func (h *PullTabbycatHandler) cancelPull(evt *disgord.MessageCreate) {
+	h.mutex.Lock()
+	cancel := h.cancel
+	h.mutex.Unlock()
+
+	if cancel == nil {
+		h.t.RejectMessage(evt.Message)
+		h.t.ReplyMessage(evt.Message, "I'm not pulling from Tabbycat right now.")
+		return
+	}
+
+	cancel()
+	h.t.AcknowledgeMessage(evt.Message)
+}
+
+func collectBarcodeFailures(err error, failures *[]tabbycat.BarcodeFailure) bool {
+	if err == nil {
+		return true
+	}
+
+	var barcodeErr *tabbycat.BarcodeError
+	if !errors.As(err, &barcodeErr) {
+		return false
+	}
+
+	*failures = append(*failures, barcodeErr.Failures...)
+	return true
+}
+
+func describeBarcodeFailures(failures []tabbycat.BarcodeFailure) []string {
+	lines := make([]string, 0, len(failures))
+	for _, failure := range failures {
+		lines = append(lines, fmt.Sprintf("• %v (%v): %v", failure.Participant.Name, failure.Participant.Id, describeTabbycatError(failure.Err)))
 	}
 
-	return false
+	return lines
 }
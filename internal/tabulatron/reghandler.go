@@ -5,8 +5,11 @@ import (
 	"log"
 	"regexp"
 	"strings"
+	"sync"
 
 	"github.com/andersfylling/disgord"
+	"github.com/hitecherik/Tabulatron/internal/layout"
+	"github.com/hitecherik/Tabulatron/internal/templates"
 )
 
 const maxNicknameLength int = 32
@@ -20,28 +23,21 @@ var (
 )
 
 type RegHandler struct {
-	t              *Tabulatron
-	regStarted     bool
-	regChannel     *disgord.Channel
-	regHelpChannel *disgord.Channel
-	speakerRole    *disgord.Role
-	judgeRole      *disgord.Role
-	tabRole        *disgord.Role
+	t       *Tabulatron
+	mutex   sync.Mutex
+	started bool
 }
 
 func NewRegHandler(t *Tabulatron) *RegHandler {
 	return &RegHandler{
-		t:          t,
-		regStarted: false,
+		t: t,
 	}
 }
 
-func (h *RegHandler) CanHandle(_ disgord.Session, evt *disgord.MessageCreate) bool {
+func (h *RegHandler) CanHandle(evt *disgord.MessageCreate) bool {
 	message := sanitiseMessage(evt.Message.Content)
 
-	if err := h.populateChannels(evt); err != nil {
-		log.Printf("error populating channels: %v", err.Error())
-	} else if evt.Message.ChannelID == h.regChannel.ID && numbers.Match(message) {
+	if numbers.Match(message) && h.t.inChannel(evt.Message, layout.RegistrationChannel) {
 		return true
 	}
 
@@ -53,7 +49,7 @@ func (h *RegHandler) CanHandle(_ disgord.Session, evt *disgord.MessageCreate) bo
 	return register.Match(message) || startreg.Match(message) || link.Match(message)
 }
 
-func (h *RegHandler) Handle(s disgord.Session, evt *disgord.MessageCreate) {
+func (h *RegHandler) Handle(evt *disgord.MessageCreate) {
 	messageContent := sanitiseMessage(evt.Message.Content)
 	usernameRegistration := evt.Message.Type == disgord.MessageTypeGuildMemberJoin
 	linkRegistration := link.Match(messageContent)
@@ -68,31 +64,21 @@ func (h *RegHandler) Handle(s disgord.Session, evt *disgord.MessageCreate) {
 	}
 
 	if startreg.Match(messageContent) {
-		if h.regStarted {
+		if h.isStarted() {
 			h.t.ReplyMessage(evt.Message, "I can't do that. Registration has already started.")
 			h.t.RejectMessage(evt.Message)
 			return
 		}
 
-		if err := h.populateRoles(evt); err != nil {
-			log.Printf("error populating roles: %v", err.Error())
-			return
-		}
-
-		if h.hasTabRole(evt.Message.Member) {
+		if h.t.fromTab(evt.Message) {
 			h.t.AcknowledgeMessage(evt.Message)
-			h.regStarted = true
-			return
+			h.setStarted(true)
 		}
 
-		h.t.ReplyMessage(evt.Message, "you can't ask me to do that.")
-		h.t.RejectMessage(evt.Message)
 		return
 	}
 
-	if linkRegistration && !h.hasTabRole(evt.Message.Member) {
-		h.t.ReplyMessage(evt.Message, "you can't ask me to do that.")
-		h.t.RejectMessage(evt.Message)
+	if linkRegistration && !h.t.fromTab(evt.Message) {
 		return
 	}
 
@@ -113,132 +99,114 @@ func (h *RegHandler) Handle(s disgord.Session, evt *disgord.MessageCreate) {
 		code = string(matches[1])
 	}
 
-	if !h.regStarted {
-		h.t.ReplyMessage(evt.Message, "I can't do that. Registration hasn't started yet.")
+	if !h.isStarted() {
+		h.t.ReplyMessage(evt.Message, "%v", h.t.render(evt.Message.Author.ID, "registration.notstarted", h.templateData(evt.Message.GuildID)))
 		h.t.RejectMessage(evt.Message)
 		return
 	}
 
-	if !usernameRegistration && evt.Message.ChannelID != h.regChannel.ID {
-		h.t.ReplyMessage(
-			evt.Message,
-			"you can't do that here. Registration can only happen in the %v channel.",
-			h.regChannel.Mention(),
-		)
+	if !usernameRegistration && !h.t.inChannel(evt.Message, layout.RegistrationChannel) {
+		h.t.ReplyMessage(evt.Message, "%v", h.t.render(evt.Message.Author.ID, "registration.wrongchannel", h.templateData(evt.Message.GuildID)))
 		h.t.RejectMessage(evt.Message)
 		return
 	}
 
-	if len(code) != 6 {
-		h.t.ReplyMessage(
-			evt.Message,
-			"please double-check your registration code – it should be six digits long.",
-		)
-		h.t.RejectMessage(evt.Message)
-		return
-	}
-
-	_, name, speaker, err := h.t.database.ParticipantFromBarcode(code, author.ID.String())
-
-	if err != nil {
-		if code == "123456" {
-			h.t.ReplyMessage(
-				evt.Message,
-				"please replace `123456` in your message with your registration code. If you don't know what this is, ask in %v.",
-				h.regHelpChannel.Mention(),
-			)
-			h.t.RejectMessage(evt.Message)
-			return
-		}
-
-		log.Printf("error registering speaker: %v", err.Error())
-		h.t.ReplyMessage(evt.Message, "there was an error registering you. Please check the code you entered and try again.")
+	reply, ok := h.registerCode(evt.Message.GuildID, author.ID, code)
+	if !ok {
+		h.t.ReplyMessage(evt.Message, "%v", h.t.render(evt.Message.Author.ID, reply, h.templateData(evt.Message.GuildID)))
 		h.t.RejectMessage(evt.Message)
 		return
 	}
 
 	h.t.AcknowledgeMessage(evt.Message)
-
-	role := h.judgeRole
-	if speaker {
-		role = h.speakerRole
+	if reply != "" {
+		h.t.ReplyMessage(evt.Message, "%v", h.t.render(evt.Message.Author.ID, reply, h.templateData(evt.Message.GuildID)))
 	}
 
-	if len(name) > maxNicknameLength {
-		name = name[:maxNicknameLength]
+	go h.t.CreateDMAndSendMessage(author.ID, h.t.render(author.ID, "registration.success", h.templateData(evt.Message.GuildID)))
+}
+
+// handleCommand registers user from the /register command, which works in any
+// channel, and returns the reply.
+func (h *RegHandler) handleCommand(guild disgord.Snowflake, user disgord.Snowflake, code string) string {
+	if !h.isStarted() {
+		return h.t.render(user, "registration.notstarted", h.templateData(guild))
 	}
 
-	err = h.t.discord.
-		UpdateGuildMember(context.Background(), evt.Message.GuildID, author.ID).
-		SetNick(name).
-		SetRoles([]disgord.Snowflake{role.ID}).
-		Execute()
-	if err != nil {
-		log.Printf("error setting nickname: %v", err.Error())
-		h.t.ReplyMessage(
-			evt.Message,
-			"there was an error setting your nickname and/or role! Please ask in %v for help.",
-			h.regHelpChannel.Mention(),
-		)
+	reply, ok := h.registerCode(guild, user, strings.TrimSpace(code))
+	if ok && reply == "" {
+		reply = "registration.success"
 	}
 
-	go h.t.CreateDMAndSendMessage(author.ID, "Congratulations! You have successfully registered.")
+	return h.t.render(user, reply, h.templateData(guild))
 }
 
-func (h *RegHandler) populateChannels(evt *disgord.MessageCreate) error {
-	if h.regChannel != nil && h.regHelpChannel != nil {
-		return nil
+// registerCode links user to the participant with the code and gives them
+// their nickname and role. It returns the template to reply with, which is
+// empty if everything went to plan, and whether they were registered.
+func (h *RegHandler) registerCode(guild disgord.Snowflake, user disgord.Snowflake, code string) (string, bool) {
+	if len(code) != 6 {
+		return "registration.badcode", false
 	}
 
-	channels, err := h.t.discord.GetGuildChannels(context.Background(), evt.Message.GuildID)
-	if err != nil {
-		return err
-	}
+	_, name, speaker, err := h.t.database.ParticipantFromBarcode(code, user.String())
 
-	for _, channel := range channels {
-		if channel.Name == "registration-help" {
-			h.regHelpChannel = channel
-		} else if channel.Name == "registration" {
-			h.regChannel = channel
+	if err != nil {
+		if code == "123456" {
+			return "registration.placeholder", false
 		}
-	}
 
-	return nil
-}
+		log.Printf("error registering speaker: %v", err.Error())
+		return "registration.error", false
+	}
 
-func (h *RegHandler) populateRoles(evt *disgord.MessageCreate) error {
-	if h.speakerRole != nil && h.judgeRole != nil && h.tabRole != nil {
-		return nil
+	roleName := layout.JudgeRole
+	if speaker {
+		roleName = layout.SpeakerRole
 	}
 
-	roles, err := h.t.discord.GetGuildRoles(context.Background(), evt.Message.GuildID)
+	role, err := h.t.role(guild, roleName)
 	if err != nil {
-		return nil
+		log.Printf("error finding %v role: %v", roleName, err.Error())
+		return "registration.nickname", true
 	}
 
-	for _, role := range roles {
-		if role.Name == "Speaker" {
-			h.speakerRole = role
-		} else if role.Name == "Judge" {
-			h.judgeRole = role
-		} else if role.Name == "Tab/Tech" {
-			h.tabRole = role
-		}
+	if len(name) > maxNicknameLength {
+		name = name[:maxNicknameLength]
 	}
 
-	return nil
+	err = h.t.discord.UpdateMember(context.Background(), guild, user, name, []disgord.Snowflake{role.ID})
+	if err != nil {
+		log.Printf("error setting nickname: %v", err.Error())
+		return "registration.nickname", true
+	}
+
+	return "", true
 }
 
-func (h *RegHandler) hasTabRole(member *disgord.Member) bool {
-	for _, role := range member.Roles {
-		if role == h.tabRole.ID {
-			return true
-		}
+func (h *RegHandler) templateData(guild disgord.Snowflake) templates.Registration {
+	return templates.Registration{
+		Channel:     h.t.mention(guild, layout.RegistrationChannel),
+		HelpChannel: h.t.mention(guild, layout.RegistrationHelpChannel),
 	}
-
-	return false
 }
 
 func sanitiseMessage(message string) []byte {
 	return whitespace.ReplaceAll([]byte(strings.ToLower(message)), []byte{})
 }
+
+// isStarted reports whether registration is open. /register reads it from the
+// interactions server as well as the gateway.
+func (h *RegHandler) isStarted() bool {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	return h.started
+}
+
+func (h *RegHandler) setStarted(started bool) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.started = started
+}
@@ -0,0 +1,229 @@
+package tabulatron
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andersfylling/disgord"
+	"github.com/hitecherik/Tabulatron/internal/hermes"
+	"github.com/hitecherik/Tabulatron/internal/roundmessenger"
+	"github.com/hitecherik/Tabulatron/internal/util"
+)
+
+// inlineNames is how many people the progress message lists before leaving
+// the rest to messages of their own, so that it stays under Discord's limit.
+const inlineNames int = 10
+
+var (
+	releasedraw *regexp.Regexp = regexp.MustCompile(`^!releasedraw((?:\s+\d+)+)$`)
+	roundIds    *regexp.Regexp = regexp.MustCompile(`\d+`)
+)
+
+type ReleaseDrawHandler struct {
+	t         *Tabulatron
+	mutex     sync.Mutex
+	releasing bool
+}
+
+func NewReleaseDrawHandler(t *Tabulatron) *ReleaseDrawHandler {
+	return &ReleaseDrawHandler{
+		t: t,
+	}
+}
+
+func (h *ReleaseDrawHandler) CanHandle(evt *disgord.MessageCreate) bool {
+	return releasedraw.MatchString(evt.Message.Content)
+}
+
+func (h *ReleaseDrawHandler) Handle(evt *disgord.MessageCreate) {
+	if !h.t.fromTab(evt.Message) {
+		return
+	}
+
+	matches := releasedraw.FindStringSubmatch(evt.Message.Content)
+	rounds := make([]uint64, 0)
+	for _, match := range roundIds.FindAllString(matches[1], -1) {
+		round, err := strconv.ParseUint(match, 10, 64)
+		if err != nil {
+			log.Printf("error extracting round: %v", err.Error())
+			h.t.ReplyMessage(evt.Message, "there was an error parsing your request.")
+			h.t.RejectMessage(evt.Message)
+			return
+		}
+
+		rounds = append(rounds, round)
+	}
+
+	if h.t.messenger == nil {
+		h.t.ReplyMessage(evt.Message, "I don't have any bots to send messages with.")
+		h.t.RejectMessage(evt.Message)
+		return
+	}
+
+	h.mutex.Lock()
+	if h.releasing {
+		h.mutex.Unlock()
+		h.t.ReplyMessage(evt.Message, "I'm already releasing a draw.")
+		h.t.RejectMessage(evt.Message)
+		return
+	}
+	h.releasing = true
+	h.mutex.Unlock()
+
+	defer func() {
+		h.mutex.Lock()
+		h.releasing = false
+		h.mutex.Unlock()
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), pullTimeout)
+	defer cancel()
+
+	var logMsg *disgord.Message
+	report := func(progress roundmessenger.Progress) {
+		var updated *disgord.Message
+		var err error
+		content := h.describeProgress(progress)
+
+		if logMsg == nil {
+			updated, err = h.t.discord.SendMessage(context.Background(), evt.Message.ChannelID, content)
+		} else {
+			updated, err = h.t.discord.EditMessage(context.Background(), logMsg.ChannelID, logMsg.ID, content)
+		}
+
+		if err != nil {
+			log.Printf("error updating progress message: %v", err.Error())
+			return
+		}
+
+		logMsg = updated
+	}
+
+	messenger := roundmessenger.New(h.t.tabbycat, h.t.database, h.t.messenger, h.t.categories)
+	messenger.SetTemplates(h.t.templates)
+	progress, err := messenger.Release(ctx, rounds, report)
+	h.listInFull(evt.Message.ChannelID, progress)
+
+	if err != nil {
+		log.Printf("error releasing draw: %v", err.Error())
+		h.t.ReplyMessage(
+			evt.Message,
+			"there was an error releasing the draw after %v of %v rooms: %v.",
+			progress.Rooms,
+			progress.TotalRooms,
+			describeTabbycatError(err),
+		)
+		h.t.RejectMessage(evt.Message)
+		return
+	}
+
+	h.t.AcknowledgeMessage(evt.Message)
+}
+
+func (h *ReleaseDrawHandler) describeProgress(progress roundmessenger.Progress) string {
+	lines := []string{
+		fmt.Sprintf("Fetched %v pairings", progress.TotalRooms),
+		fmt.Sprintf("Queued %v messages for %v/%v rooms", progress.Messages, progress.Rooms, progress.TotalRooms),
+	}
+
+	if progress.Delivered > 0 || progress.Skipped > 0 || len(progress.Failed) > 0 {
+		lines = append(lines, fmt.Sprintf("Delivered %v messages", progress.Delivered))
+	}
+
+	if progress.Skipped > 0 {
+		lines = append(lines, fmt.Sprintf("Skipped %v messages which had already been sent", progress.Skipped))
+	}
+
+	if unregistered := h.unregistered(progress); len(unregistered) > 0 {
+		shown, more := capNames(unregistered)
+		line := fmt.Sprintf("Adjudicators not on Discord: %v", strings.Join(shown, ", "))
+		if more > 0 {
+			line += fmt.Sprintf(" and %v more, listed below once the release is done", more)
+		}
+
+		lines = append(lines, line)
+	}
+
+	if len(progress.Failed) > 0 {
+		lines = append(lines, fmt.Sprintf("Could not message %v participants, who need to be contacted manually:", len(progress.Failed)))
+
+		shown, more := capNames(hermes.DescribeFailures(h.t.database, progress.Failed))
+		lines = append(lines, shown...)
+		if more > 0 {
+			lines = append(lines, fmt.Sprintf("…and %v more, listed below once the release is done", more))
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// listInFull sends the lists that were too long for the progress message in
+// messages of their own.
+func (h *ReleaseDrawHandler) listInFull(channelId disgord.Snowflake, progress roundmessenger.Progress) {
+	pages := make([]string, 0)
+
+	if unregistered := h.unregistered(progress); len(unregistered) > inlineNames {
+		pages = append(pages, util.PaginateLines(fmt.Sprintf("All %v adjudicators not on Discord", len(unregistered)), bullet(unregistered), util.MessageLimit)...)
+	}
+
+	if len(progress.Failed) > inlineNames {
+		pages = append(pages, util.PaginateLines(fmt.Sprintf("All %v participants who need to be contacted manually", len(progress.Failed)), hermes.DescribeFailures(h.t.database, progress.Failed), util.MessageLimit)...)
+	}
+
+	for _, page := range pages {
+		if _, err := h.t.discord.SendMessage(context.Background(), channelId, page); err != nil {
+			log.Printf("error sending message: %v", err.Error())
+		}
+	}
+}
+
+// unregistered names each adjudicator who isn't on Discord once.
+func (h *ReleaseDrawHandler) unregistered(progress roundmessenger.Progress) []string {
+	if len(progress.Unregistered) == 0 {
+		return nil
+	}
+
+	names, err := h.t.database.ParticipantNames(progress.Unregistered)
+	if err != nil {
+		log.Printf("error fetching names: %v", err.Error())
+	}
+
+	seen := make(map[string]bool)
+	unregistered := make([]string, 0, len(progress.Unregistered))
+	for _, id := range progress.Unregistered {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		name, ok := names[id]
+		if !ok {
+			name = fmt.Sprintf("adjudicator %v", id)
+		}
+		unregistered = append(unregistered, name)
+	}
+
+	return unregistered
+}
+
+func capNames(names []string) ([]string, int) {
+	if len(names) <= inlineNames {
+		return names, 0
+	}
+
+	return names[:inlineNames], len(names) - inlineNames
+}
+
+func bullet(names []string) []string {
+	lines := make([]string, 0, len(names))
+	for _, name := range names {
+		lines = append(lines, fmt.Sprintf("• %v", name))
+	}
+
+	return lines
+}
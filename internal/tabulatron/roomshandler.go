@@ -0,0 +1,104 @@
+package tabulatron
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+
+	"github.com/andersfylling/disgord"
+	"github.com/hitecherik/Tabulatron/internal/discordrooms"
+	"github.com/hitecherik/Tabulatron/internal/util"
+)
+
+var roomaccess *regexp.Regexp = regexp.MustCompile(`^!(open|close)rooms((?:\s+\d+)+)$`)
+
+type RoomsHandler struct {
+	t *Tabulatron
+}
+
+func NewRoomsHandler(t *Tabulatron) *RoomsHandler {
+	return &RoomsHandler{
+		t: t,
+	}
+}
+
+func (h *RoomsHandler) CanHandle(evt *disgord.MessageCreate) bool {
+	return roomaccess.MatchString(evt.Message.Content)
+}
+
+func (h *RoomsHandler) Handle(evt *disgord.MessageCreate) {
+	if !h.t.fromTab(evt.Message) {
+		return
+	}
+
+	matches := roomaccess.FindStringSubmatch(evt.Message.Content)
+	opening := matches[1] == "open"
+
+	ctx, cancel := context.WithTimeout(context.Background(), pullTimeout)
+	defer cancel()
+
+	allocator := discordrooms.New(h.t.discord, evt.Message.GuildID, h.t.tabbycat, h.t.database)
+	opened := discordrooms.Report{}
+	missing := make([]string, 0)
+	for _, match := range roundIds.FindAllString(matches[2], -1) {
+		round, err := strconv.ParseUint(match, 10, 64)
+		if err != nil {
+			log.Printf("error extracting round: %v", err.Error())
+			h.t.ReplyMessage(evt.Message, "there was an error parsing your request.")
+			h.t.RejectMessage(evt.Message)
+			return
+		}
+
+		if !opening {
+			revoked, err := allocator.Close(ctx, round)
+			if err != nil {
+				log.Printf("error closing rooms: %v", err.Error())
+				h.t.ReplyMessage(evt.Message, "there was an error closing the rooms after removing %v permissions: %v.", revoked, describeRoomsError(err))
+				h.t.RejectMessage(evt.Message)
+				return
+			}
+
+			continue
+		}
+
+		report, err := allocator.Open(ctx, round)
+		if err != nil {
+			log.Printf("error opening rooms: %v", err.Error())
+			h.t.ReplyMessage(evt.Message, "there was an error opening the rooms after %v rooms: %v.", report.Rooms, describeRoomsError(err))
+			h.t.RejectMessage(evt.Message)
+			return
+		}
+
+		opened.Rooms += report.Rooms
+		opened.Participants += report.Participants
+		for _, venue := range report.Missing {
+			missing = append(missing, fmt.Sprintf("• %v (round %v)", venue, round))
+		}
+	}
+
+	if len(missing) > 0 {
+		// leave room for the mention ReplyMessage puts in front of each page
+		header := fmt.Sprintf("I let %v participants into %v rooms, but couldn't find channels for these %v venues", opened.Participants, opened.Rooms, len(missing))
+		for _, page := range util.PaginateLines(header, missing, util.MessageLimit-len(evt.Message.Author.Mention())-2) {
+			h.t.ReplyMessage(evt.Message, "%v", page)
+		}
+		h.t.RejectMessage(evt.Message)
+		return
+	}
+
+	h.t.AcknowledgeMessage(evt.Message)
+}
+
+// describeRoomsError describes why opening or closing rooms failed, which is
+// as likely to be Discord refusing to change a channel as Tabbycat.
+func describeRoomsError(err error) string {
+	var rest *disgord.ErrRest
+	if errors.As(err, &rest) {
+		return describeDiscordError(rest)
+	}
+
+	return describeTabbycatError(err)
+}
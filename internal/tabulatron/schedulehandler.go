@@ -0,0 +1,328 @@
+package tabulatron
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/andersfylling/disgord"
+	"github.com/hitecherik/Tabulatron/internal/db"
+	"github.com/hitecherik/Tabulatron/internal/layout"
+	"github.com/hitecherik/Tabulatron/internal/preptimer"
+	"github.com/hitecherik/Tabulatron/internal/util"
+	"github.com/hitecherik/Tabulatron/pkg/tabbycat"
+)
+
+const (
+	defaultInfoSlideLead time.Duration = time.Minute * 5
+	missedReleaseTimeout time.Duration = time.Minute * 2
+)
+
+var (
+	schedulemotion   *regexp.Regexp = regexp.MustCompile(`^!schedulemotion\s+(\d+)\s+(\d{1,2}):(\d{2})(?:\s+(\S+))?$`)
+	unschedulemotion *regexp.Regexp = regexp.MustCompile(`^!schedulemotion\s+cancel\s+(\d+)(?:\s+(\S+))?$`)
+)
+
+type ScheduleHandler struct {
+	t             *Tabulatron
+	infoSlideLead time.Duration
+	location      *time.Location
+	mutex         sync.Mutex
+	schedules     map[preptimer.Key]*scheduledRun
+}
+
+// scheduledRun is compared by pointer, so that a run only forgets its round
+// if it hasn't been rescheduled in the meantime. Tracks are scheduled
+// separately, so runs are keyed the same way as prep timers.
+type scheduledRun struct {
+	cancel context.CancelFunc
+}
+
+func NewScheduleHandler(t *Tabulatron) *ScheduleHandler {
+	return &ScheduleHandler{
+		t:             t,
+		infoSlideLead: defaultInfoSlideLead,
+		location:      time.Local,
+		schedules:     make(map[preptimer.Key]*scheduledRun),
+	}
+}
+
+func (h *ScheduleHandler) CanHandle(evt *disgord.MessageCreate) bool {
+	return schedulemotion.MatchString(evt.Message.Content) || unschedulemotion.MatchString(evt.Message.Content)
+}
+
+func (h *ScheduleHandler) Handle(evt *disgord.MessageCreate) {
+	if !h.t.fromTab(evt.Message) {
+		return
+	}
+
+	if matches := unschedulemotion.FindStringSubmatch(evt.Message.Content); matches != nil {
+		round, _ := strconv.ParseUint(matches[1], 10, 64)
+		key := preptimer.NewKey(round, matches[2])
+
+		if err := h.t.database.UnscheduleMotion(key.Round, key.Track); err != nil {
+			log.Printf("error unscheduling motion: %v", err.Error())
+			h.t.ReplyMessage(evt.Message, "there was an error cancelling that schedule.")
+			h.t.RejectMessage(evt.Message)
+			return
+		}
+
+		if !h.stop(key) {
+			h.t.ReplyMessage(evt.Message, "there is no motion scheduled for that round.")
+			h.t.RejectMessage(evt.Message)
+			return
+		}
+
+		h.t.AcknowledgeMessage(evt.Message)
+		return
+	}
+
+	drawChannel, err := h.t.channel(evt.Message.GuildID, layout.DrawChannel)
+	if err != nil {
+		log.Printf("error finding draw channel: %v", err.Error())
+		h.t.ReplyMessage(evt.Message, "I can't do that: %v.", err.Error())
+		h.t.RejectMessage(evt.Message)
+		return
+	}
+
+	// Failed releases are reported here, since nobody is waiting on a reply.
+	tabChannel, err := h.t.channel(evt.Message.GuildID, layout.TabChannel)
+	if err != nil {
+		log.Printf("error finding tab channel: %v", err.Error())
+		h.t.ReplyMessage(evt.Message, "I can't do that: %v.", err.Error())
+		h.t.RejectMessage(evt.Message)
+		return
+	}
+
+	matches := schedulemotion.FindStringSubmatch(evt.Message.Content)
+	id, _ := strconv.ParseUint(matches[1], 10, 64)
+	hour, _ := strconv.Atoi(matches[2])
+	minute, _ := strconv.Atoi(matches[3])
+	key := preptimer.NewKey(id, matches[4])
+
+	if hour > 23 || minute > 59 {
+		h.t.ReplyMessage(evt.Message, "%v:%v isn't a valid time.", matches[2], matches[3])
+		h.t.RejectMessage(evt.Message)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+	defer cancel()
+
+	round, err := h.t.tabbycat.GetRoundContext(ctx, id)
+	if err != nil {
+		log.Printf("error fetching round: %v", err.Error())
+
+		if errors.Is(err, tabbycat.ErrNotFound) {
+			h.t.ReplyMessage(evt.Message, "I couldn't find any information about that round.")
+		} else {
+			h.t.ReplyMessage(evt.Message, "there was an error fetching that round: %v.", describeTabbycatError(err))
+		}
+
+		h.t.RejectMessage(evt.Message)
+		return
+	}
+
+	roundName := round.Name
+	if key.Track != "" {
+		trackName, _, err := h.t.tracks.Lookup(key.Track)
+		if err != nil {
+			h.t.ReplyMessage(evt.Message, "%v.", err.Error())
+			h.t.RejectMessage(evt.Message)
+			return
+		}
+
+		roundName = fmt.Sprintf("%v (%v)", roundName, trackName)
+	}
+
+	now := time.Now().In(h.location)
+	release := time.Date(now.Year(), now.Month(), now.Day(), hour, minute, 0, 0, now.Location())
+	if !release.After(now) {
+		release = release.AddDate(0, 0, 1)
+	}
+
+	schedule := db.ScheduledMotion{
+		Round:        key.Round,
+		Track:        key.Track,
+		ChannelId:    drawChannel.ID.String(),
+		TabChannelId: tabChannel.ID.String(),
+		InfoSlide:    release.Add(-h.infoSlideLead),
+		Motion:       release,
+	}
+
+	if err := h.t.database.ScheduleMotion(schedule); errors.Is(err, db.ErrMotionReleased) {
+		h.t.ReplyMessage(evt.Message, "the motion for %v has already been released.", roundName)
+		h.t.RejectMessage(evt.Message)
+		return
+	} else if err != nil {
+		log.Printf("error scheduling motion: %v", err.Error())
+		h.t.ReplyMessage(evt.Message, "there was an error scheduling that motion.")
+		h.t.RejectMessage(evt.Message)
+		return
+	}
+
+	h.start(schedule)
+
+	h.t.ReplyMessage(
+		evt.Message,
+		"I'll release the info slide for %v at %v (%v) and the motion at %v (%v).",
+		roundName,
+		schedule.InfoSlide.Format("15:04 MST"),
+		util.DiscordTimestamp(schedule.InfoSlide),
+		schedule.Motion.Format("15:04 MST"),
+		util.DiscordTimestamp(schedule.Motion),
+	)
+	h.t.AcknowledgeMessage(evt.Message)
+}
+
+func (h *ScheduleHandler) restore() error {
+	schedules, err := h.t.database.PendingMotions()
+	if err != nil {
+		return err
+	}
+
+	for _, schedule := range schedules {
+		h.start(schedule)
+	}
+
+	return nil
+}
+
+func (h *ScheduleHandler) start(schedule db.ScheduledMotion) {
+	ctx, cancel := context.WithCancel(context.Background())
+	run := &scheduledRun{cancel}
+	key := preptimer.NewKey(schedule.Round, schedule.Track)
+
+	h.mutex.Lock()
+	if previous, ok := h.schedules[key]; ok {
+		previous.cancel()
+	}
+	h.schedules[key] = run
+	h.mutex.Unlock()
+
+	go h.run(ctx, run, schedule)
+}
+
+func (h *ScheduleHandler) stop(key preptimer.Key) bool {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	run, ok := h.schedules[key]
+	if ok {
+		run.cancel()
+		delete(h.schedules, key)
+	}
+
+	return ok
+}
+
+func (h *ScheduleHandler) run(ctx context.Context, run *scheduledRun, schedule db.ScheduledMotion) {
+	channel, err := util.StringToSnowflake(schedule.ChannelId)
+	if err != nil {
+		log.Printf("error converting to snowflake: %v", err.Error())
+		return
+	}
+
+	if !schedule.InfoSlideReleased {
+		if !waitUntil(ctx, schedule.InfoSlide) {
+			return
+		}
+
+		if h.claim(schedule, h.t.database.ClaimInfoSlide, "info slide", schedule.InfoSlide, schedule.Motion) {
+			if err := h.release(channel, schedule, true); err != nil {
+				log.Printf("error releasing info slide for %v: %v", describeSchedule(schedule), err.Error())
+				h.report(schedule, "I couldn't release the info slide for %v: %v. Post it with `!infoslide %v`.", describeSchedule(schedule), err.Error(), scheduleArguments(schedule))
+			}
+		}
+	}
+
+	if !waitUntil(ctx, schedule.Motion) {
+		return
+	}
+
+	if h.claim(schedule, h.t.database.ClaimMotion, "motion", schedule.Motion, schedule.Motion.Add(missedReleaseTimeout)) {
+		if err := h.release(channel, schedule, false); err != nil {
+			log.Printf("error releasing motion for %v: %v", describeSchedule(schedule), err.Error())
+			h.report(schedule, "I couldn't release the motion for %v: %v. Release it with `!motion %v`.", describeSchedule(schedule), err.Error(), scheduleArguments(schedule))
+		}
+	}
+
+	key := preptimer.NewKey(schedule.Round, schedule.Track)
+	h.mutex.Lock()
+	if h.schedules[key] == run {
+		delete(h.schedules, key)
+	}
+	h.mutex.Unlock()
+}
+
+// claim marks a stage as released, but skips it if it was missed by so much
+// (e.g. because the bot was offline) that announcing it now would be wrong.
+func (h *ScheduleHandler) claim(schedule db.ScheduledMotion, claim func(uint64, string) (bool, error), stage string, at time.Time, deadline time.Time) bool {
+	claimed, err := claim(schedule.Round, schedule.Track)
+	if err != nil {
+		log.Printf("error claiming scheduled release: %v", err.Error())
+		h.report(schedule, "I couldn't release the %v for %v: %v.", stage, describeSchedule(schedule), err.Error())
+		return false
+	}
+
+	if claimed && time.Now().After(deadline) {
+		scheduled := at.In(h.location).Format("15:04 MST")
+		log.Printf("skipping release for %v scheduled at %v", describeSchedule(schedule), scheduled)
+		h.report(schedule, "I missed the %v for %v, which was due at %v, so I didn't release it.", stage, describeSchedule(schedule), scheduled)
+		return false
+	}
+
+	return claimed
+}
+
+// report tells the tab team about a scheduled release that went wrong.
+func (h *ScheduleHandler) report(schedule db.ScheduledMotion, format string, a ...interface{}) {
+	channel, err := util.StringToSnowflake(schedule.TabChannelId)
+	if err != nil {
+		log.Printf("error converting to snowflake: %v", err.Error())
+		return
+	}
+
+	if _, err := h.t.discord.SendMessage(context.Background(), channel, fmt.Sprintf(format, a...)); err != nil {
+		log.Printf("error reporting scheduled release: %v", err.Error())
+	}
+}
+
+func (h *ScheduleHandler) release(channel disgord.Snowflake, schedule db.ScheduledMotion, infoSlide bool) error {
+	ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+	defer cancel()
+
+	return h.t.announceMotion(ctx, channel, schedule.Round, schedule.Track, infoSlide)
+}
+
+func describeSchedule(schedule db.ScheduledMotion) string {
+	if schedule.Track == "" {
+		return fmt.Sprintf("round %v", schedule.Round)
+	}
+
+	return fmt.Sprintf("round %v (%v)", schedule.Round, schedule.Track)
+}
+
+// scheduleArguments are what to follow !infoslide or !motion with to release a
+// schedule by hand.
+func scheduleArguments(schedule db.ScheduledMotion) string {
+	return strings.TrimSpace(fmt.Sprintf("%v %v", schedule.Round, schedule.Track))
+}
+
+func waitUntil(ctx context.Context, at time.Time) bool {
+	timer := time.NewTimer(time.Until(at))
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
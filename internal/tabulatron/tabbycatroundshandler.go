@@ -11,8 +11,7 @@ import (
 )
 
 type TabbycatRoundsHandler struct {
-	t       *Tabulatron
-	tabRole *disgord.Role
+	t *Tabulatron
 }
 
 func NewTabbycatRoundsHandler(t *Tabulatron) *TabbycatRoundsHandler {
@@ -21,26 +20,22 @@ func NewTabbycatRoundsHandler(t *Tabulatron) *TabbycatRoundsHandler {
 	}
 }
 
-func (h *TabbycatRoundsHandler) CanHandle(_ disgord.Session, evt *disgord.MessageCreate) bool {
+func (h *TabbycatRoundsHandler) CanHandle(evt *disgord.MessageCreate) bool {
 	return evt.Message.Content == "!tabbycatrounds"
 }
 
-func (h *TabbycatRoundsHandler) Handle(s disgord.Session, evt *disgord.MessageCreate) {
-	if err := h.populateRoles(evt); err != nil {
-		log.Printf("error populating roles: %v", err.Error())
+func (h *TabbycatRoundsHandler) Handle(evt *disgord.MessageCreate) {
+	if !h.t.fromTab(evt.Message) {
 		return
 	}
 
-	if !h.hasTabRole(evt.Message.Member) {
-		h.t.RejectMessage(evt.Message)
-		h.t.ReplyMessage(evt.Message, "you can't ask me to do that.")
-		return
-	}
+	ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+	defer cancel()
 
-	rounds, err := h.t.tabbycat.GetRounds()
+	rounds, err := h.t.tabbycat.GetRoundsContext(ctx)
 	if err != nil {
 		h.t.RejectMessage(evt.Message)
-		h.t.ReplyMessage(evt.Message, "there was an error fetching rounds for this tournament.")
+		h.t.ReplyMessage(evt.Message, "there was an error fetching rounds for this tournament: %v.", describeTabbycatError(err))
 		log.Printf("error fetching rounds: %v", err.Error())
 		return
 	}
@@ -55,37 +50,8 @@ func (h *TabbycatRoundsHandler) Handle(s disgord.Session, evt *disgord.MessageCr
 
 	table.Render()
 
-	_, err = h.t.discord.SendMsg(context.Background(), evt.Message.ChannelID, fmt.Sprintf("The rounds for this tournament:\n```%v```", writer.String()))
+	_, err = h.t.discord.SendMessage(context.Background(), evt.Message.ChannelID, fmt.Sprintf("The rounds for this tournament:\n```%v```", writer.String()))
 	if err != nil {
 		log.Printf("error sending rounds: %v", err.Error())
 	}
 }
-
-func (h *TabbycatRoundsHandler) populateRoles(evt *disgord.MessageCreate) error {
-	if h.tabRole != nil {
-		return nil
-	}
-
-	roles, err := h.t.discord.GetGuildRoles(context.Background(), evt.Message.GuildID)
-	if err != nil {
-		return nil
-	}
-
-	for _, role := range roles {
-		if role.Name == "Tab/Tech" {
-			h.tabRole = role
-		}
-	}
-
-	return nil
-}
-
-func (h *TabbycatRoundsHandler) hasTabRole(member *disgord.Member) bool {
-	for _, role := range member.Roles {
-		if role == h.tabRole.ID {
-			return true
-		}
-	}
-
-	return false
-}
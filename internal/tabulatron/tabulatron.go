@@ -2,39 +2,192 @@ package tabulatron
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"net"
+	"net/http"
+	"time"
 
 	"github.com/andersfylling/disgord"
+	"github.com/hitecherik/Tabulatron/internal/chat"
 	"github.com/hitecherik/Tabulatron/internal/db"
+	"github.com/hitecherik/Tabulatron/internal/hermes"
+	"github.com/hitecherik/Tabulatron/internal/interactions"
+	"github.com/hitecherik/Tabulatron/internal/layout"
+	"github.com/hitecherik/Tabulatron/internal/locale"
+	"github.com/hitecherik/Tabulatron/internal/mailer"
+	"github.com/hitecherik/Tabulatron/internal/multiroom"
+	"github.com/hitecherik/Tabulatron/internal/preptimer"
 	"github.com/hitecherik/Tabulatron/internal/pundit"
+	"github.com/hitecherik/Tabulatron/internal/scheduler"
+	"github.com/hitecherik/Tabulatron/internal/templates"
+	"github.com/hitecherik/Tabulatron/internal/tracks"
+	"github.com/hitecherik/Tabulatron/internal/util"
 	"github.com/hitecherik/Tabulatron/pkg/tabbycat"
 )
 
+const (
+	commandTimeout        time.Duration = time.Minute
+	pullTimeout           time.Duration = time.Minute * 10
+	defaultBallotInterval time.Duration = time.Minute * 5
+	defaultPrepTime       time.Duration = time.Minute * 15
+)
+
+var commands []interactions.Command = []interactions.Command{
+	{
+		Name:        "register",
+		Description: "Register for the tournament",
+		Options: []interactions.Option{
+			{Type: interactions.OptionString, Name: "code", Description: "Your six-digit registration code", Required: true},
+		},
+	},
+	{Name: "checkin", Description: "Check in for the next round"},
+	{Name: "checkout", Description: "Check out as an adjudicator"},
+}
+
 type MessageHandler interface {
-	CanHandle(disgord.Session, *disgord.MessageCreate) bool
-	Handle(disgord.Session, *disgord.MessageCreate)
+	CanHandle(*disgord.MessageCreate) bool
+	Handle(*disgord.MessageCreate)
 }
 
 type Tabulatron struct {
-	discord  *disgord.Client
-	database *db.Database
-	tabbycat *tabbycat.Tabbycat
-	handlers []MessageHandler
-	pundit   *pundit.Pundit
+	discord        chat.Platform
+	database       *db.Database
+	tabbycat       *tabbycat.Tabbycat
+	handlers       []MessageHandler
+	pundit         *pundit.Pundit
+	messenger      *hermes.Hermes
+	bots           *scheduler.Scheduler
+	ballotInterval time.Duration
+	timers         *preptimer.Registry
+	scheduler      *ScheduleHandler
+	categories     multiroom.Categories
+	tracks         tracks.Tracks
+	templates      *templates.Templates
+	catalogue      *locale.Catalogue
+	mailer         *mailer.Mailer
+	layout         *layout.Layout
+	resolved       *guildLayout
+	voice          *voiceStates
+	interactions   *interactions.Client
+	registration   *RegHandler
+	checkins       *CheckinHandler
 }
 
-func New(discord *disgord.Client, database *db.Database, tabbycat *tabbycat.Tabbycat, p *pundit.Pundit) *Tabulatron {
-	t := &Tabulatron{discord, database, tabbycat, []MessageHandler{}, p}
-	t.handlers = append(t.handlers, NewRegHandler(t), NewCheckinHandler(t), NewClearHandler(t), NewMotionHandler(t), NewTabbycatRoundsHandler(t), NewPullTabbycatHandler(t))
+func New(discord chat.Platform, database *db.Database, tabbycat *tabbycat.Tabbycat, p *pundit.Pundit, messenger *hermes.Hermes, bots *scheduler.Scheduler) *Tabulatron {
+	timers := preptimer.New(discord, database, defaultPrepTime)
+	t := &Tabulatron{
+		discord:        discord,
+		database:       database,
+		tabbycat:       tabbycat,
+		pundit:         p,
+		messenger:      messenger,
+		bots:           bots,
+		ballotInterval: defaultBallotInterval,
+		timers:         timers,
+		templates:      &templates.Templates{},
+		catalogue:      &locale.Catalogue{},
+		layout:         &layout.Layout{},
+		resolved:       newGuildLayout(),
+		voice:          newVoiceStates(),
+	}
+	t.scheduler = NewScheduleHandler(t)
+	t.registration = NewRegHandler(t)
+	t.checkins = NewCheckinHandler(t)
+	t.handlers = append(t.handlers, t.registration, t.checkins, NewClearHandler(t), NewMotionHandler(t), NewPrepHandler(t), NewDrawHandler(t), NewReleaseDrawHandler(t), t.scheduler, NewTabbycatRoundsHandler(t), NewPullTabbycatHandler(t), NewBallotHandler(t), NewBotStatsHandler(t), NewLanguageHandler(t), NewAnnounceHandler(t), NewRoomsHandler(t), NewMoveHandler(t))
 
 	return t
 }
 
-func (t *Tabulatron) HandleMessage(s disgord.Session, evt *disgord.MessageCreate) {
+func (t *Tabulatron) SetBallotInterval(interval time.Duration) {
+	t.ballotInterval = interval
+}
+
+func (t *Tabulatron) SetPrepTime(length time.Duration) {
+	t.timers.SetLength(length)
+}
+
+func (t *Tabulatron) SetInfoSlideLead(lead time.Duration) {
+	t.scheduler.infoSlideLead = lead
+}
+
+func (t *Tabulatron) SetCategories(categories multiroom.Categories) {
+	t.categories = categories
+}
+
+func (t *Tabulatron) SetTracks(tracks tracks.Tracks) {
+	t.tracks = tracks
+}
+
+func (t *Tabulatron) SetTemplates(templates *templates.Templates) {
+	t.templates = templates
+	t.timers.SetTemplates(templates)
+}
+
+func (t *Tabulatron) SetCatalogue(catalogue *locale.Catalogue) {
+	t.catalogue = catalogue
+}
+
+func (t *Tabulatron) SetMailer(mailer *mailer.Mailer) {
+	t.mailer = mailer
+}
+
+func (t *Tabulatron) SetLayout(layout *layout.Layout) {
+	t.layout = layout
+	t.resolved = newGuildLayout()
+}
+
+func (t *Tabulatron) SetInteractions(client *interactions.Client) {
+	t.interactions = client
+}
+
+// RegisterCommands adds /register, /checkin and /checkout to the guild. The
+// text commands keep working alongside them.
+func (t *Tabulatron) RegisterCommands(guild disgord.Snowflake) error {
+	if t.interactions == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+	defer cancel()
+
+	return t.interactions.RegisterCommands(ctx, guild, commands)
+}
+
+// CheckLayout reports the roles and channels from the layout that the guild
+// is missing.
+func (t *Tabulatron) CheckLayout(guild disgord.Snowflake) ([]string, error) {
+	roles, err := t.discord.GetGuildRoles(context.Background(), guild)
+	if err != nil {
+		return nil, err
+	}
+
+	channels, err := t.discord.GetGuildChannels(context.Background(), guild)
+	if err != nil {
+		return nil, err
+	}
+
+	return t.layout.Check(roles, channels), nil
+}
+
+func (t *Tabulatron) SetLocation(location *time.Location) {
+	t.timers.SetLocation(location)
+	t.scheduler.location = location
+}
+
+func (t *Tabulatron) RestoreTimers() error {
+	if err := t.timers.Restore(); err != nil {
+		return err
+	}
+
+	return t.scheduler.restore()
+}
+
+func (t *Tabulatron) HandleMessage(evt *disgord.MessageCreate) {
 	for _, handler := range t.handlers {
-		if handler.CanHandle(s, evt) {
-			handler.Handle(s, evt)
+		if handler.CanHandle(evt) {
+			handler.Handle(evt)
 			return
 		}
 	}
@@ -42,15 +195,62 @@ func (t *Tabulatron) HandleMessage(s disgord.Session, evt *disgord.MessageCreate
 	log.Printf("could not find handler for message '%v' from '%v'", evt.Message.Content, evt.Message.Author.Username)
 }
 
-func (t *Tabulatron) HandleDeparture(s disgord.Session, evt *disgord.GuildMemberRemove) {
+func (t *Tabulatron) HandleDeparture(evt *disgord.GuildMemberRemove) {
 	if err := t.database.ClearParticipantFromDiscord(fmt.Sprint(evt.User.ID)); err != nil {
 		log.Printf("could not clear user %v (snowflake %v): %v", evt.User.Username, evt.User.ID, err.Error())
 	}
 }
 
+func (t *Tabulatron) HandleGuild(evt *disgord.GuildCreate) {
+	for _, state := range evt.Guild.VoiceStates {
+		if state.GuildID.IsZero() {
+			state.GuildID = evt.Guild.ID
+		}
+
+		t.voice.update(state)
+	}
+}
+
+func (t *Tabulatron) HandleVoiceState(evt *disgord.VoiceStateUpdate) {
+	t.voice.update(evt.VoiceState)
+}
+
+// HandleInteraction answers a slash command or button press with the reply
+// only its sender can see.
+func (t *Tabulatron) HandleInteraction(interaction *interactions.Interaction) string {
+	if interaction.Member == nil || interaction.Member.User == nil {
+		return "I can only do that in the tournament's server."
+	}
+
+	member := interaction.Member
+	guild := interaction.GuildID
+
+	switch {
+	case interaction.Type == interactions.TypeApplicationCommand && interaction.Data.Name == "register":
+		return t.registration.handleCommand(guild, member.User.ID, interaction.Data.Option("code"))
+	case interaction.Type == interactions.TypeApplicationCommand && interaction.Data.Name == "checkin",
+		interaction.Type == interactions.TypeMessageComponent && interaction.Data.CustomId == checkinButton:
+		return t.checkins.handleCommand(guild, member, false)
+	case interaction.Type == interactions.TypeApplicationCommand && interaction.Data.Name == "checkout":
+		return t.checkins.handleCommand(guild, member, true)
+	}
+
+	log.Printf("could not find handler for interaction '%v%v' from '%v'", interaction.Data.Name, interaction.Data.CustomId, member.User.Username)
+	return t.translate(member.User.ID, "I don't know how to do that.")
+}
+
+// ReplyMessage translates reply, and any strings it's formatted with, into the
+// author's chosen language.
 func (t *Tabulatron) ReplyMessage(message *disgord.Message, reply string, a ...interface{}) *disgord.Message {
-	fullReply := fmt.Sprintf(reply, a...)
-	m, err := message.Reply(context.Background(), t.discord, fmt.Sprintf("%v, %v", message.Author.Mention(), fullReply))
+	language := t.language(message.Author.ID)
+	for i, arg := range a {
+		if text, ok := arg.(string); ok {
+			a[i] = t.catalogue.Translate(language, text)
+		}
+	}
+
+	fullReply := fmt.Sprintf(t.catalogue.Translate(language, reply), a...)
+	m, err := t.discord.SendMessage(context.Background(), message.ChannelID, fmt.Sprintf("%v, %v", message.Author.Mention(), fullReply))
 
 	if err != nil {
 		log.Printf("Error sending message '%v': %v", reply, err.Error())
@@ -67,6 +267,17 @@ func (t *Tabulatron) RejectMessage(message *disgord.Message) {
 	t.reactMessage(message, "❌")
 }
 
+// sendList posts a header and a line for each item, split across as many
+// messages as Discord's limit needs. The lines aren't translated, since they're
+// usually names.
+func (t *Tabulatron) sendList(channelId disgord.Snowflake, header string, lines []string) {
+	for _, page := range util.PaginateLines(header, lines, util.MessageLimit) {
+		if _, err := t.discord.SendMessage(context.Background(), channelId, page); err != nil {
+			log.Printf("error sending message: %v", err.Error())
+		}
+	}
+}
+
 func (t *Tabulatron) CreateDMAndSendMessage(snowflake disgord.Snowflake, message string) {
 	channel, err := t.discord.CreateDM(context.Background(), snowflake)
 	if err != nil {
@@ -74,12 +285,70 @@ func (t *Tabulatron) CreateDMAndSendMessage(snowflake disgord.Snowflake, message
 		return
 	}
 
-	_, err = channel.SendMsgString(context.Background(), t.discord, message)
+	_, err = t.discord.SendMessage(context.Background(), channel.ID, t.catalogue.Translate(t.language(snowflake), message))
 	if err != nil {
 		log.Printf("error sending DM to user %v: %v", snowflake, err.Error())
 	}
 }
 
+func (t *Tabulatron) language(snowflake disgord.Snowflake) string {
+	language, err := t.database.LanguageFromDiscord(snowflake.String())
+	if err != nil {
+		log.Printf("error finding language for %v: %v", snowflake, err.Error())
+	}
+
+	return language
+}
+
+func (t *Tabulatron) translate(snowflake disgord.Snowflake, msgid string) string {
+	return t.catalogue.Translate(t.language(snowflake), msgid)
+}
+
+func (t *Tabulatron) render(snowflake disgord.Snowflake, name string, data interface{}) string {
+	return t.templates.RenderIn(t.language(snowflake), name, data)
+}
+
+func describeTabbycatError(err error) string {
+	var (
+		tabbycatErr *tabbycat.Error
+		netErr      net.Error
+	)
+
+	switch {
+	case errors.Is(err, context.Canceled):
+		return "the request was cancelled"
+	case errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()):
+		return "Tabbycat took too long to respond"
+	case errors.Is(err, tabbycat.ErrUnauthorised):
+		return "Tabbycat rejected my API key"
+	case errors.Is(err, tabbycat.ErrNotFound):
+		return "Tabbycat couldn't find what I asked for"
+	case errors.Is(err, tabbycat.ErrValidation) && errors.As(err, &tabbycatErr):
+		return fmt.Sprintf("Tabbycat rejected the request (%v)", tabbycatErr.Summary())
+	case errors.Is(err, tabbycat.ErrRateLimited):
+		return "Tabbycat is receiving too many requests"
+	case errors.Is(err, tabbycat.ErrServer):
+		return "Tabbycat is having problems right now"
+	case errors.As(err, &tabbycatErr):
+		return fmt.Sprintf("Tabbycat responded with status %v", tabbycatErr.StatusCode)
+	default:
+		return "I couldn't reach Tabbycat"
+	}
+}
+
+func describeDiscordError(rest *disgord.ErrRest) string {
+	switch rest.HTTPCode {
+	case http.StatusForbidden:
+		return "Discord says I'm missing permissions"
+	case http.StatusNotFound:
+		return "Discord couldn't find a channel or member"
+	case http.StatusTooManyRequests:
+		return "Discord is receiving too many requests"
+	default:
+		return fmt.Sprintf("Discord responded with status %v (%v)", rest.HTTPCode, rest.Msg)
+	}
+}
+
 func (t *Tabulatron) reactMessage(message *disgord.Message, reaction string) {
 	t.pundit.SendReaction(message.ChannelID, message.ID, reaction)
 }
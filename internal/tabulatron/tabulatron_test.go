@@ -0,0 +1,537 @@
+package tabulatron
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/andersfylling/disgord"
+	"github.com/hitecherik/Tabulatron/internal/chat/simguild"
+	"github.com/hitecherik/Tabulatron/internal/db"
+	"github.com/hitecherik/Tabulatron/internal/hermes"
+	"github.com/hitecherik/Tabulatron/internal/interactions"
+	"github.com/hitecherik/Tabulatron/internal/layout"
+	"github.com/hitecherik/Tabulatron/internal/mailer"
+	"github.com/hitecherik/Tabulatron/internal/pundit"
+	"github.com/hitecherik/Tabulatron/internal/roundmessenger"
+	"github.com/hitecherik/Tabulatron/internal/scheduler"
+	"github.com/hitecherik/Tabulatron/internal/tracks"
+	"github.com/hitecherik/Tabulatron/internal/util"
+	"github.com/hitecherik/Tabulatron/pkg/tabbycat"
+	"github.com/hitecherik/Tabulatron/pkg/tabbycat/tabbycattest"
+)
+
+// tournament is a guild laid out the way the bot expects, with a tab team
+// member, two participants who haven't registered yet and someone who isn't
+// taking part.
+type tournament struct {
+	t            *testing.T
+	guild        *simguild.Guild
+	fake         *tabbycattest.Server
+	database     *db.Database
+	tron         *Tabulatron
+	speakerRole  *disgord.Role
+	judgeRole    *disgord.Role
+	registration *disgord.Channel
+	checkin      *disgord.Channel
+	availability *disgord.Channel
+	draw         *disgord.Channel
+	tab          *disgord.Channel
+	tabMember    *disgord.Member
+	ada          *disgord.Member
+	hedy         *disgord.Member
+	stranger     *disgord.Member
+}
+
+func newTournament(t *testing.T) *tournament {
+	t.Helper()
+
+	client, fake, database := tabbycattest.NewTournament(t)
+
+	guild := simguild.New()
+	tr := &tournament{t: t, guild: guild, fake: fake, database: database}
+
+	tr.speakerRole = guild.AddRole("Speaker")
+	tr.judgeRole = guild.AddRole("Judge")
+	tabRole := guild.AddRole("Tab/Tech")
+
+	tr.registration = guild.AddChannel("registration")
+	guild.AddChannel("registration-help")
+	tr.checkin = guild.AddChannel("checkin")
+	tr.availability = guild.AddChannel("adjudicator-availability")
+	guild.AddChannel("tab-and-tech-help")
+	tr.draw = guild.AddChannel("motions-and-draw")
+	tr.tab = guild.AddChannel("tab")
+
+	tr.tabMember = guild.AddMember("tab", tabRole)
+	tr.ada = guild.AddMember("ada")
+	tr.hedy = guild.AddMember("hedy")
+	tr.stranger = guild.AddMember("stranger")
+
+	bots := scheduler.New()
+	bots.AddClient(guild)
+	tr.tron = New(guild, database, client, pundit.New(bots), nil, bots)
+
+	return tr
+}
+
+// say posts content and returns the message so that replies and reactions to
+// it can be checked.
+func (tr *tournament) say(channel *disgord.Channel, member *disgord.Member, content string) *disgord.Message {
+	evt := tr.guild.Post(channel, tr.guild.Member(member.User.ID), content)
+	tr.tron.HandleMessage(evt)
+
+	return evt.Message
+}
+
+func (tr *tournament) expectReaction(message *disgord.Message, emoji string) {
+	tr.t.Helper()
+
+	eventually(tr.t, func() bool {
+		reactions := tr.guild.Reactions(message)
+		return len(reactions) > 0 && reactions[0] == emoji
+	}, "%q to get %v, got %v", message.Content, emoji, tr.guild.Reactions(message))
+}
+
+func (tr *tournament) expectReply(channel *disgord.Channel, member *disgord.Member, text string) {
+	tr.t.Helper()
+
+	messages := tr.guild.Messages(channel)
+	last := messages[len(messages)-1]
+
+	if !strings.HasPrefix(last.Content, member.User.Mention()) || !strings.Contains(last.Content, text) {
+		tr.t.Errorf("got reply %q, want one to %v containing %q", last.Content, member.User.Username, text)
+	}
+}
+
+func (tr *tournament) hasRole(member *disgord.Member, role *disgord.Role) bool {
+	for _, id := range tr.guild.Member(member.User.ID).Roles {
+		if id == role.ID {
+			return true
+		}
+	}
+
+	return false
+}
+
+func eventually(t *testing.T, condition func() bool, format string, a ...interface{}) {
+	t.Helper()
+
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if condition() {
+			return
+		}
+	}
+
+	t.Errorf("timed out waiting for "+format, a...)
+}
+
+func TestTournamentDay(t *testing.T) {
+	tr := newTournament(t)
+
+	early := tr.say(tr.registration, tr.ada, "100001")
+	tr.expectReaction(early, "❌")
+	tr.expectReply(tr.registration, tr.ada, "Registration hasn't started yet")
+
+	sneaky := tr.say(tr.tab, tr.stranger, "!startreg")
+	tr.expectReaction(sneaky, "❌")
+	tr.expectReply(tr.tab, tr.stranger, "you can't ask me to do that")
+
+	tr.expectReaction(tr.say(tr.tab, tr.tabMember, "!startreg"), "✅")
+
+	tr.expectReaction(tr.say(tr.registration, tr.ada, "100001"), "✅")
+	tr.expectReaction(tr.say(tr.registration, tr.hedy, "!register 200009"), "✅")
+
+	if nick := tr.guild.Member(tr.ada.User.ID).Nick; nick != "[🐙] Ada Lovelace" {
+		t.Errorf("got nickname %q for Ada", nick)
+	}
+
+	if !tr.hasRole(tr.ada, tr.speakerRole) || tr.hasRole(tr.ada, tr.judgeRole) {
+		t.Errorf("Ada should only have the speaker role")
+	}
+
+	if !tr.hasRole(tr.hedy, tr.judgeRole) || tr.hasRole(tr.hedy, tr.speakerRole) {
+		t.Errorf("Hedy should only have the judge role")
+	}
+
+	eventually(t, func() bool {
+		dms := tr.guild.DMs(tr.ada)
+		return len(dms) == 1 && strings.Contains(dms[0].Content, "successfully registered")
+	}, "Ada's registration DM, got %v", tr.guild.DMs(tr.ada))
+
+	wrong := tr.say(tr.registration, tr.stranger, "999999")
+	tr.expectReaction(wrong, "❌")
+	tr.expectReply(tr.registration, tr.stranger, "there was an error registering you")
+
+	tr.expectReaction(tr.say(tr.checkin, tr.ada, "!checkin"), "❌")
+	tr.expectReply(tr.checkin, tr.ada, "Check-in hasn't started yet")
+
+	tr.expectReaction(tr.say(tr.tab, tr.tabMember, "!startcheckin"), "✅")
+	tr.expectReaction(tr.say(tr.checkin, tr.ada, "!checkin"), "✅")
+	tr.expectReaction(tr.say(tr.availability, tr.hedy, "!checkout"), "✅")
+
+	tr.expectReaction(tr.say(tr.checkin, tr.ada, "!checkout"), "❌")
+	tr.expectReply(tr.checkin, tr.ada, "Only judges can check out")
+
+	if got := len(tr.fake.RequestsTo("PUT", "speakers/1/checkin")); got != 1 {
+		t.Errorf("got %v check-ins for Ada, want 1", got)
+	}
+
+	if got := len(tr.fake.RequestsTo("DELETE", "adjudicators/9/checkin")); got != 1 {
+		t.Errorf("got %v check-outs for Hedy, want 1", got)
+	}
+
+	command := tr.say(tr.tab, tr.tabMember, "!motion 1")
+	for _, message := range tr.guild.Messages(tr.tab) {
+		if message.ID == command.ID {
+			t.Errorf("the !motion command wasn't deleted")
+		}
+	}
+
+	announcements := tr.guild.Messages(tr.draw)
+	if len(announcements) != 2 {
+		t.Fatalf("got %v messages in the draw channel, want the motion and a prep timer", len(announcements))
+	}
+
+	if !strings.Contains(announcements[0].Content, "This House would example motion 1") {
+		t.Errorf("got motion announcement %q", announcements[0].Content)
+	}
+
+	if !strings.Contains(announcements[1].Content, "prep time") {
+		t.Errorf("got prep timer %q", announcements[1].Content)
+	}
+
+	if got := len(tr.fake.RequestsTo("POST", "rounds/1")); got != 1 {
+		t.Errorf("got %v motion releases, want 1", got)
+	}
+}
+
+func TestMotionTracks(t *testing.T) {
+	tr := newTournament(t)
+	tr.tron.SetTracks(tracks.Tracks{"Open": {1}, "Novice": {2}})
+
+	tr.expectReaction(tr.say(tr.tab, tr.tabMember, "!motion 2 pro-am"), "❌")
+	tr.expectReply(tr.tab, tr.tabMember, "there's no pro-am track; try one of Novice, Open")
+
+	tr.say(tr.tab, tr.tabMember, "!motion 2 open")
+	tr.say(tr.tab, tr.tabMember, "!motion 2 novice")
+
+	announcements := tr.guild.Messages(tr.draw)
+	if len(announcements) != 4 {
+		t.Fatalf("got %v messages in the draw channel, want a motion and a prep timer for each track", len(announcements))
+	}
+
+	if !strings.Contains(announcements[2].Content, "novice motion 2") || !strings.Contains(announcements[3].Content, "prep time") {
+		t.Errorf("got %q and %q for the novice track", announcements[2].Content, announcements[3].Content)
+	}
+
+	tr.expectReaction(tr.say(tr.tab, tr.tabMember, "!prep pause 2 novice"), "✅")
+	tr.expectReaction(tr.say(tr.tab, tr.tabMember, "!prep pause 2 Novice"), "❌")
+	tr.expectReaction(tr.say(tr.tab, tr.tabMember, "!prep pause 2 open"), "✅")
+	tr.expectReaction(tr.say(tr.tab, tr.tabMember, "!prep cancel 2"), "❌")
+}
+
+func TestVetoes(t *testing.T) {
+	tr := newTournament(t)
+
+	tr.expectReaction(tr.say(tr.tab, tr.tabMember, "!vetoes 1"), "✅")
+	tr.expectReply(tr.tab, tr.tabMember, "**1.** This House would example motion 1 (Motion 1): 1")
+
+	if got := len(tr.guild.Messages(tr.draw)); got != 0 {
+		t.Errorf("counting vetoes posted %v messages in the draw channel", got)
+	}
+}
+
+func TestMissingTabRole(t *testing.T) {
+	tr := newTournament(t)
+
+	dir, err := ioutil.TempDir("", "layout")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	file := filepath.Join(dir, "layout.toml")
+	if err := ioutil.WriteFile(file, []byte("[roles]\ntab = \"Adjudication Core\"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var l layout.Layout
+	if err := l.Set(file); err != nil {
+		t.Fatalf("loading layout: %v", err)
+	}
+	tr.tron.SetLayout(&l)
+
+	for _, command := range []string{"!startreg", "!motion 1", "!prep pause 1"} {
+		tr.expectReaction(tr.say(tr.tab, tr.tabMember, command), "❌")
+		tr.expectReply(tr.tab, tr.tabMember, "the server has no Adjudication Core role")
+	}
+}
+
+func TestPullTabbycatListsFailuresAcrossMessages(t *testing.T) {
+	tr := newTournament(t)
+
+	for i := 0; i < 60; i++ {
+		name := fmt.Sprintf("Adjudicator With A Rather Long Name Number %v", i)
+		if _, err := tr.tron.tabbycat.CreateAdjudicator(tabbycat.AdjudicatorDetails{Name: name}); err != nil {
+			t.Fatalf("CreateAdjudicator: %v", err)
+		}
+	}
+
+	tr.expectReaction(tr.say(tr.tab, tr.tabMember, "!pulltabbycat"), "❌")
+
+	listed := 0
+	for _, message := range tr.guild.Messages(tr.tab) {
+		listed += strings.Count(message.Content, "Rather Long Name")
+	}
+
+	if listed != 60 {
+		t.Errorf("got %v mentions of adjudicators without barcodes, want each listed once", listed)
+	}
+}
+
+func TestReleaseDrawProgressStaysUnderLimit(t *testing.T) {
+	tr := newTournament(t)
+	h := NewReleaseDrawHandler(tr.tron)
+
+	progress := roundmessenger.Progress{TotalRooms: 300, Rooms: 300}
+	for i := 0; i < 300; i++ {
+		progress.Unregistered = append(progress.Unregistered, fmt.Sprint(1000+i))
+		progress.Failed = append(progress.Failed, hermes.Delivery{To: disgord.Snowflake(5000 + i)})
+	}
+
+	if content := h.describeProgress(progress); len(content) > util.MessageLimit {
+		t.Errorf("got a progress message of %v characters", len(content))
+	}
+
+	h.listInFull(tr.tab.ID, progress)
+
+	listed := 0
+	for _, message := range tr.guild.Messages(tr.tab) {
+		listed += strings.Count(message.Content, "• ")
+	}
+
+	if listed != 600 {
+		t.Errorf("listed %v people in full, want all 300 unregistered adjudicators and 300 failures", listed)
+	}
+}
+
+func TestScheduledReleaseFailureIsReported(t *testing.T) {
+	tr := newTournament(t)
+
+	schedule := db.ScheduledMotion{
+		Round:             99,
+		ChannelId:         tr.draw.ID.String(),
+		TabChannelId:      tr.tab.ID.String(),
+		InfoSlide:         time.Now(),
+		Motion:            time.Now(),
+		InfoSlideReleased: true,
+	}
+	if err := tr.database.ScheduleMotion(schedule); err != nil {
+		t.Fatalf("ScheduleMotion: %v", err)
+	}
+
+	tr.tron.scheduler.start(schedule)
+
+	eventually(t, func() bool {
+		messages := tr.guild.Messages(tr.tab)
+		return len(messages) == 1 && strings.Contains(messages[0].Content, "couldn't release the motion for round 99: I couldn't find any information about that round")
+	}, "the failed release to be reported, got %v", tr.guild.Messages(tr.tab))
+
+	if err := tr.database.ScheduleMotion(schedule); !errors.Is(err, db.ErrMotionReleased) {
+		t.Errorf("got %v rescheduling the claimed round, want ErrMotionReleased", err)
+	}
+}
+
+func TestScheduledTracksAfterEarlyRelease(t *testing.T) {
+	tr := newTournament(t)
+	tr.tron.SetTracks(tracks.Tracks{"Open": {1}, "Novice": {2}})
+
+	for _, track := range []string{"open", "novice"} {
+		schedule := db.ScheduledMotion{
+			Round:             2,
+			Track:             track,
+			ChannelId:         tr.draw.ID.String(),
+			TabChannelId:      tr.tab.ID.String(),
+			InfoSlide:         time.Now(),
+			Motion:            time.Now().Add(200 * time.Millisecond),
+			InfoSlideReleased: true,
+		}
+		if err := tr.database.ScheduleMotion(schedule); err != nil {
+			t.Fatalf("ScheduleMotion: %v", err)
+		}
+
+		tr.tron.scheduler.start(schedule)
+	}
+
+	// The open track is released early by hand.
+	tr.say(tr.tab, tr.tabMember, "!motion 2 open")
+
+	eventually(t, func() bool {
+		tr.tron.scheduler.mutex.Lock()
+		defer tr.tron.scheduler.mutex.Unlock()
+
+		return len(tr.tron.scheduler.schedules) == 0
+	}, "the schedules to finish")
+
+	announcements := tr.guild.Messages(tr.draw)
+	if len(announcements) != 4 {
+		t.Fatalf("got %v messages in the draw channel, want a motion and a prep timer for each track", len(announcements))
+	}
+
+	if !strings.Contains(announcements[0].Content, "Round 2 (Open)") || !strings.Contains(announcements[2].Content, "Round 2 (Novice)") {
+		t.Errorf("got %q and %q, want the open motion once and then the novice one", announcements[0].Content, announcements[2].Content)
+	}
+}
+
+func TestOpenRoomsDescribesDiscordErrors(t *testing.T) {
+	tr := newTournament(t)
+	text := tr.guild.AddChannel("open-1")
+	tr.guild.LockChannel(text)
+
+	teams, err := tr.tron.tabbycat.GetTeams()
+	if err != nil {
+		t.Fatalf("GetTeams: %v", err)
+	}
+
+	if _, _, _, err := tr.database.ParticipantFromBarcode(teams[0].Speakers[0].Barcode, tr.ada.User.ID.String()); err != nil {
+		t.Fatalf("registering ada: %v", err)
+	}
+
+	tr.expectReaction(tr.say(tr.tab, tr.tabMember, "!openrooms 1"), "❌")
+	tr.expectReply(tr.tab, tr.tabMember, "Discord says I'm missing permissions")
+}
+
+func TestOpenRoomsCarriesOnPastMissingChannels(t *testing.T) {
+	tr := newTournament(t)
+
+	pairing := `{
+		"venue": "http://localhost:8000/api/v1/tournaments/example/venues/2",
+		"adjudicators": {"chair": "http://localhost:8000/api/v1/tournaments/example/adjudicators/9", "panellists": [], "trainees": []},
+		"teams": [
+			{"side": "og", "team": "http://localhost:8000/api/v1/tournaments/example/teams/3"},
+			{"side": "oo", "team": "http://localhost:8000/api/v1/tournaments/example/teams/4"},
+			{"side": "cg", "team": "http://localhost:8000/api/v1/tournaments/example/teams/1"},
+			{"side": "co", "team": "http://localhost:8000/api/v1/tournaments/example/teams/2"}
+		]
+	}`
+	request := httptest.NewRequest(http.MethodPost, "/api/v1/tournaments/example/rounds/2/pairings", bytes.NewBufferString(pairing))
+	recorder := httptest.NewRecorder()
+	tr.fake.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusCreated {
+		t.Fatalf("drawing round 2: got status %v", recorder.Code)
+	}
+
+	tr.expectReaction(tr.say(tr.tab, tr.tabMember, "!openrooms 1 2"), "❌")
+	tr.expectReply(tr.tab, tr.tabMember, "couldn't find channels for these 3 venues")
+	tr.expectReply(tr.tab, tr.tabMember, "Open 1 (round 1)")
+	tr.expectReply(tr.tab, tr.tabMember, "Novice 1 (round 2)")
+}
+
+func TestAnnounceListsUnreachableAcrossMessages(t *testing.T) {
+	tr := newTournament(t)
+	tr.tron.SetMailer(mailer.New("127.0.0.1", "1", "", "", "tab@example.com"))
+
+	adjudicators := make([]tabbycat.Participant, 0)
+	for i := 0; i < 60; i++ {
+		adjudicators = append(adjudicators, tabbycat.Participant{
+			Id:      uint(1000 + i),
+			Name:    fmt.Sprintf("Adjudicator With A Rather Long Name Number %v", i),
+			Barcode: fmt.Sprint(900000 + i),
+		})
+	}
+
+	if err := tr.database.AddParticipants(false, adjudicators); err != nil {
+		t.Fatalf("AddParticipants: %v", err)
+	}
+
+	tr.say(tr.tab, tr.tabMember, "!announce adjudicators unregistered\nHello {{.Name}}")
+	tr.expectReply(tr.tab, tr.tabMember, "this will email 63 unregistered adjudicators")
+	tr.expectReaction(tr.say(tr.tab, tr.tabMember, "!announce confirm"), "❌")
+
+	listed := 0
+	for _, message := range tr.guild.Messages(tr.tab) {
+		listed += strings.Count(message.Content, "Rather Long Name")
+	}
+
+	if listed != 60 {
+		t.Errorf("listed %v adjudicators who couldn't be emailed, want all 60", listed)
+	}
+}
+
+func TestMoveRoomsListsUnplacedAcrossMessages(t *testing.T) {
+	tr := newTournament(t)
+	waitingRoom := tr.guild.AddVoiceChannel("Waiting room")
+
+	for i := 0; i < 100; i++ {
+		member := tr.guild.AddMember(fmt.Sprintf("latecomer-with-a-long-name-%v", i))
+		tr.tron.HandleVoiceState(tr.guild.JoinVoice(member, waitingRoom))
+	}
+
+	before := len(tr.guild.Messages(tr.tab))
+	tr.expectReaction(tr.say(tr.tab, tr.tabMember, "!moverooms 1"), "❌")
+
+	replies := tr.guild.Messages(tr.tab)[before+1:]
+	if len(replies) < 2 {
+		t.Fatalf("got %v replies, want the list split", len(replies))
+	}
+
+	listed := 0
+	for _, reply := range replies {
+		if len(reply.Content) > util.MessageLimit {
+			t.Errorf("got a reply of %v characters", len(reply.Content))
+		}
+
+		if !strings.HasPrefix(reply.Content, tr.tabMember.User.Mention()) {
+			t.Errorf("got reply %q, want it to mention %v", reply.Content, tr.tabMember.User.Username)
+		}
+
+		listed += strings.Count(reply.Content, "(they aren't registered)")
+	}
+
+	if listed != 100 {
+		t.Errorf("listed %v people who couldn't be placed, want all 100", listed)
+	}
+}
+
+// TestCommandsDuringStart is meant for go test -race: slash commands arrive on
+// the interactions server's goroutine while !startcheckin and !startreg arrive
+// on the gateway's.
+func TestCommandsDuringStart(t *testing.T) {
+	tr := newTournament(t)
+	member := tr.guild.Member(tr.ada.User.ID)
+
+	commands := []*interactions.Interaction{
+		{Type: interactions.TypeApplicationCommand, GuildID: tr.guild.ID, Member: member, Data: interactions.Data{Name: "checkin"}},
+		{Type: interactions.TypeApplicationCommand, GuildID: tr.guild.ID, Member: member, Data: interactions.Data{Name: "register", Options: []interactions.OptionValue{{Name: "code", Value: "000000"}}}},
+	}
+
+	var wg sync.WaitGroup
+	for _, command := range commands {
+		wg.Add(1)
+		go func(command *interactions.Interaction) {
+			defer wg.Done()
+
+			for i := 0; i < 20; i++ {
+				tr.tron.HandleInteraction(command)
+			}
+		}(command)
+	}
+
+	tr.say(tr.tab, tr.tabMember, "!startcheckin")
+	tr.say(tr.tab, tr.tabMember, "!startreg")
+	wg.Wait()
+
+	if reply := tr.tron.HandleInteraction(commands[0]); strings.Contains(reply, "hasn't started") {
+		t.Errorf("got %q after check-in started", reply)
+	}
+}
@@ -0,0 +1,50 @@
+package tabulatron
+
+import (
+	"sync"
+
+	"github.com/andersfylling/disgord"
+)
+
+// voiceStates remembers which voice channel everyone is in, since Discord only
+// tells us when the guild is first sent and when somebody moves.
+type voiceStates struct {
+	mutex    sync.Mutex
+	channels map[disgord.Snowflake]map[disgord.Snowflake]disgord.Snowflake
+}
+
+func newVoiceStates() *voiceStates {
+	return &voiceStates{channels: make(map[disgord.Snowflake]map[disgord.Snowflake]disgord.Snowflake)}
+}
+
+func (v *voiceStates) update(state *disgord.VoiceState) {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	guild, ok := v.channels[state.GuildID]
+	if !ok {
+		guild = make(map[disgord.Snowflake]disgord.Snowflake)
+		v.channels[state.GuildID] = guild
+	}
+
+	if state.ChannelID.IsZero() {
+		delete(guild, state.UserID)
+		return
+	}
+
+	guild[state.UserID] = state.ChannelID
+}
+
+func (v *voiceStates) in(guild, channel disgord.Snowflake) []disgord.Snowflake {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	users := make([]disgord.Snowflake, 0)
+	for user, current := range v.channels[guild] {
+		if current == channel {
+			users = append(users, user)
+		}
+	}
+
+	return users
+}
@@ -0,0 +1,418 @@
+package templates
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/hitecherik/Tabulatron/internal/locale"
+	"github.com/pelletier/go-toml"
+)
+
+// Templates overrides the default wording of participant-facing messages with
+// templates loaded from a TOML file. Tables prefixed with a language code only
+// apply to participants who chose that language, e.g.
+//
+//	[draw]
+//	speaker = "You're speaking as **{{.Side}}** in **{{.Venue}}**."
+//
+//	[de.draw]
+//	speaker = "Du sprichst als **{{.Side}}** in **{{.Venue}}**."
+//
+// Its zero value renders the defaults.
+type Templates struct {
+	path      string
+	overrides *template.Template
+	languages map[string]bool
+}
+
+type Draw struct {
+	Side       string
+	Venue      string
+	Position   string
+	ZoomUrl    string
+	PrivateUrl string
+}
+
+type Registration struct {
+	Channel     string
+	HelpChannel string
+}
+
+type Checkin struct {
+	Direction       string
+	Judge           bool
+	CheckinChannel  string
+	CheckoutChannel string
+	HelpChannel     string
+	Error           string
+}
+
+type Prep struct {
+	Round     string
+	Minutes   int
+	Starts    string
+	Timestamp string
+}
+
+type Ballot struct {
+	Round      string
+	Venue      string
+	PrivateUrl string
+}
+
+type Motion struct {
+	Seq  uint
+	Text string
+}
+
+type Motions struct {
+	Round   string
+	Motions []Motion
+}
+
+type Participant struct {
+	Name string
+}
+
+type definition struct {
+	text   string
+	sample interface{}
+}
+
+var sampleDraw = Draw{
+	Side:       "Opening Government",
+	Venue:      "Room 1",
+	Position:   "chair",
+	ZoomUrl:    "https://zoom.us/j/123456789",
+	PrivateUrl: "https://tabbycat.example.com/example/privateurls/abcdefgh/",
+}
+
+var sampleRegistration = Registration{Channel: "#registration", HelpChannel: "#registration-help"}
+
+var sampleCheckin = Checkin{
+	Direction:       "in",
+	Judge:           true,
+	CheckinChannel:  "#check-in",
+	CheckoutChannel: "#check-out",
+	HelpChannel:     "#tech-help",
+	Error:           "Tabbycat didn't respond in time",
+}
+
+var samplePrep = Prep{Round: "Round 1", Minutes: 15, Starts: "09:15 UTC", Timestamp: "<t:1600000000:t>"}
+
+var sampleMotions = Motions{
+	Round: "Round 1",
+	Motions: []Motion{
+		{1, "This House would abolish homework."},
+		{2, "This House regrets the rise of homework apps."},
+	},
+}
+
+const drawLinks = `{{if .ZoomUrl}}
+
+The link to your Zoom room is {{.ZoomUrl}}.{{end}}{{if .PrivateUrl}}
+
+Your private URL is {{.PrivateUrl}}.{{end}}`
+
+// definitions are the participant-facing messages that can be overridden. The
+// replies to !language and to interactions the bot doesn't recognise aren't
+// among them: they're fixed sentences around at most a list of language codes,
+// so the -locale catalogue translates them, and a language has to be chosen
+// before a template in it could apply anyway.
+var definitions = map[string]definition{
+	"draw.speaker": {
+		"In this round, you will be speaking in **{{.Side}}** in room **{{.Venue}}**." + drawLinks,
+		sampleDraw,
+	},
+	"draw.judge": {
+		`In this round, you will be judging as **{{if eq .Position "chair"}}the chair{{else if eq .Position "trainee"}}a trainee{{else}}a panellist{{end}}** in room **{{.Venue}}**.` + drawLinks,
+		sampleDraw,
+	},
+	"registration.notstarted": {
+		"I can't do that. Registration hasn't started yet.",
+		sampleRegistration,
+	},
+	"registration.wrongchannel": {
+		"you can't do that here. Registration can only happen in the {{.Channel}} channel.",
+		sampleRegistration,
+	},
+	"registration.badcode": {
+		"please double-check your registration code – it should be six digits long.",
+		sampleRegistration,
+	},
+	"registration.placeholder": {
+		"please replace `123456` in your message with your registration code. If you don't know what this is, ask in {{.HelpChannel}}.",
+		sampleRegistration,
+	},
+	"registration.error": {
+		"there was an error registering you. Please check the code you entered and try again.",
+		sampleRegistration,
+	},
+	"registration.nickname": {
+		"there was an error setting your nickname and/or role! Please ask in {{.HelpChannel}} for help.",
+		sampleRegistration,
+	},
+	"registration.success": {
+		"Congratulations! You have successfully registered.",
+		sampleRegistration,
+	},
+	"checkin.notstarted": {
+		"I can't do that. Check-in hasn't started yet.",
+		sampleCheckin,
+	},
+	"checkin.wrongchannel": {
+		"you can't do that here. {{if .Judge}}Check-in and check-out can only happen in the {{.CheckinChannel}} or {{.CheckoutChannel}} channels.{{else}}Check-in can only happen in the {{.CheckinChannel}} channel.{{end}}",
+		sampleCheckin,
+	},
+	"checkin.judgesonly": {
+		"you can't do that. Only judges can check out.",
+		sampleCheckin,
+	},
+	"checkin.success": {
+		"you're now checked {{.Direction}}.",
+		sampleCheckin,
+	},
+	"checkin.error": {
+		"there was an error checking you {{.Direction}}{{if .Error}}: {{.Error}}{{end}}. Please ask for help in {{.HelpChannel}}.",
+		sampleCheckin,
+	},
+	"prep.running": {
+		"There {{if eq .Minutes 1}}is **1 minute**{{else}}are **{{.Minutes}} minutes**{{end}} of prep time left. The round starts at **{{.Starts}}** ({{.Timestamp}}).",
+		samplePrep,
+	},
+	"prep.paused": {
+		"Prep time is **paused** with {{if eq .Minutes 1}}**1 minute**{{else}}**{{.Minutes}} minutes**{{end}} left.",
+		samplePrep,
+	},
+	"prep.cancelled": {
+		"Prep time for {{.Round}} was cancelled.",
+		samplePrep,
+	},
+	"prep.over": {
+		"Prep time for {{.Round}} is over.",
+		samplePrep,
+	},
+	"prep.announcement": {
+		"@everyone Prep time for {{.Round}} over!",
+		samplePrep,
+	},
+	"ballot.reminder": {
+		"The ballot for your room (**{{.Venue}}**) in **{{.Round}}** hasn't been submitted yet. Please submit it as soon as possible.{{if .PrivateUrl}}\n\nYour private URL is {{.PrivateUrl}}.{{end}}",
+		Ballot{Round: "Round 1", Venue: "Room 1", PrivateUrl: sampleDraw.PrivateUrl},
+	},
+	"motion.infoslide": {
+		"{{if not .Motions}}There is no info slide for {{.Round}}.{{else}}@everyone\n{{if eq (len .Motions) 1}}The info slide for **{{.Round}}** is:\n\n{{(index .Motions 0).Text}}{{else}}The info slides for **{{.Round}}** are:{{range .Motions}}\n\n**{{.Seq}}.** {{.Text}}{{end}}{{end}}{{end}}",
+		sampleMotions,
+	},
+	"motion.motion": {
+		"{{if not .Motions}}There is no motion for {{.Round}}.{{else}}@everyone\n{{if eq (len .Motions) 1}}The motion for **{{.Round}}** is:\n\n{{(index .Motions 0).Text}}{{else}}The motions for **{{.Round}}** are:{{range .Motions}}\n\n**{{.Seq}}.** {{.Text}}{{end}}{{end}}{{end}}",
+		sampleMotions,
+	},
+}
+
+var defaults *template.Template = parseDefaults()
+
+func parseDefaults() *template.Template {
+	t := template.New("")
+
+	for name, definition := range definitions {
+		template.Must(t.New(name).Parse(definition.text))
+	}
+
+	return t
+}
+
+func (t *Templates) String() string {
+	return t.path
+}
+
+func (t *Templates) Set(path string) error {
+	tree, err := toml.LoadFile(path)
+	if err != nil {
+		return err
+	}
+
+	overrides := template.New("")
+	languages := make(map[string]bool)
+	for name, value := range flatten("", tree.ToMap()) {
+		language, base := splitLanguage(name)
+		if _, ok := definitions[base]; !ok {
+			return fmt.Errorf("unknown template \"%v\" in %v", name, path)
+		}
+
+		text, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("template \"%v\" in %v isn't a string", name, path)
+		}
+
+		if _, err := overrides.New(overrideName(language, base)).Parse(text); err != nil {
+			return err
+		}
+
+		if language != "" {
+			languages[language] = true
+		}
+	}
+
+	t.path = path
+	t.overrides = overrides
+	t.languages = languages
+	return nil
+}
+
+// splitLanguage separates the language from a template name like
+// "fr.draw.speaker", for templates that only apply to one language.
+func splitLanguage(name string) (string, string) {
+	if _, ok := definitions[name]; ok {
+		return "", name
+	}
+
+	parts := strings.SplitN(name, ".", 2)
+	if len(parts) != 2 {
+		return "", name
+	}
+
+	return locale.Normalise(parts[0]), parts[1]
+}
+
+func overrideName(language string, name string) string {
+	if language == "" {
+		return name
+	}
+
+	return fmt.Sprintf("%v:%v", language, name)
+}
+
+func (t *Templates) Has(language string) bool {
+	return t != nil && t.languages[locale.Normalise(language)]
+}
+
+func (t *Templates) Languages() []string {
+	languages := []string{}
+	if t == nil {
+		return languages
+	}
+
+	for language := range t.languages {
+		languages = append(languages, language)
+	}
+	sort.Strings(languages)
+
+	return languages
+}
+
+func flatten(prefix string, tree map[string]interface{}) map[string]interface{} {
+	flat := make(map[string]interface{})
+
+	for key, value := range tree {
+		if subtree, ok := value.(map[string]interface{}); ok {
+			for k, v := range flatten(prefix+key+".", subtree) {
+				flat[k] = v
+			}
+		} else {
+			flat[prefix+key] = value
+		}
+	}
+
+	return flat
+}
+
+func (t *Templates) Render(name string, data interface{}) string {
+	return t.RenderIn("", name, data)
+}
+
+// RenderIn prefers a template for the given language, then the file's general
+// override, then the default wording. It falls back if an override fails to
+// render, so a mistake in the template file never stops a message going out.
+func (t *Templates) RenderIn(language string, name string, data interface{}) string {
+	candidates := []string{name}
+	if language != "" {
+		candidates = []string{overrideName(locale.Normalise(language), name), name}
+	}
+
+	for _, candidate := range candidates {
+		if t == nil || t.overrides == nil || t.overrides.Lookup(candidate) == nil {
+			continue
+		}
+
+		text, err := execute(t.overrides, candidate, data)
+		if err == nil {
+			return text
+		}
+
+		log.Printf("error rendering template %v: %v", candidate, err.Error())
+	}
+
+	text, err := execute(defaults, name, data)
+	if err != nil {
+		log.Printf("error rendering default template %v: %v", name, err.Error())
+	}
+
+	return text
+}
+
+type Sample struct {
+	Name string
+	Text string
+}
+
+// Validate renders every template with sample data, in every language the
+// file has templates for, returning the results in name order.
+func (t *Templates) Validate() ([]Sample, error) {
+	names := make([]string, 0, len(definitions))
+	for name := range definitions {
+		names = append(names, name)
+		if t.overrides == nil {
+			continue
+		}
+
+		for language := range t.languages {
+			if t.overrides.Lookup(overrideName(language, name)) != nil {
+				names = append(names, overrideName(language, name))
+			}
+		}
+	}
+	sort.Strings(names)
+
+	samples := make([]Sample, 0, len(names))
+	var failed []string
+
+	for _, name := range names {
+		set := defaults
+		if t.overrides != nil && t.overrides.Lookup(name) != nil {
+			set = t.overrides
+		}
+
+		base := name
+		if i := strings.Index(name, ":"); i >= 0 {
+			base = name[i+1:]
+		}
+
+		text, err := execute(set, name, definitions[base].sample)
+		if err != nil {
+			failed = append(failed, err.Error())
+		}
+
+		samples = append(samples, Sample{name, text})
+	}
+
+	if len(failed) > 0 {
+		return samples, fmt.Errorf("%v templates failed to render:\n%v", len(failed), strings.Join(failed, "\n"))
+	}
+
+	return samples, nil
+}
+
+func execute(set *template.Template, name string, data interface{}) (string, error) {
+	var buffer bytes.Buffer
+
+	if err := set.ExecuteTemplate(&buffer, name, data); err != nil {
+		return "", err
+	}
+
+	return buffer.String(), nil
+}
@@ -0,0 +1,161 @@
+package templates
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func load(t *testing.T, contents string) (*Templates, error) {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "templates")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	file := filepath.Join(dir, "templates.toml")
+	if err := ioutil.WriteFile(file, []byte(contents), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var templates Templates
+	return &templates, templates.Set(file)
+}
+
+func TestSet(t *testing.T) {
+	tests := []struct {
+		name      string
+		contents  string
+		languages []string
+		fails     bool
+	}{
+		{"override", "[draw]\nspeaker = \"You're in {{.Venue}}.\"\n", []string{}, false},
+		{"languages", "[fr.draw]\nspeaker = \"Salle {{.Venue}}.\"\n[DE.prep]\nover = \"Vorbei.\"\n", []string{"de", "fr"}, false},
+		{"unknown template", "[draw]\nspeakers = \"You're in {{.Venue}}.\"\n", nil, true},
+		{"not a string", "[draw]\nspeaker = 1\n", nil, true},
+		{"bad syntax", "[draw]\nspeaker = \"{{.Venue\"\n", nil, true},
+		{"not TOML", "[draw\n", nil, true},
+	}
+
+	for _, test := range tests {
+		templates, err := load(t, test.contents)
+
+		if test.fails {
+			if err == nil {
+				t.Errorf("%v: loaded %v, want an error", test.name, test.contents)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%v: %v", test.name, err)
+			continue
+		}
+
+		if got := templates.Languages(); !reflect.DeepEqual(got, test.languages) {
+			t.Errorf("%v: got languages %v, want %v", test.name, got, test.languages)
+		}
+	}
+}
+
+func TestSplitLanguage(t *testing.T) {
+	tests := []struct {
+		name     string
+		language string
+		base     string
+	}{
+		{"draw.speaker", "", "draw.speaker"},
+		{"fr.draw.speaker", "fr", "draw.speaker"},
+		{"PT-BR.prep.over", "pt-br", "prep.over"},
+		{"draw", "", "draw"},
+	}
+
+	for _, test := range tests {
+		if language, base := splitLanguage(test.name); language != test.language || base != test.base {
+			t.Errorf("splitLanguage(%q) = %q, %q, want %q, %q", test.name, language, base, test.language, test.base)
+		}
+	}
+}
+
+func TestRenderIn(t *testing.T) {
+	templates, err := load(t, `
+[draw]
+speaker = "general {{.Venue}}"
+
+[fr.draw]
+speaker = "fr {{.Venue}}"
+
+[de.draw]
+speaker = "{{.Missing}}"
+`)
+	if err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+
+	draw := Draw{Side: "Opening Government", Venue: "Room 1", Position: "chair"}
+
+	tests := []struct {
+		language string
+		name     string
+		want     string
+	}{
+		{"", "draw.speaker", "general Room 1"},
+		{"fr", "draw.speaker", "fr Room 1"},
+		{" FR ", "draw.speaker", "fr Room 1"},
+		{"de", "draw.speaker", "general Room 1"},
+		{"es", "draw.speaker", "general Room 1"},
+		{"fr", "draw.judge", "In this round, you will be judging as **the chair** in room **Room 1**."},
+	}
+
+	for _, test := range tests {
+		if got := templates.RenderIn(test.language, test.name, draw); got != test.want {
+			t.Errorf("RenderIn(%q, %q) = %q, want %q", test.language, test.name, got, test.want)
+		}
+	}
+
+	var zero Templates
+	if got := zero.Render("prep.over", Prep{Round: "Round 1"}); got != "Prep time for Round 1 is over." {
+		t.Errorf("the zero value rendered %q", got)
+	}
+
+	var none *Templates
+	if got := none.RenderIn("fr", "prep.over", Prep{Round: "Round 1"}); got != "Prep time for Round 1 is over." {
+		t.Errorf("a nil Templates rendered %q", got)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	var defaults Templates
+	samples, err := defaults.Validate()
+	if err != nil {
+		t.Errorf("the defaults failed to render: %v", err)
+	}
+
+	if len(samples) != len(definitions) {
+		t.Errorf("got %v samples, want one for each of the %v templates", len(samples), len(definitions))
+	}
+
+	templates, err := load(t, "[fr.draw]\nspeaker = \"Salle {{.Venue}}.\"\n[prep]\nover = \"{{.Missing}}\"\n")
+	if err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+
+	samples, err = templates.Validate()
+	if err == nil || !strings.Contains(err.Error(), "1 templates failed") {
+		t.Errorf("got %v, want the prep.over override to fail", err)
+	}
+
+	found := false
+	for _, sample := range samples {
+		found = found || (sample.Name == "fr:draw.speaker" && sample.Text == "Salle Room 1.")
+	}
+
+	if !found || len(samples) != len(definitions)+1 {
+		t.Errorf("got samples %+v, want the French override alongside every template", samples)
+	}
+}
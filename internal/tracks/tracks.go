@@ -0,0 +1,79 @@
+package tracks
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/pelletier/go-toml"
+)
+
+// Tracks maps each release track to the sequence numbers of its motions in
+// every round, e.g.
+//
+//	[tracks]
+//	Open = [1]
+//	Novice = [2]
+type Tracks map[string][]uint
+
+type rawTracks struct {
+	Tracks map[string][]uint
+}
+
+func (ts *Tracks) String() string {
+	raw, err := toml.Marshal(rawTracks{*ts})
+	if err != nil {
+		log.Fatalf("could not marshal Tracks: %v", err)
+	}
+
+	return string(raw)
+}
+
+func (ts *Tracks) Set(path string) error {
+	tree, err := toml.LoadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var raw rawTracks
+	if err := tree.Unmarshal(&raw); err != nil {
+		return err
+	}
+
+	seen := make(map[string]string)
+	for name, seqs := range raw.Tracks {
+		if len(seqs) == 0 {
+			return fmt.Errorf("track \"%v\" in %v has no motions", name, path)
+		}
+
+		if other, ok := seen[strings.ToLower(name)]; ok {
+			return fmt.Errorf("tracks \"%v\" and \"%v\" in %v only differ in case", other, name, path)
+		}
+		seen[strings.ToLower(name)] = name
+	}
+
+	*ts = raw.Tracks
+	return nil
+}
+
+// Lookup finds a track regardless of case, returning its name as configured.
+func (ts Tracks) Lookup(name string) (string, []uint, error) {
+	for configured, seqs := range ts {
+		if strings.EqualFold(configured, name) {
+			return configured, seqs, nil
+		}
+	}
+
+	names := make([]string, 0, len(ts))
+	for configured := range ts {
+		names = append(names, configured)
+	}
+	sort.Strings(names)
+
+	if len(names) == 0 {
+		return "", nil, fmt.Errorf("no release tracks are set up")
+	}
+
+	return "", nil, fmt.Errorf("there's no %v track; try one of %v", name, strings.Join(names, ", "))
+}
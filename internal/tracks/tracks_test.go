@@ -0,0 +1,75 @@
+package tracks
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSet(t *testing.T) {
+	tests := []struct {
+		name     string
+		contents string
+		want     Tracks
+		fails    bool
+	}{
+		{"tracks", "[tracks]\nOpen = [1]\nNovice = [2, 3]\n", Tracks{"Open": {1}, "Novice": {2, 3}}, false},
+		{"no motions", "[tracks]\nOpen = []\n", nil, true},
+		{"clashing case", "[tracks]\nOpen = [1]\nopen = [2]\n", nil, true},
+	}
+
+	dir, err := ioutil.TempDir("", "tracks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	for _, test := range tests {
+		file := filepath.Join(dir, "tracks.toml")
+		if err := ioutil.WriteFile(file, []byte(test.contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		var got Tracks
+		err := got.Set(file)
+
+		if test.fails {
+			if err == nil {
+				t.Errorf("%v: got %v, want an error", test.name, got)
+			}
+			continue
+		}
+
+		if err != nil || !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%v: got %v (%v), want %v", test.name, got, err, test.want)
+		}
+	}
+}
+
+func TestLookup(t *testing.T) {
+	ts := Tracks{"Open": {1}, "Novice": {2}}
+
+	tests := []struct {
+		track string
+		name  string
+		seqs  []uint
+		fails bool
+	}{
+		{"Open", "Open", []uint{1}, false},
+		{"novice", "Novice", []uint{2}, false},
+		{"pro-am", "", nil, true},
+	}
+
+	for _, test := range tests {
+		name, seqs, err := ts.Lookup(test.track)
+		if (err != nil) != test.fails || name != test.name || !reflect.DeepEqual(seqs, test.seqs) {
+			t.Errorf("Lookup(%q) = %q, %v, %v", test.track, name, seqs, err)
+		}
+	}
+
+	if _, _, err := (Tracks{}).Lookup("open"); err == nil {
+		t.Errorf("looking up a track with none set up succeeded")
+	}
+}
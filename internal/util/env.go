@@ -0,0 +1,43 @@
+package util
+
+import (
+	"os"
+	"strconv"
+	"time"
+)
+
+func EnvInt(name string, fallback int) (int, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback, nil
+	}
+
+	return strconv.Atoi(value)
+}
+
+func EnvFloat(name string, fallback float64) (float64, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback, nil
+	}
+
+	return strconv.ParseFloat(value, 64)
+}
+
+func EnvDuration(name string, fallback time.Duration) (time.Duration, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback, nil
+	}
+
+	return time.ParseDuration(value)
+}
+
+func EnvLocation(name string) (*time.Location, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return time.Local, nil
+	}
+
+	return time.LoadLocation(value)
+}
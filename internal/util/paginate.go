@@ -0,0 +1,52 @@
+package util
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MessageLimit is the most characters Discord accepts in a message.
+const MessageLimit int = 2000
+
+// Paginate splits blocks across as few messages as fit within limit, keeping
+// each block whole and numbering the pages if there's more than one.
+func Paginate(header string, blocks []string, limit int) []string {
+	return paginateWith(header, blocks, "\n\n", limit)
+}
+
+// PaginateLines is like Paginate, but for lists with one item per line.
+func PaginateLines(header string, lines []string, limit int) []string {
+	return paginateWith(header, lines, "\n", limit)
+}
+
+func paginateWith(header string, blocks []string, separator string, limit int) []string {
+	pageHeader := func(page, total int) string {
+		if total == 1 {
+			return fmt.Sprintf("%v:", header)
+		}
+
+		return fmt.Sprintf("%v (%v/%v):", header, page, total)
+	}
+
+	// leave room for the longest possible page header
+	budget := limit - len(pageHeader(len(blocks), len(blocks))) - len(separator)
+
+	pages := [][]string{{}}
+	size := 0
+	for _, block := range blocks {
+		if size > 0 && size+len(block)+len(separator) > budget {
+			pages = append(pages, []string{})
+			size = 0
+		}
+
+		pages[len(pages)-1] = append(pages[len(pages)-1], block)
+		size += len(block) + len(separator)
+	}
+
+	messages := make([]string, 0, len(pages))
+	for i, page := range pages {
+		messages = append(messages, fmt.Sprintf("%v%v%v", pageHeader(i+1, len(pages)), separator, strings.Join(page, separator)))
+	}
+
+	return messages
+}
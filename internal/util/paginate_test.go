@@ -0,0 +1,73 @@
+package util
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPaginate(t *testing.T) {
+	tests := []struct {
+		name   string
+		blocks []string
+		limit  int
+		want   []string
+	}{
+		{"one page", []string{"a", "b"}, 100, []string{"Draw:\n\na\n\nb"}},
+		{
+			"split",
+			[]string{"aaaa", "bbbb", "cccc"},
+			24,
+			[]string{"Draw (1/3):\n\naaaa", "Draw (2/3):\n\nbbbb", "Draw (3/3):\n\ncccc"},
+		},
+		{
+			"blocks kept together",
+			[]string{"aa", "bb", "cccccc"},
+			28,
+			[]string{"Draw (1/2):\n\naa\n\nbb", "Draw (2/2):\n\ncccccc"},
+		},
+		{"block over the limit", []string{strings.Repeat("a", 30)}, 20, []string{"Draw:\n\n" + strings.Repeat("a", 30)}},
+	}
+
+	for _, test := range tests {
+		got := Paginate("Draw", test.blocks, test.limit)
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%v: got %q, want %q", test.name, got, test.want)
+		}
+
+		for _, page := range got {
+			if len(page) > test.limit && test.name != "block over the limit" {
+				t.Errorf("%v: page %q is over the limit of %v", test.name, page, test.limit)
+			}
+		}
+	}
+}
+
+func TestPaginateLines(t *testing.T) {
+	lines := make([]string, 0, 500)
+	for i := 0; i < 500; i++ {
+		lines = append(lines, "• Adjudicator With A Rather Long Name")
+	}
+
+	pages := PaginateLines("Not on Discord", lines, MessageLimit)
+	if len(pages) < 2 {
+		t.Fatalf("got %v pages, want the list split", len(pages))
+	}
+
+	listed := 0
+	for _, page := range pages {
+		if len(page) > MessageLimit {
+			t.Errorf("got a page of %v characters", len(page))
+		}
+
+		if strings.Contains(page, "\n\n") {
+			t.Errorf("got blank lines between items in %q", page)
+		}
+
+		listed += strings.Count(page, "•")
+	}
+
+	if listed != len(lines) {
+		t.Errorf("listed %v lines, want %v", listed, len(lines))
+	}
+}
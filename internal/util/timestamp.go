@@ -0,0 +1,11 @@
+package util
+
+import (
+	"fmt"
+	"time"
+)
+
+// DiscordTimestamp is shown by Discord in each reader's own timezone.
+func DiscordTimestamp(at time.Time) string {
+	return fmt.Sprintf("<t:%v:t>", at.Unix())
+}
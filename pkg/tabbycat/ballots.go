@@ -0,0 +1,273 @@
+package tabbycat
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const (
+	ResultStatusNone      string = "N"
+	ResultStatusDraft     string = "D"
+	ResultStatusConfirmed string = "C"
+	ResultStatusPostponed string = "P"
+)
+
+type Ballot struct {
+	Id        uint
+	DebateId  string
+	MotionId  string
+	Confirmed bool
+	Discarded bool
+	Version   uint
+	Timestamp time.Time
+	Sheets    []Sheet
+	Vetoes    []Veto
+}
+
+type Veto struct {
+	TeamId   string
+	MotionId string
+}
+
+type Sheet struct {
+	AdjudicatorId string
+	Teams         []TeamResult
+}
+
+type TeamResult struct {
+	TeamId   string
+	Side     string
+	Points   int
+	Win      bool
+	Score    float64
+	Speeches []SpeakerScore
+}
+
+type SpeakerScore struct {
+	SpeakerId string
+	Score     float64
+	Ghost     bool
+}
+
+type ballotResponse struct {
+	Id        uint
+	Motion    string
+	Confirmed bool
+	Discarded bool
+	Version   uint
+	Timestamp time.Time
+	Vetos     []struct {
+		Team   string
+		Motion string
+	}
+	Result struct {
+		Sheets []struct {
+			Adjudicator string
+			Teams       []struct {
+				Side     string
+				Points   int
+				Win      bool
+				Score    float64
+				Team     string
+				Speeches []struct {
+					Score   float64
+					Speaker string
+					Ghost   bool
+				}
+			}
+		}
+	}
+}
+
+func (t *Tabbycat) GetBallots(round uint64, debate string) ([]Ballot, error) {
+	return t.GetBallotsContext(context.Background(), round, debate)
+}
+
+func (t *Tabbycat) GetBallotsContext(ctx context.Context, round uint64, debate string) ([]Ballot, error) {
+	response, err := t.makeRequest(ctx, http.MethodGet, fmt.Sprintf("rounds/%v/pairings/%v/ballots", round, debate), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var responses []ballotResponse
+	if err := json.Unmarshal(response, &responses); err != nil {
+		return nil, err
+	}
+
+	ballots := make([]Ballot, 0, len(responses))
+	for _, response := range responses {
+		ballot, err := response.toBallot(debate)
+		if err != nil {
+			return nil, err
+		}
+
+		ballots = append(ballots, ballot)
+	}
+
+	return ballots, nil
+}
+
+func (t *Tabbycat) GetRoundBallots(round uint64) (map[string][]Ballot, error) {
+	return t.GetRoundBallotsContext(context.Background(), round)
+}
+
+func (t *Tabbycat) GetRoundBallotsContext(ctx context.Context, round uint64) (map[string][]Ballot, error) {
+	rooms, err := t.GetDrawContext(ctx, round)
+	if err != nil {
+		return nil, err
+	}
+
+	return t.ballotsForRooms(ctx, round, rooms)
+}
+
+func (t *Tabbycat) RoomsWithoutConfirmedBallot(round uint64) ([]Room, error) {
+	return t.RoomsWithoutConfirmedBallotContext(context.Background(), round)
+}
+
+func (t *Tabbycat) RoomsWithoutConfirmedBallotContext(ctx context.Context, round uint64) ([]Room, error) {
+	rooms, err := t.GetDrawContext(ctx, round)
+	if err != nil {
+		return nil, err
+	}
+
+	ballots, err := t.ballotsForRooms(ctx, round, rooms)
+	if err != nil {
+		return nil, err
+	}
+
+	missing := make([]Room, 0)
+	for _, room := range rooms {
+		if _, ok := ConfirmedBallot(ballots[room.Id]); !ok {
+			missing = append(missing, room)
+		}
+	}
+
+	return missing, nil
+}
+
+func ConfirmedBallot(ballots []Ballot) (Ballot, bool) {
+	for _, ballot := range ballots {
+		if ballot.Confirmed && !ballot.Discarded {
+			return ballot, true
+		}
+	}
+
+	return Ballot{}, false
+}
+
+// CountVetoes counts how many times each motion was vetoed, by motion ID,
+// using only the confirmed ballot from each room.
+func CountVetoes(ballots map[string][]Ballot) map[string]int {
+	vetoes := make(map[string]int)
+
+	for _, roomBallots := range ballots {
+		ballot, ok := ConfirmedBallot(roomBallots)
+		if !ok {
+			continue
+		}
+
+		for _, veto := range ballot.Vetoes {
+			vetoes[veto.MotionId] += 1
+		}
+	}
+
+	return vetoes
+}
+
+func (t *Tabbycat) ballotsForRooms(ctx context.Context, round uint64, rooms []Room) (map[string][]Ballot, error) {
+	ballots := make(map[string][]Ballot, len(rooms))
+
+	for _, room := range rooms {
+		roomBallots, err := t.GetBallotsContext(ctx, round, room.Id)
+		if err != nil {
+			return nil, err
+		}
+
+		ballots[room.Id] = roomBallots
+	}
+
+	return ballots, nil
+}
+
+func (b *ballotResponse) toBallot(debate string) (Ballot, error) {
+	ballot := Ballot{
+		Id:        b.Id,
+		DebateId:  debate,
+		Confirmed: b.Confirmed,
+		Discarded: b.Discarded,
+		Version:   b.Version,
+		Timestamp: b.Timestamp,
+		Sheets:    make([]Sheet, 0, len(b.Result.Sheets)),
+		Vetoes:    make([]Veto, 0, len(b.Vetos)),
+	}
+
+	if b.Motion != "" {
+		motionId, err := stripIdentifier(b.Motion)
+		if err != nil {
+			return Ballot{}, err
+		}
+
+		ballot.MotionId = motionId
+	}
+
+	for _, rawVeto := range b.Vetos {
+		teamId, err := stripIdentifier(rawVeto.Team)
+		if err != nil {
+			return Ballot{}, err
+		}
+
+		motionId, err := stripIdentifier(rawVeto.Motion)
+		if err != nil {
+			return Ballot{}, err
+		}
+
+		ballot.Vetoes = append(ballot.Vetoes, Veto{teamId, motionId})
+	}
+
+	for _, rawSheet := range b.Result.Sheets {
+		sheet := Sheet{Teams: make([]TeamResult, 0, len(rawSheet.Teams))}
+
+		if rawSheet.Adjudicator != "" {
+			adjudicatorId, err := stripIdentifier(rawSheet.Adjudicator)
+			if err != nil {
+				return Ballot{}, err
+			}
+
+			sheet.AdjudicatorId = adjudicatorId
+		}
+
+		for _, rawTeam := range rawSheet.Teams {
+			teamId, err := stripIdentifier(rawTeam.Team)
+			if err != nil {
+				return Ballot{}, err
+			}
+
+			team := TeamResult{
+				TeamId:   teamId,
+				Side:     rawTeam.Side,
+				Points:   rawTeam.Points,
+				Win:      rawTeam.Win,
+				Score:    rawTeam.Score,
+				Speeches: make([]SpeakerScore, 0, len(rawTeam.Speeches)),
+			}
+
+			for _, speech := range rawTeam.Speeches {
+				speakerId, err := stripIdentifier(speech.Speaker)
+				if err != nil {
+					return Ballot{}, err
+				}
+
+				team.Speeches = append(team.Speeches, SpeakerScore{speakerId, speech.Score, speech.Ghost})
+			}
+
+			sheet.Teams = append(sheet.Teams, team)
+		}
+
+		ballot.Sheets = append(ballot.Sheets, sheet)
+	}
+
+	return ballot, nil
+}
@@ -0,0 +1,143 @@
+package tabbycat
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+var (
+	ErrUnauthorised = errors.New("unauthorised")
+	ErrNotFound     = errors.New("not found")
+	ErrValidation   = errors.New("validation error")
+	ErrRateLimited  = errors.New("rate limited")
+	ErrServer       = errors.New("server error")
+)
+
+type Error struct {
+	Method     string
+	Path       string
+	StatusCode int
+	Detail     string
+	Fields     map[string][]string
+	kind       error
+}
+
+func newError(method string, path string, statusCode int, body []byte) *Error {
+	e := &Error{
+		Method:     method,
+		Path:       path,
+		StatusCode: statusCode,
+		Fields:     make(map[string][]string),
+	}
+
+	switch {
+	case statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden:
+		e.kind = ErrUnauthorised
+	case statusCode == http.StatusNotFound:
+		e.kind = ErrNotFound
+	case statusCode == http.StatusBadRequest:
+		e.kind = ErrValidation
+	case statusCode == http.StatusTooManyRequests:
+		e.kind = ErrRateLimited
+	case statusCode >= 500:
+		e.kind = ErrServer
+	}
+
+	var raw map[string]interface{}
+	if err := json.Unmarshal(body, &raw); err != nil {
+		e.Detail = strings.TrimSpace(string(body))
+		return e
+	}
+
+	for field, value := range raw {
+		if field == "detail" {
+			e.Detail = fmt.Sprint(value)
+			continue
+		}
+
+		e.Fields[field] = flattenMessages(value)
+	}
+
+	return e
+}
+
+func (e *Error) Error() string {
+	message := fmt.Sprintf("tabbycat: %v %v: %v %v", e.Method, e.Path, e.StatusCode, http.StatusText(e.StatusCode))
+
+	if summary := e.Summary(); summary != "" {
+		message = fmt.Sprintf("%v: %v", message, summary)
+	}
+
+	return message
+}
+
+func (e *Error) Unwrap() error {
+	return e.kind
+}
+
+func (e *Error) Summary() string {
+	parts := make([]string, 0, len(e.Fields)+1)
+
+	if e.Detail != "" {
+		parts = append(parts, e.Detail)
+	}
+
+	fields := make([]string, 0, len(e.Fields))
+	for field := range e.Fields {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	for _, field := range fields {
+		parts = append(parts, fmt.Sprintf("%v: %v", field, strings.Join(e.Fields[field], " ")))
+	}
+
+	return strings.Join(parts, "; ")
+}
+
+type BarcodeFailure struct {
+	Participant Participant
+	Err         error
+}
+
+type BarcodeError struct {
+	Failures []BarcodeFailure
+}
+
+func (e *BarcodeError) Error() string {
+	descriptions := make([]string, 0, len(e.Failures))
+
+	for _, failure := range e.Failures {
+		descriptions = append(descriptions, fmt.Sprintf("%v (%v): %v", failure.Participant.Name, failure.Participant.Id, failure.Err))
+	}
+
+	return fmt.Sprintf("could not fetch %v barcodes: %v", len(e.Failures), strings.Join(descriptions, "; "))
+}
+
+func flattenMessages(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		messages := make([]string, 0, len(v))
+		for _, item := range v {
+			messages = append(messages, flattenMessages(item)...)
+		}
+		return messages
+	case map[string]interface{}:
+		messages := make([]string, 0, len(v))
+		for field, item := range v {
+			for _, message := range flattenMessages(item) {
+				messages = append(messages, fmt.Sprintf("%v: %v", field, message))
+			}
+		}
+		sort.Strings(messages)
+		return messages
+	default:
+		return []string{fmt.Sprint(v)}
+	}
+}
@@ -2,40 +2,65 @@ package tabbycat
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"io/ioutil"
 	"net/http"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
+const (
+	defaultRetries     int           = 3
+	defaultBackoff     time.Duration = time.Second
+	defaultConcurrency int           = 4
+	defaultTimeout     time.Duration = time.Second * 30
+	maxRetryDelay      time.Duration = time.Minute
+)
+
 var identifierStripper *regexp.Regexp = regexp.MustCompile(`/(\d+)$`)
 
 type Tabbycat struct {
-	apiKey      string
-	client      *http.Client
-	endpoint    string
-	privateUrls string
+	apiKey         string
+	client         *http.Client
+	api            string
+	endpoint       string
+	privateUrls    string
+	retries        int
+	backoff        time.Duration
+	concurrency    int
+	rateLimit      float64
+	requestTimeout time.Duration
+	location       *time.Location
 }
 
 type Team struct {
-	Id       uint          `json:"id"`
-	Emoji    string        `json:"emoji"`
-	Speakers []Participant `json:"speakers"`
+	Id          uint          `json:"id"`
+	Reference   string        `json:"reference"`
+	ShortName   string        `json:"short_name"`
+	Emoji       string        `json:"emoji"`
+	Institution string        `json:"institution"`
+	Speakers    []Participant `json:"speakers"`
 }
 
 type Participant struct {
-	Email   string `json:"email"`
-	Id      uint   `json:"id"`
-	Name    string `json:"name"`
-	Barcode string
-	UrlKey  string `json:"url_key"`
+	Email       string `json:"email"`
+	Id          uint   `json:"id"`
+	Name        string `json:"name"`
+	Barcode     string
+	CheckedIn   bool
+	UrlKey      string `json:"url_key"`
+	Institution string `json:"institution"`
 }
 
 type Room struct {
+	Id           string
+	ResultStatus string
 	VenueId      string
 	ChairId      string
 	TeamIds      []string
@@ -45,22 +70,28 @@ type Room struct {
 }
 
 type Round struct {
-	Id     string
-	Name   string
-	Motion Motion
+	Id      string
+	Name    string
+	Motions []Motion
 }
 
 type Venue struct {
-	Id   uint
-	Name string
+	Id         uint
+	Name       string
+	Categories []string
 }
 
 type Motion struct {
+	Id        string `json:"-"`
+	Seq       uint
+	Reference string
 	InfoSlide string `json:"info_slide"`
 	Motion    string `json:"text"`
 }
 
 type teamResponse struct {
+	Url          string
+	ResultStatus string `json:"result_status"`
 	Adjudicators struct {
 		Chair      string
 		Panellists []string
@@ -76,30 +107,83 @@ type teamResponse struct {
 type roundResponse struct {
 	Url     string
 	Name    string
-	Motions []Motion
+	Motions []struct {
+		Motion
+		Url string
+	}
 }
 
 func New(apiKey string, url string, slug string) *Tabbycat {
 	return &Tabbycat{
 		apiKey:      apiKey,
-		client:      &http.Client{},
+		client:      &http.Client{Timeout: defaultTimeout},
+		api:         fmt.Sprintf("%v/api/v1/", url),
 		endpoint:    fmt.Sprintf("%v/api/v1/tournaments/%v/", url, slug),
 		privateUrls: fmt.Sprintf("%v/%v/privateurls/", url, slug),
+		retries:     defaultRetries,
+		backoff:     defaultBackoff,
+		concurrency: defaultConcurrency,
+		location:    time.Local,
 	}
 }
 
+func (t *Tabbycat) SetRetries(retries int, backoff time.Duration) {
+	t.retries = retries
+	t.backoff = backoff
+}
+
+func (t *Tabbycat) SetTimeout(timeout time.Duration) {
+	t.client.Timeout = timeout
+}
+
+func (t *Tabbycat) SetRequestTimeout(timeout time.Duration) {
+	t.requestTimeout = timeout
+}
+
+func (t *Tabbycat) SetConcurrency(concurrency int) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	t.concurrency = concurrency
+}
+
+func (t *Tabbycat) SetRateLimit(requestsPerSecond float64) {
+	t.rateLimit = requestsPerSecond
+}
+
+// SetLocation should match the TIME_ZONE setting of the Tabbycat server.
+func (t *Tabbycat) SetLocation(location *time.Location) {
+	t.location = location
+}
+
 func (t *Tabbycat) GetAdjudicators() ([]Participant, error) {
-	response, err := t.makeRequest(http.MethodGet, "adjudicators", nil)
+	return t.GetAdjudicatorsContext(context.Background())
+}
+
+func (t *Tabbycat) GetAdjudicatorsContext(ctx context.Context) ([]Participant, error) {
+	adjudicators, err := t.ListAdjudicatorsContext(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	var adjudicators []Participant
-	if err := json.Unmarshal(response, &adjudicators); err != nil {
+	return adjudicators, t.GetBarcodesContext(ctx, false, adjudicators)
+}
+
+// ListAdjudicators and ListTeams leave out barcodes and check-ins, which take a
+// request per participant to fetch.
+func (t *Tabbycat) ListAdjudicators() ([]Participant, error) {
+	return t.ListAdjudicatorsContext(context.Background())
+}
+
+func (t *Tabbycat) ListAdjudicatorsContext(ctx context.Context) ([]Participant, error) {
+	response, err := t.makeRequest(ctx, http.MethodGet, "adjudicators", nil)
+	if err != nil {
 		return nil, err
 	}
 
-	if err := t.GetBarcodes(false, adjudicators); err != nil {
+	var adjudicators []Participant
+	if err := json.Unmarshal(response, &adjudicators); err != nil {
 		return nil, err
 	}
 
@@ -107,50 +191,67 @@ func (t *Tabbycat) GetAdjudicators() ([]Participant, error) {
 }
 
 func (t *Tabbycat) GetTeams() ([]Team, error) {
-	response, err := t.makeRequest(http.MethodGet, "teams", nil)
+	return t.GetTeamsContext(context.Background())
+}
+
+func (t *Tabbycat) GetTeamsContext(ctx context.Context) ([]Team, error) {
+	teams, err := t.ListTeamsContext(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	var teams []Team
-	if err := json.Unmarshal(response, &teams); err != nil {
+	speakers := make([]*Participant, 0, len(teams)*2)
+	for i := range teams {
+		for j := range teams[i].Speakers {
+			speakers = append(speakers, &teams[i].Speakers[j])
+		}
+	}
+
+	return teams, t.fetchBarcodes(ctx, "speakers", speakers)
+}
+
+func (t *Tabbycat) ListTeams() ([]Team, error) {
+	return t.ListTeamsContext(context.Background())
+}
+
+func (t *Tabbycat) ListTeamsContext(ctx context.Context) ([]Team, error) {
+	response, err := t.makeRequest(ctx, http.MethodGet, "teams", nil)
+	if err != nil {
 		return nil, err
 	}
 
-	for i := range teams {
-		if err := t.GetBarcodes(true, teams[i].Speakers); err != nil {
-			return nil, err
-		}
+	var teams []Team
+	if err := json.Unmarshal(response, &teams); err != nil {
+		return nil, err
 	}
 
 	return teams, nil
 }
 
 func (t *Tabbycat) GetBarcodes(speakers bool, participants []Participant) error {
+	return t.GetBarcodesContext(context.Background(), speakers, participants)
+}
+
+func (t *Tabbycat) GetBarcodesContext(ctx context.Context, speakers bool, participants []Participant) error {
 	category := "adjudicators"
 	if speakers {
 		category = "speakers"
 	}
 
+	pointers := make([]*Participant, 0, len(participants))
 	for i := range participants {
-		raw, err := t.makeRequest(http.MethodGet, fmt.Sprintf("%v/%v/checkin", category, participants[i].Id), nil)
-		if err != nil {
-			return err
-		}
-
-		var barcode struct{ Barcode string }
-		if err := json.Unmarshal(raw, &barcode); err != nil {
-			return err
-		}
-
-		participants[i].Barcode = barcode.Barcode
+		pointers = append(pointers, &participants[i])
 	}
 
-	return nil
+	return t.fetchBarcodes(ctx, category, pointers)
 }
 
 func (t *Tabbycat) GetRounds() ([]Round, error) {
-	response, err := t.makeRequest(http.MethodGet, "rounds", nil)
+	return t.GetRoundsContext(context.Background())
+}
+
+func (t *Tabbycat) GetRoundsContext(ctx context.Context) ([]Round, error) {
+	response, err := t.makeRequest(ctx, http.MethodGet, "rounds", nil)
 	if err != nil {
 		return nil, err
 	}
@@ -174,7 +275,11 @@ func (t *Tabbycat) GetRounds() ([]Round, error) {
 }
 
 func (t *Tabbycat) GetRound(round uint64) (Round, error) {
-	response, err := t.makeRequest(http.MethodGet, fmt.Sprintf("rounds/%v", round), nil)
+	return t.GetRoundContext(context.Background(), round)
+}
+
+func (t *Tabbycat) GetRoundContext(ctx context.Context, round uint64) (Round, error) {
+	response, err := t.makeRequest(ctx, http.MethodGet, fmt.Sprintf("rounds/%v", round), nil)
 	if err != nil {
 		return Round{}, err
 	}
@@ -188,8 +293,12 @@ func (t *Tabbycat) GetRound(round uint64) (Round, error) {
 }
 
 func (t *Tabbycat) ReleaseMotion(round uint64, starts time.Time) error {
+	return t.ReleaseMotionContext(context.Background(), round, starts)
+}
+
+func (t *Tabbycat) ReleaseMotionContext(ctx context.Context, round uint64, starts time.Time) error {
 	path := fmt.Sprintf("rounds/%v", round)
-	response, err := t.makeRequest(http.MethodGet, path, nil)
+	response, err := t.makeRequest(ctx, http.MethodGet, path, nil)
 	if err != nil {
 		return err
 	}
@@ -200,19 +309,23 @@ func (t *Tabbycat) ReleaseMotion(round uint64, starts time.Time) error {
 	}
 
 	roundObject["motions_released"] = true
-	roundObject["starts_at"] = starts.Format("15:04:05")
+	roundObject["starts_at"] = starts.In(t.location).Format("15:04:05")
 
 	serialized, err := json.Marshal(roundObject)
 	if err != nil {
 		return err
 	}
 
-	_, err = t.makeRequest(http.MethodPost, path, bytes.NewReader(serialized))
+	_, err = t.makeRequest(ctx, http.MethodPost, path, serialized)
 	return err
 }
 
 func (t *Tabbycat) GetDraw(round uint64) ([]Room, error) {
-	response, err := t.makeRequest(http.MethodGet, fmt.Sprintf("rounds/%v/pairings", round), nil)
+	return t.GetDrawContext(context.Background(), round)
+}
+
+func (t *Tabbycat) GetDrawContext(ctx context.Context, round uint64) ([]Room, error) {
+	response, err := t.makeRequest(ctx, http.MethodGet, fmt.Sprintf("rounds/%v/pairings", round), nil)
 	if err != nil {
 		return nil, err
 	}
@@ -224,6 +337,11 @@ func (t *Tabbycat) GetDraw(round uint64) ([]Room, error) {
 
 	rooms := make([]Room, 0, len(data))
 	for _, datum := range data {
+		id, err := stripIdentifier(datum.Url)
+		if err != nil {
+			return nil, err
+		}
+
 		venueId, err := stripIdentifier(datum.Venue)
 		if err != nil {
 			return nil, err
@@ -257,6 +375,8 @@ func (t *Tabbycat) GetDraw(round uint64) ([]Room, error) {
 		}
 
 		rooms = append(rooms, Room{
+			Id:           id,
+			ResultStatus: datum.ResultStatus,
 			VenueId:      venueId,
 			ChairId:      chairId,
 			TeamIds:      teamIds,
@@ -269,8 +389,88 @@ func (t *Tabbycat) GetDraw(round uint64) ([]Room, error) {
 	return rooms, nil
 }
 
+func (t *Tabbycat) GetTeamNames() (map[string]string, error) {
+	return t.GetTeamNamesContext(context.Background())
+}
+
+func (t *Tabbycat) GetTeamNamesContext(ctx context.Context) (map[string]string, error) {
+	response, err := t.makeRequest(ctx, http.MethodGet, "teams", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var teams []Team
+	if err := json.Unmarshal(response, &teams); err != nil {
+		return nil, err
+	}
+
+	names := make(map[string]string, len(teams))
+	for _, team := range teams {
+		name := team.ShortName
+		if name == "" {
+			name = team.Reference
+		}
+
+		names[strconv.FormatUint(uint64(team.Id), 10)] = name
+	}
+
+	return names, nil
+}
+
+// GetTeamReferences is GetTeamNames without short names, for matching teams
+// by the reference they were created with.
+func (t *Tabbycat) GetTeamReferences() (map[string]string, error) {
+	return t.GetTeamReferencesContext(context.Background())
+}
+
+func (t *Tabbycat) GetTeamReferencesContext(ctx context.Context) (map[string]string, error) {
+	response, err := t.makeRequest(ctx, http.MethodGet, "teams", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var teams []Team
+	if err := json.Unmarshal(response, &teams); err != nil {
+		return nil, err
+	}
+
+	references := make(map[string]string, len(teams))
+	for _, team := range teams {
+		references[strconv.FormatUint(uint64(team.Id), 10)] = team.Reference
+	}
+
+	return references, nil
+}
+
+func (t *Tabbycat) GetAdjudicatorNames() (map[string]string, error) {
+	return t.GetAdjudicatorNamesContext(context.Background())
+}
+
+func (t *Tabbycat) GetAdjudicatorNamesContext(ctx context.Context) (map[string]string, error) {
+	response, err := t.makeRequest(ctx, http.MethodGet, "adjudicators", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var adjudicators []Participant
+	if err := json.Unmarshal(response, &adjudicators); err != nil {
+		return nil, err
+	}
+
+	names := make(map[string]string, len(adjudicators))
+	for _, adjudicator := range adjudicators {
+		names[strconv.FormatUint(uint64(adjudicator.Id), 10)] = adjudicator.Name
+	}
+
+	return names, nil
+}
+
 func (t *Tabbycat) GetVenues() ([]Venue, error) {
-	response, err := t.makeRequest(http.MethodGet, "venues", nil)
+	return t.GetVenuesContext(context.Background())
+}
+
+func (t *Tabbycat) GetVenuesContext(ctx context.Context) ([]Venue, error) {
+	response, err := t.makeRequest(ctx, http.MethodGet, "venues", nil)
 	if err != nil {
 		return nil, err
 	}
@@ -284,17 +484,25 @@ func (t *Tabbycat) GetVenues() ([]Venue, error) {
 }
 
 func (t *Tabbycat) CheckOutAdjudicator(id uint) error {
-	_, err := t.makeRequest(http.MethodDelete, fmt.Sprintf("adjudicators/%v/checkin", id), nil)
+	return t.CheckOutAdjudicatorContext(context.Background(), id)
+}
+
+func (t *Tabbycat) CheckOutAdjudicatorContext(ctx context.Context, id uint) error {
+	_, err := t.makeRequest(ctx, http.MethodDelete, fmt.Sprintf("adjudicators/%v/checkin", id), nil)
 	return err
 }
 
 func (t *Tabbycat) CheckIn(id uint, speaker bool) error {
+	return t.CheckInContext(context.Background(), id, speaker)
+}
+
+func (t *Tabbycat) CheckInContext(ctx context.Context, id uint, speaker bool) error {
 	category := "adjudicators"
 	if speaker {
 		category = "speakers"
 	}
 
-	_, err := t.makeRequest(http.MethodPut, fmt.Sprintf("%v/%v/checkin", category, id), nil)
+	_, err := t.makeRequest(ctx, http.MethodPut, fmt.Sprintf("%v/%v/checkin", category, id), nil)
 	return err
 }
 
@@ -302,26 +510,162 @@ func (t *Tabbycat) PrivateUrlFromKey(urlKey string) string {
 	return fmt.Sprintf("%v%v/", t.privateUrls, urlKey)
 }
 
-func (t *Tabbycat) makeRequest(method string, url string, body io.Reader) ([]byte, error) {
-	req, err := http.NewRequest(method, t.endpoint+url, body)
+func (t *Tabbycat) fetchBarcodes(ctx context.Context, category string, participants []*Participant) error {
+	jobs := make(chan int)
+	errs := make([]error, len(participants))
+	wg := sync.WaitGroup{}
+
+	var throttle <-chan time.Time
+	if t.rateLimit > 0 {
+		ticker := time.NewTicker(time.Duration(float64(time.Second) / t.rateLimit))
+		defer ticker.Stop()
+		throttle = ticker.C
+	}
+
+	for worker := 0; worker < t.concurrency; worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for i := range jobs {
+				if throttle != nil {
+					select {
+					case <-throttle:
+					case <-ctx.Done():
+						errs[i] = ctx.Err()
+						continue
+					}
+				}
+
+				errs[i] = t.fetchBarcode(ctx, category, participants[i])
+			}
+		}()
+	}
+
+	for i := range participants {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	failures := make([]BarcodeFailure, 0)
+	for i, err := range errs {
+		if err != nil {
+			failures = append(failures, BarcodeFailure{*participants[i], err})
+		}
+	}
+
+	if len(failures) > 0 {
+		return &BarcodeError{failures}
+	}
+
+	return nil
+}
+
+func (t *Tabbycat) fetchBarcode(ctx context.Context, category string, participant *Participant) error {
+	raw, err := t.makeRequest(ctx, http.MethodGet, fmt.Sprintf("%v/%v/checkin", category, participant.Id), nil)
 	if err != nil {
-		return nil, err
+		return err
+	}
+
+	var checkin struct {
+		Barcode string
+		Checked bool
+	}
+	if err := json.Unmarshal(raw, &checkin); err != nil {
+		return err
+	}
+
+	participant.Barcode = checkin.Barcode
+	participant.CheckedIn = checkin.Checked
+	return nil
+}
+
+func (t *Tabbycat) makeRequest(ctx context.Context, method string, url string, body []byte) ([]byte, error) {
+	return t.makeRequestTo(ctx, t.endpoint, method, url, body)
+}
+
+func (t *Tabbycat) makeApiRequest(ctx context.Context, method string, url string, body []byte) ([]byte, error) {
+	return t.makeRequestTo(ctx, t.api, method, url, body)
+}
+
+func (t *Tabbycat) makeRequestTo(ctx context.Context, base string, method string, url string, body []byte) ([]byte, error) {
+	for attempt := 0; ; attempt++ {
+		response, statusCode, retryAfter, err := t.doRequest(ctx, method, base+url, body)
+		if err != nil {
+			return nil, err
+		}
+
+		if statusCode >= 200 && statusCode < 300 {
+			return response, nil
+		}
+
+		// A server error may come after Tabbycat has already made a change, so
+		// only requests that are safe to repeat are retried.
+		transient := statusCode == http.StatusTooManyRequests || (statusCode >= 500 && idempotent(method))
+		if !transient || attempt >= t.retries {
+			return nil, newError(method, url, statusCode, response)
+		}
+
+		select {
+		case <-time.After(t.retryDelay(attempt, retryAfter)):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func (t *Tabbycat) doRequest(ctx context.Context, method string, url string, body []byte) ([]byte, int, string, error) {
+	if t.requestTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, t.requestTimeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, 0, "", err
 	}
 
 	req.Header.Add("Authorization", fmt.Sprintf("Token %v", t.apiKey))
 
-	if method == http.MethodPost {
+	if len(body) > 0 {
 		req.Header.Add("Content-Type", "application/json")
 	}
 
 	resp, err := t.client.Do(req)
 	if err != nil {
-		return nil, err
+		return nil, 0, "", err
 	}
-
 	defer resp.Body.Close()
 
-	return ioutil.ReadAll(resp.Body)
+	response, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, 0, "", err
+	}
+
+	return response, resp.StatusCode, resp.Header.Get("Retry-After"), nil
+}
+
+func (t *Tabbycat) retryDelay(attempt int, retryAfter string) time.Duration {
+	delay := t.backoff * time.Duration(1<<uint(attempt))
+	if seconds, err := strconv.Atoi(retryAfter); err == nil && seconds >= 0 {
+		delay = time.Duration(seconds) * time.Second
+	}
+
+	if delay > maxRetryDelay {
+		return maxRetryDelay
+	}
+
+	return delay
+}
+
+func idempotent(method string) bool {
+	return method == http.MethodGet || method == http.MethodPut || method == http.MethodDelete
 }
 
 func stripIdentifier(url string) (string, error) {
@@ -355,10 +699,42 @@ func (r *roundResponse) toRound() (Round, error) {
 		return Round{}, err
 	}
 
-	var motion Motion
-	if len(r.Motions) > 0 {
-		motion = r.Motions[0]
+	motions := make([]Motion, 0, len(r.Motions))
+	for _, response := range r.Motions {
+		motion := response.Motion
+
+		if response.Url != "" {
+			if motion.Id, err = stripIdentifier(response.Url); err != nil {
+				return Round{}, err
+			}
+		}
+
+		motions = append(motions, motion)
+	}
+
+	sort.SliceStable(motions, func(i, j int) bool {
+		return motions[i].Seq < motions[j].Seq
+	})
+
+	return Round{id, r.Name, motions}, nil
+}
+
+// MotionsWithSeqs picks out the motions with the given sequence numbers, or
+// returns every motion if there are none.
+func (r Round) MotionsWithSeqs(seqs []uint) []Motion {
+	if len(seqs) == 0 {
+		return r.Motions
+	}
+
+	motions := make([]Motion, 0, len(seqs))
+	for _, motion := range r.Motions {
+		for _, seq := range seqs {
+			if motion.Seq == seq {
+				motions = append(motions, motion)
+				break
+			}
+		}
 	}
 
-	return Round{id, r.Name, motion}, nil
+	return motions
 }
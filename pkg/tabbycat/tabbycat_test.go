@@ -0,0 +1,419 @@
+package tabbycat_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hitecherik/Tabulatron/pkg/tabbycat"
+	"github.com/hitecherik/Tabulatron/pkg/tabbycat/tabbycattest"
+)
+
+func newTabbycat(t *testing.T) (*tabbycat.Tabbycat, *tabbycattest.Server) {
+	t.Helper()
+
+	fake, err := tabbycattest.New("example", "tabbycattest/fixtures")
+	if err != nil {
+		t.Fatalf("loading fixtures: %v", err)
+	}
+	fake.SetApiKey("key")
+
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	return tabbycat.New("key", server.URL, "example"), fake
+}
+
+func TestGetTeams(t *testing.T) {
+	client, _ := newTabbycat(t)
+
+	teams, err := client.GetTeams()
+	if err != nil {
+		t.Fatalf("GetTeams: %v", err)
+	}
+
+	if len(teams) != 4 {
+		t.Fatalf("got %v teams, want 4", len(teams))
+	}
+
+	first := teams[0]
+	if first.Id != 1 || first.Emoji != "🐙" || len(first.Speakers) != 2 {
+		t.Errorf("got team %+v", first)
+	}
+
+	ada := first.Speakers[0]
+	if ada.Name != "Ada Lovelace" || ada.Barcode != "100001" || !ada.CheckedIn {
+		t.Errorf("got speaker %+v", ada)
+	}
+
+	if alan := first.Speakers[1]; alan.Barcode == "" || alan.CheckedIn {
+		t.Errorf("got speaker %+v", alan)
+	}
+}
+
+func TestGetDraw(t *testing.T) {
+	client, _ := newTabbycat(t)
+
+	rooms, err := client.GetDraw(1)
+	if err != nil {
+		t.Fatalf("GetDraw: %v", err)
+	}
+
+	if len(rooms) != 1 {
+		t.Fatalf("got %v rooms, want 1", len(rooms))
+	}
+
+	room := rooms[0]
+	want := []string{"1", "2", "3", "4"}
+	if len(room.TeamIds) != len(want) {
+		t.Fatalf("got teams %v, want %v", room.TeamIds, want)
+	}
+
+	for i := range want {
+		if room.TeamIds[i] != want[i] {
+			t.Errorf("got teams %v, want %v", room.TeamIds, want)
+		}
+	}
+
+	if room.VenueId != "1" || room.ChairId != "9" || len(room.PanellistIds) != 1 || len(room.TraineeIds) != 1 {
+		t.Errorf("got room %+v", room)
+	}
+}
+
+func TestGetDrawMissingRound(t *testing.T) {
+	client, _ := newTabbycat(t)
+
+	if _, err := client.GetDraw(99); !errors.Is(err, tabbycat.ErrNotFound) {
+		t.Errorf("got %v, want ErrNotFound", err)
+	}
+}
+
+func TestMotionsWithSeqs(t *testing.T) {
+	client, _ := newTabbycat(t)
+
+	round, err := client.GetRound(2)
+	if err != nil {
+		t.Fatalf("GetRound: %v", err)
+	}
+
+	if motions := round.MotionsWithSeqs(nil); len(motions) != 2 {
+		t.Errorf("got %v motions without a track, want both", len(motions))
+	}
+
+	motions := round.MotionsWithSeqs([]uint{2})
+	if len(motions) != 1 || motions[0].Motion != "This House would example novice motion 2" {
+		t.Errorf("got motions %+v for seq 2", motions)
+	}
+
+	if motions := round.MotionsWithSeqs([]uint{3}); len(motions) != 0 {
+		t.Errorf("got motions %+v for a seq the round doesn't have", motions)
+	}
+}
+
+func TestVetoes(t *testing.T) {
+	client, _ := newTabbycat(t)
+
+	round, err := client.GetRound(1)
+	if err != nil {
+		t.Fatalf("GetRound: %v", err)
+	}
+
+	if len(round.Motions) != 1 || round.Motions[0].Id != "1" {
+		t.Fatalf("got motions %+v", round.Motions)
+	}
+
+	ballots, err := client.GetRoundBallots(1)
+	if err != nil {
+		t.Fatalf("GetRoundBallots: %v", err)
+	}
+
+	if vetoes := ballots["1"][0].Vetoes; len(vetoes) != 1 || vetoes[0] != (tabbycat.Veto{TeamId: "2", MotionId: "1"}) {
+		t.Errorf("got vetoes %+v", vetoes)
+	}
+
+	if counts := tabbycat.CountVetoes(ballots); counts["1"] != 1 || len(counts) != 1 {
+		t.Errorf("got veto counts %v", counts)
+	}
+}
+
+func TestUnauthorised(t *testing.T) {
+	_, fake := newTabbycat(t)
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	if _, err := tabbycat.New("wrong", server.URL, "example").GetVenues(); !errors.Is(err, tabbycat.ErrUnauthorised) {
+		t.Errorf("got %v, want ErrUnauthorised", err)
+	}
+}
+
+func TestCheckInAndOut(t *testing.T) {
+	client, fake := newTabbycat(t)
+
+	if err := client.CheckIn(2, true); err != nil {
+		t.Fatalf("CheckIn: %v", err)
+	}
+
+	if err := client.CheckIn(9, false); err != nil {
+		t.Fatalf("CheckIn: %v", err)
+	}
+
+	if got := len(fake.RequestsTo("PUT", "speakers/2/checkin")); got != 1 {
+		t.Errorf("got %v check-ins for speaker 2, want 1", got)
+	}
+
+	if !checkedIn(t, client, true, 2) || !checkedIn(t, client, false, 9) {
+		t.Errorf("participants weren't checked in")
+	}
+
+	if err := client.CheckOutAdjudicator(9); err != nil {
+		t.Fatalf("CheckOutAdjudicator: %v", err)
+	}
+
+	if got := len(fake.RequestsTo("DELETE", "adjudicators/9/checkin")); got != 1 {
+		t.Errorf("got %v check-outs for adjudicator 9, want 1", got)
+	}
+
+	if checkedIn(t, client, false, 9) {
+		t.Errorf("adjudicator 9 is still checked in")
+	}
+}
+
+func TestCreateAndDeleteTeam(t *testing.T) {
+	client, fake := newTabbycat(t)
+
+	team, err := client.CreateTeam(tabbycat.TeamDetails{
+		Reference: "Team 5",
+		Speakers:  []tabbycat.SpeakerDetails{{Name: "Kathleen Booth"}, {Name: "John Backus"}},
+	})
+	if err != nil {
+		t.Fatalf("CreateTeam: %v", err)
+	}
+
+	requests := fake.RequestsTo("POST", "teams")
+	if len(requests) != 1 {
+		t.Fatalf("got %v POSTs to teams, want 1", len(requests))
+	}
+
+	var sent tabbycat.TeamDetails
+	if err := json.Unmarshal(requests[0].Body, &sent); err != nil || sent.Reference != "Team 5" || len(sent.Speakers) != 2 {
+		t.Errorf("sent %+v (%v)", sent, err)
+	}
+
+	if team.Id == 0 || len(team.Speakers) != 2 {
+		t.Errorf("got team %+v", team)
+	}
+
+	if names, err := client.GetTeamNames(); err != nil || len(names) != 5 {
+		t.Errorf("got %v team names (%v), want 5", len(names), err)
+	}
+
+	if err := client.DeleteTeam(team.Id); err != nil {
+		t.Fatalf("DeleteTeam: %v", err)
+	}
+
+	if names, err := client.GetTeamNames(); err != nil || len(names) != 4 {
+		t.Errorf("got %v team names (%v) after deleting, want 4", len(names), err)
+	}
+
+	if err := client.DeleteTeam(team.Id); !errors.Is(err, tabbycat.ErrNotFound) {
+		t.Errorf("deleting twice got %v, want ErrNotFound", err)
+	}
+}
+
+func TestRetries(t *testing.T) {
+	attempts := make(map[string]int)
+	var mutex sync.Mutex
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		attempts[r.Method] += 1
+		mutex.Unlock()
+
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	client := tabbycat.New("", server.URL, "example")
+	client.SetRetries(2, time.Millisecond)
+
+	if _, err := client.GetVenues(); !errors.Is(err, tabbycat.ErrServer) {
+		t.Errorf("GetVenues got %v, want ErrServer", err)
+	}
+
+	if _, err := client.CreateTeam(tabbycat.TeamDetails{Reference: "Team 5"}); !errors.Is(err, tabbycat.ErrServer) {
+		t.Errorf("CreateTeam got %v, want ErrServer", err)
+	}
+
+	if attempts["GET"] != 3 {
+		t.Errorf("got %v GETs, want 3", attempts["GET"])
+	}
+
+	if attempts["POST"] != 1 {
+		t.Errorf("got %v POSTs, want 1 because creating isn't safe to repeat", attempts["POST"])
+	}
+}
+
+func checkedIn(t *testing.T, client *tabbycat.Tabbycat, speaker bool, id uint) bool {
+	t.Helper()
+
+	participants := []tabbycat.Participant{{Id: id}}
+	if err := client.GetBarcodes(speaker, participants); err != nil {
+		t.Fatalf("GetBarcodes: %v", err)
+	}
+
+	return participants[0].CheckedIn
+}
+
+func TestErrorParsing(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		kind    error
+		detail  string
+		fields  map[string][]string
+		summary string
+	}{
+		{
+			"fields",
+			http.StatusBadRequest,
+			`{"reference": ["This field is required."], "speakers": [{"name": ["Ensure this field has no more than 70 characters."]}]}`,
+			tabbycat.ErrValidation,
+			"",
+			map[string][]string{
+				"reference": {"This field is required."},
+				"speakers":  {"name: Ensure this field has no more than 70 characters."},
+			},
+			"reference: This field is required.; speakers: name: Ensure this field has no more than 70 characters.",
+		},
+		{
+			"detail",
+			http.StatusForbidden,
+			`{"detail": "You do not have permission to perform this action."}`,
+			tabbycat.ErrUnauthorised,
+			"You do not have permission to perform this action.",
+			map[string][]string{},
+			"You do not have permission to perform this action.",
+		},
+		{
+			"not JSON",
+			http.StatusServiceUnavailable,
+			"upstream unavailable",
+			tabbycat.ErrServer,
+			"upstream unavailable",
+			map[string][]string{},
+			"upstream unavailable",
+		},
+	}
+
+	for _, test := range tests {
+		client, fake := newTabbycat(t)
+		fake.Fail(http.MethodPost, "teams", tabbycattest.Failure{Status: test.status, Body: test.body})
+
+		_, err := client.CreateTeam(tabbycat.TeamDetails{Reference: "Team 5"})
+
+		var tabbycatErr *tabbycat.Error
+		if !errors.As(err, &tabbycatErr) || !errors.Is(err, test.kind) {
+			t.Errorf("%v: got %v, want a %v", test.name, err, test.kind)
+			continue
+		}
+
+		if tabbycatErr.StatusCode != test.status || tabbycatErr.Detail != test.detail || !reflect.DeepEqual(tabbycatErr.Fields, test.fields) {
+			t.Errorf("%v: got %+v", test.name, tabbycatErr)
+		}
+
+		if summary := tabbycatErr.Summary(); summary != test.summary {
+			t.Errorf("%v: got summary %q, want %q", test.name, summary, test.summary)
+		}
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	client, fake := newTabbycat(t)
+	client.SetRetries(1, time.Millisecond)
+	fake.Fail(http.MethodGet, "venues", tabbycattest.Failure{Status: http.StatusTooManyRequests, RetryAfter: "1", Times: 1})
+
+	start := time.Now()
+	if _, err := client.GetVenues(); err != nil {
+		t.Fatalf("GetVenues: %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("retried after %v, want Retry-After's second", elapsed)
+	}
+}
+
+func TestServerErrorOnCreate(t *testing.T) {
+	client, fake := newTabbycat(t)
+	client.SetRetries(2, time.Millisecond)
+	fake.Fail(http.MethodPost, "teams", tabbycattest.Failure{Status: http.StatusBadGateway, Times: 1})
+
+	if _, err := client.CreateTeam(tabbycat.TeamDetails{Reference: "Team 5"}); !errors.Is(err, tabbycat.ErrServer) {
+		t.Errorf("got %v, want ErrServer", err)
+	}
+
+	if got := len(fake.RequestsTo(http.MethodPost, "teams")); got != 1 {
+		t.Errorf("got %v POSTs to teams, want 1 because creating isn't safe to repeat", got)
+	}
+}
+
+func TestBarcodePartialFailure(t *testing.T) {
+	client, fake := newTabbycat(t)
+	client.SetRetries(0, time.Millisecond)
+	fake.Fail(http.MethodGet, "speakers/2/checkin", tabbycattest.Failure{Status: http.StatusInternalServerError})
+
+	teams, err := client.GetTeams()
+
+	var barcodeErr *tabbycat.BarcodeError
+	if !errors.As(err, &barcodeErr) {
+		t.Fatalf("got %v, want a BarcodeError", err)
+	}
+
+	if len(barcodeErr.Failures) != 1 || barcodeErr.Failures[0].Participant.Id != 2 || !errors.Is(barcodeErr.Failures[0].Err, tabbycat.ErrServer) {
+		t.Errorf("got failures %+v, want speaker 2's", barcodeErr.Failures)
+	}
+
+	if len(teams) != 4 || teams[0].Speakers[0].Barcode != "100001" {
+		t.Errorf("got teams %+v, want the rest fetched anyway", teams)
+	}
+}
+
+func TestReleaseMotionUsesLocation(t *testing.T) {
+	starts := time.Date(2026, time.March, 7, 12, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		location *time.Location
+		want     string
+	}{
+		{time.UTC, "12:30:00"},
+		{time.FixedZone("EST", -5*60*60), "07:30:00"},
+		{time.FixedZone("IST", 5*60*60+30*60), "18:00:00"},
+	}
+
+	for _, test := range tests {
+		client, fake := newTabbycat(t)
+		client.SetLocation(test.location)
+
+		if err := client.ReleaseMotion(1, starts); err != nil {
+			t.Fatalf("ReleaseMotion: %v", err)
+		}
+
+		requests := fake.RequestsTo(http.MethodPost, "rounds/1")
+		if len(requests) != 1 {
+			t.Fatalf("got %v POSTs to rounds/1, want 1", len(requests))
+		}
+
+		var sent struct {
+			StartsAt        string `json:"starts_at"`
+			MotionsReleased bool   `json:"motions_released"`
+		}
+		if err := json.Unmarshal(requests[0].Body, &sent); err != nil || sent.StartsAt != test.want || !sent.MotionsReleased {
+			t.Errorf("in %v, sent %+v (%v), want starts_at %v", test.location, sent, err, test.want)
+		}
+	}
+}
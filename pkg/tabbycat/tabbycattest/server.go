@@ -0,0 +1,404 @@
+package tabbycattest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+type Request struct {
+	Method string
+	Path   string
+	Body   []byte
+}
+
+// Failure is a response the server gives instead of the usual one. Times is
+// how many requests fail before the server recovers, or every request if it's
+// zero.
+type Failure struct {
+	Status     int
+	Body       string
+	RetryAfter string
+	Times      int
+}
+
+// Server serves GET requests for /api/v1/tournaments/<slug>/<path> from the
+// fixture file <path>.json, so teams.json answers GetTeams and
+// rounds/1/pairings.json answers GetDraw(1).
+type Server struct {
+	slug      string
+	apiKey    string
+	mutex     sync.Mutex
+	resources map[string][]byte
+	requests  []Request
+	failures  map[string]*Failure
+	counter   uint64
+}
+
+func New(slug string, fixtures string) (*Server, error) {
+	s := &Server{
+		slug:      slug,
+		resources: make(map[string][]byte),
+		failures:  make(map[string]*Failure),
+	}
+
+	err := filepath.Walk(fixtures, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || filepath.Ext(path) != ".json" {
+			return err
+		}
+
+		contents, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		var parsed interface{}
+		if err := json.Unmarshal(contents, &parsed); err != nil {
+			return fmt.Errorf("fixture %v is not valid JSON: %v", path, err)
+		}
+
+		if id := highestId(parsed); id > s.counter {
+			s.counter = id
+		}
+
+		relative, err := filepath.Rel(fixtures, path)
+		if err != nil {
+			return err
+		}
+
+		s.resources[strings.TrimSuffix(filepath.ToSlash(relative), ".json")] = contents
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (s *Server) SetApiKey(apiKey string) {
+	s.apiKey = apiKey
+}
+
+// Fail makes requests with method to path, relative to the tournament, fail.
+func (s *Server) Fail(method string, path string, failure Failure) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.failures[method+" "+path] = &failure
+}
+
+func (s *Server) Requests() []Request {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return append([]Request{}, s.requests...)
+}
+
+func (s *Server) RequestsTo(method string, path string) []Request {
+	matching := make([]Request, 0)
+
+	for _, request := range s.Requests() {
+		if request.Method == method && request.Path == path {
+			matching = append(matching, request)
+		}
+	}
+
+	return matching
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	tournament := fmt.Sprintf("/api/v1/tournaments/%v/", s.slug)
+
+	var path string
+	switch {
+	case strings.HasPrefix(r.URL.Path, tournament):
+		path = strings.TrimPrefix(r.URL.Path, tournament)
+	case strings.HasPrefix(r.URL.Path, "/api/v1/") && !strings.HasPrefix(r.URL.Path, "/api/v1/tournaments/"):
+		path = strings.TrimPrefix(r.URL.Path, "/api/v1/")
+	default:
+		writeJson(w, http.StatusNotFound, []byte(`{"detail":"Not found."}`))
+		return
+	}
+
+	if s.apiKey != "" && r.Header.Get("Authorization") != fmt.Sprintf("Token %v", s.apiKey) {
+		writeJson(w, http.StatusUnauthorized, []byte(`{"detail":"Invalid token."}`))
+		return
+	}
+
+	path = strings.Trim(path, "/")
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		writeJson(w, http.StatusBadRequest, []byte(`{"detail":"Could not read body."}`))
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if r.Method != http.MethodGet {
+		s.requests = append(s.requests, Request{r.Method, path, body})
+	}
+
+	if failure, ok := s.failures[r.Method+" "+path]; ok {
+		if failure.Times == 1 {
+			delete(s.failures, r.Method+" "+path)
+		} else if failure.Times > 1 {
+			failure.Times -= 1
+		}
+
+		if failure.RetryAfter != "" {
+			w.Header().Set("Retry-After", failure.RetryAfter)
+		}
+
+		writeJson(w, failure.Status, []byte(failure.Body))
+		return
+	}
+
+	resource, found := s.resources[path]
+
+	switch r.Method {
+	case http.MethodGet:
+		if !found {
+			writeJson(w, http.StatusNotFound, []byte(`{"detail":"Not found."}`))
+			return
+		}
+
+		writeJson(w, http.StatusOK, resource)
+	case http.MethodPost, http.MethodPut, http.MethodPatch:
+		if len(body) == 0 {
+			if !found {
+				resource = []byte(`{}`)
+			}
+
+			if isCheckin(path) && found {
+				resource = s.setChecked(path, true)
+			}
+
+			writeJson(w, http.StatusOK, resource)
+			return
+		}
+
+		var object map[string]interface{}
+		if err := json.Unmarshal(body, &object); err != nil {
+			writeJson(w, http.StatusBadRequest, []byte(`{"detail":"JSON parse error."}`))
+			return
+		}
+
+		if r.Method == http.MethodPost && !isItem(path) {
+			created, err := s.create(baseUrl(r), path, object)
+			if err != nil {
+				writeJson(w, http.StatusInternalServerError, []byte(`{"detail":"Could not create resource."}`))
+				return
+			}
+
+			writeJson(w, http.StatusCreated, created)
+			return
+		}
+
+		if r.Method == http.MethodPatch && found {
+			var existing map[string]interface{}
+			if err := json.Unmarshal(resource, &existing); err == nil {
+				for key, value := range object {
+					existing[key] = value
+				}
+				object = existing
+			}
+		}
+
+		updated, err := s.update(path, object)
+		if err != nil {
+			writeJson(w, http.StatusInternalServerError, []byte(`{"detail":"Could not update resource."}`))
+			return
+		}
+
+		writeJson(w, http.StatusOK, updated)
+	case http.MethodDelete:
+		switch {
+		case !found:
+			writeJson(w, http.StatusNotFound, []byte(`{"detail":"Not found."}`))
+		case isCheckin(path):
+			writeJson(w, http.StatusOK, s.setChecked(path, false))
+		default:
+			s.remove(path)
+			w.WriteHeader(http.StatusNoContent)
+		}
+	default:
+		writeJson(w, http.StatusMethodNotAllowed, []byte(`{"detail":"Method not allowed."}`))
+	}
+}
+
+func (s *Server) create(url string, collection string, object map[string]interface{}) ([]byte, error) {
+	s.counter += 1
+	object["id"] = s.counter
+	object["url"] = fmt.Sprintf("%v/%v", url, s.counter)
+
+	if speakers, ok := object["speakers"].([]interface{}); ok {
+		for _, speaker := range speakers {
+			if speaker, ok := speaker.(map[string]interface{}); ok {
+				s.counter += 1
+				speaker["id"] = s.counter
+			}
+		}
+	}
+
+	created, err := json.Marshal(object)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []json.RawMessage
+	if existing, ok := s.resources[collection]; ok {
+		if err := json.Unmarshal(existing, &items); err != nil {
+			return nil, err
+		}
+	}
+
+	items = append(items, created)
+	if s.resources[collection], err = json.Marshal(items); err != nil {
+		return nil, err
+	}
+
+	s.resources[fmt.Sprintf("%v/%v", collection, object["id"])] = created
+	return created, nil
+}
+
+func (s *Server) update(path string, object map[string]interface{}) ([]byte, error) {
+	collection, id := splitItem(path)
+	if id != "" {
+		if parsed, err := strconv.ParseUint(id, 10, 64); err == nil {
+			object["id"] = parsed
+		}
+	}
+
+	updated, err := json.Marshal(object)
+	if err != nil {
+		return nil, err
+	}
+
+	s.resources[path] = updated
+	s.replaceInCollection(collection, id, updated)
+
+	return updated, nil
+}
+
+func (s *Server) remove(path string) {
+	collection, id := splitItem(path)
+
+	delete(s.resources, path)
+	s.replaceInCollection(collection, id, nil)
+}
+
+func (s *Server) replaceInCollection(collection string, id string, replacement []byte) {
+	existing, ok := s.resources[collection]
+	if !ok {
+		return
+	}
+
+	var items []json.RawMessage
+	if err := json.Unmarshal(existing, &items); err != nil {
+		return
+	}
+
+	kept := make([]json.RawMessage, 0, len(items))
+	for _, item := range items {
+		var identified struct{ Id json.Number }
+		if err := json.Unmarshal(item, &identified); err == nil && identified.Id.String() == id {
+			if replacement != nil {
+				kept = append(kept, replacement)
+			}
+			continue
+		}
+
+		kept = append(kept, item)
+	}
+
+	if serialized, err := json.Marshal(kept); err == nil {
+		s.resources[collection] = serialized
+	}
+}
+
+// setChecked checks a participant in or out, the way Tabbycat's checkin
+// endpoints do.
+func (s *Server) setChecked(path string, checked bool) []byte {
+	var checkin map[string]interface{}
+	if err := json.Unmarshal(s.resources[path], &checkin); err != nil {
+		return s.resources[path]
+	}
+
+	checkin["checked"] = checked
+	if updated, err := json.Marshal(checkin); err == nil {
+		s.resources[path] = updated
+	}
+
+	return s.resources[path]
+}
+
+func isCheckin(path string) bool {
+	return strings.HasSuffix(path, "/checkin")
+}
+
+func isItem(path string) bool {
+	_, id := splitItem(path)
+	return id != ""
+}
+
+func splitItem(path string) (string, string) {
+	separator := strings.LastIndex(path, "/")
+	if separator == -1 {
+		return path, ""
+	}
+
+	if _, err := strconv.ParseUint(path[separator+1:], 10, 64); err != nil {
+		return path, ""
+	}
+
+	return path[:separator], path[separator+1:]
+}
+
+func baseUrl(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+
+	return fmt.Sprintf("%v://%v%v", scheme, r.Host, strings.TrimSuffix(r.URL.Path, "/"))
+}
+
+func highestId(value interface{}) uint64 {
+	highest := uint64(0)
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			if id, ok := item.(float64); ok && key == "id" && uint64(id) > highest {
+				highest = uint64(id)
+			}
+
+			if id := highestId(item); id > highest {
+				highest = id
+			}
+		}
+	case []interface{}:
+		for _, item := range v {
+			if id := highestId(item); id > highest {
+				highest = id
+			}
+		}
+	}
+
+	return highest
+}
+
+func writeJson(w http.ResponseWriter, status int, body []byte) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, _ = w.Write(body)
+}
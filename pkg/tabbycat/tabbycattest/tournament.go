@@ -0,0 +1,61 @@
+package tabbycattest
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/hitecherik/Tabulatron/internal/db"
+	"github.com/hitecherik/Tabulatron/pkg/tabbycat"
+)
+
+// NewTournament serves the example tournament from the fixtures and returns a
+// client for it, along with a database that already holds its teams and
+// adjudicators. Everything is cleaned up when the test finishes.
+func NewTournament(t *testing.T) (*tabbycat.Tabbycat, *Server, *db.Database) {
+	t.Helper()
+
+	_, file, _, _ := runtime.Caller(0)
+	fake, err := New("example", filepath.Join(filepath.Dir(file), "fixtures"))
+	if err != nil {
+		t.Fatalf("loading fixtures: %v", err)
+	}
+
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+	client := tabbycat.New("", server.URL, "example")
+
+	dir, err := ioutil.TempDir("", "tabbycattest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	database, err := db.New(filepath.Join(dir, "test.db"))
+	if err != nil {
+		t.Fatalf("creating database: %v", err)
+	}
+
+	teams, err := client.GetTeams()
+	if err != nil {
+		t.Fatalf("GetTeams: %v", err)
+	}
+
+	adjudicators, err := client.GetAdjudicators()
+	if err != nil {
+		t.Fatalf("GetAdjudicators: %v", err)
+	}
+
+	if err := database.AddTeams(teams); err != nil {
+		t.Fatalf("AddTeams: %v", err)
+	}
+
+	if err := database.AddParticipants(false, adjudicators); err != nil {
+		t.Fatalf("AddParticipants: %v", err)
+	}
+
+	return client, fake, database
+}
@@ -0,0 +1,259 @@
+package tabbycat
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+const (
+	StagePreliminary string = "P"
+	StageElimination string = "E"
+
+	DrawTypeRandom      string = "R"
+	DrawTypeManual      string = "M"
+	DrawTypeRoundRobin  string = "D"
+	DrawTypePowerPaired string = "P"
+	DrawTypeElimination string = "E"
+)
+
+type Institution struct {
+	Id   uint   `json:"id"`
+	Url  string `json:"url"`
+	Name string `json:"name"`
+	Code string `json:"code"`
+}
+
+type VenueCategory struct {
+	Id   uint   `json:"id"`
+	Url  string `json:"url"`
+	Name string `json:"name"`
+}
+
+type SpeakerDetails struct {
+	Name  string `json:"name"`
+	Email string `json:"email,omitempty"`
+}
+
+type TeamDetails struct {
+	Reference            string           `json:"reference"`
+	ShortReference       string           `json:"short_reference,omitempty"`
+	Institution          string           `json:"institution,omitempty"`
+	UseInstitutionPrefix bool             `json:"use_institution_prefix"`
+	Emoji                string           `json:"emoji,omitempty"`
+	Speakers             []SpeakerDetails `json:"speakers"`
+}
+
+type AdjudicatorDetails struct {
+	Name        string  `json:"name"`
+	Email       string  `json:"email,omitempty"`
+	Institution string  `json:"institution,omitempty"`
+	BaseScore   float64 `json:"base_score"`
+}
+
+type VenueDetails struct {
+	Name       string   `json:"name"`
+	Priority   int      `json:"priority"`
+	Categories []string `json:"categories"`
+}
+
+type RoundDetails struct {
+	Seq          uint   `json:"seq"`
+	Name         string `json:"name"`
+	Abbreviation string `json:"abbreviation"`
+	Stage        string `json:"stage"`
+	DrawType     string `json:"draw_type"`
+}
+
+func (t *Tabbycat) GetInstitutions() ([]Institution, error) {
+	return t.GetInstitutionsContext(context.Background())
+}
+
+func (t *Tabbycat) GetInstitutionsContext(ctx context.Context) ([]Institution, error) {
+	var institutions []Institution
+	err := t.exchangeApi(ctx, http.MethodGet, "institutions", nil, &institutions)
+	return institutions, err
+}
+
+func (t *Tabbycat) CreateInstitution(name string, code string) (Institution, error) {
+	return t.CreateInstitutionContext(context.Background(), name, code)
+}
+
+func (t *Tabbycat) CreateInstitutionContext(ctx context.Context, name string, code string) (Institution, error) {
+	var institution Institution
+	err := t.exchangeApi(ctx, http.MethodPost, "institutions", Institution{Name: name, Code: code}, &institution)
+	return institution, err
+}
+
+func (t *Tabbycat) GetVenueCategories() ([]VenueCategory, error) {
+	return t.GetVenueCategoriesContext(context.Background())
+}
+
+func (t *Tabbycat) GetVenueCategoriesContext(ctx context.Context) ([]VenueCategory, error) {
+	var categories []VenueCategory
+	err := t.exchange(ctx, http.MethodGet, "venue-categories", nil, &categories)
+	return categories, err
+}
+
+func (t *Tabbycat) CreateVenueCategory(name string) (VenueCategory, error) {
+	return t.CreateVenueCategoryContext(context.Background(), name)
+}
+
+func (t *Tabbycat) CreateVenueCategoryContext(ctx context.Context, name string) (VenueCategory, error) {
+	var category VenueCategory
+	err := t.exchange(ctx, http.MethodPost, "venue-categories", VenueCategory{Name: name}, &category)
+	return category, err
+}
+
+func (t *Tabbycat) CreateTeam(details TeamDetails) (Team, error) {
+	return t.CreateTeamContext(context.Background(), details)
+}
+
+func (t *Tabbycat) CreateTeamContext(ctx context.Context, details TeamDetails) (Team, error) {
+	var team Team
+	err := t.exchange(ctx, http.MethodPost, "teams", details, &team)
+	return team, err
+}
+
+func (t *Tabbycat) UpdateTeam(id uint, details TeamDetails) (Team, error) {
+	return t.UpdateTeamContext(context.Background(), id, details)
+}
+
+func (t *Tabbycat) UpdateTeamContext(ctx context.Context, id uint, details TeamDetails) (Team, error) {
+	var team Team
+	err := t.exchange(ctx, http.MethodPut, fmt.Sprintf("teams/%v", id), details, &team)
+	return team, err
+}
+
+func (t *Tabbycat) DeleteTeam(id uint) error {
+	return t.DeleteTeamContext(context.Background(), id)
+}
+
+func (t *Tabbycat) DeleteTeamContext(ctx context.Context, id uint) error {
+	return t.exchange(ctx, http.MethodDelete, fmt.Sprintf("teams/%v", id), nil, nil)
+}
+
+func (t *Tabbycat) CreateAdjudicator(details AdjudicatorDetails) (Participant, error) {
+	return t.CreateAdjudicatorContext(context.Background(), details)
+}
+
+func (t *Tabbycat) CreateAdjudicatorContext(ctx context.Context, details AdjudicatorDetails) (Participant, error) {
+	var adjudicator Participant
+	err := t.exchange(ctx, http.MethodPost, "adjudicators", details, &adjudicator)
+	return adjudicator, err
+}
+
+func (t *Tabbycat) UpdateAdjudicator(id uint, details AdjudicatorDetails) (Participant, error) {
+	return t.UpdateAdjudicatorContext(context.Background(), id, details)
+}
+
+func (t *Tabbycat) UpdateAdjudicatorContext(ctx context.Context, id uint, details AdjudicatorDetails) (Participant, error) {
+	var adjudicator Participant
+	err := t.exchange(ctx, http.MethodPut, fmt.Sprintf("adjudicators/%v", id), details, &adjudicator)
+	return adjudicator, err
+}
+
+func (t *Tabbycat) DeleteAdjudicator(id uint) error {
+	return t.DeleteAdjudicatorContext(context.Background(), id)
+}
+
+func (t *Tabbycat) DeleteAdjudicatorContext(ctx context.Context, id uint) error {
+	return t.exchange(ctx, http.MethodDelete, fmt.Sprintf("adjudicators/%v", id), nil, nil)
+}
+
+func (t *Tabbycat) CreateVenue(details VenueDetails) (Venue, error) {
+	return t.CreateVenueContext(context.Background(), details)
+}
+
+func (t *Tabbycat) CreateVenueContext(ctx context.Context, details VenueDetails) (Venue, error) {
+	var venue Venue
+	err := t.exchange(ctx, http.MethodPost, "venues", details, &venue)
+	return venue, err
+}
+
+func (t *Tabbycat) UpdateVenue(id uint, details VenueDetails) (Venue, error) {
+	return t.UpdateVenueContext(context.Background(), id, details)
+}
+
+func (t *Tabbycat) UpdateVenueContext(ctx context.Context, id uint, details VenueDetails) (Venue, error) {
+	var venue Venue
+	err := t.exchange(ctx, http.MethodPut, fmt.Sprintf("venues/%v", id), details, &venue)
+	return venue, err
+}
+
+func (t *Tabbycat) DeleteVenue(id uint) error {
+	return t.DeleteVenueContext(context.Background(), id)
+}
+
+func (t *Tabbycat) DeleteVenueContext(ctx context.Context, id uint) error {
+	return t.exchange(ctx, http.MethodDelete, fmt.Sprintf("venues/%v", id), nil, nil)
+}
+
+func (t *Tabbycat) CreateRound(details RoundDetails) (Round, error) {
+	return t.CreateRoundContext(context.Background(), details)
+}
+
+func (t *Tabbycat) CreateRoundContext(ctx context.Context, details RoundDetails) (Round, error) {
+	var response roundResponse
+	if err := t.exchange(ctx, http.MethodPost, "rounds", details, &response); err != nil {
+		return Round{}, err
+	}
+
+	return response.toRound()
+}
+
+func (t *Tabbycat) UpdateRound(round uint64, details RoundDetails) (Round, error) {
+	return t.UpdateRoundContext(context.Background(), round, details)
+}
+
+func (t *Tabbycat) UpdateRoundContext(ctx context.Context, round uint64, details RoundDetails) (Round, error) {
+	var response roundResponse
+	if err := t.exchange(ctx, http.MethodPut, fmt.Sprintf("rounds/%v", round), details, &response); err != nil {
+		return Round{}, err
+	}
+
+	return response.toRound()
+}
+
+func (t *Tabbycat) DeleteRound(round uint64) error {
+	return t.DeleteRoundContext(context.Background(), round)
+}
+
+func (t *Tabbycat) DeleteRoundContext(ctx context.Context, round uint64) error {
+	return t.exchange(ctx, http.MethodDelete, fmt.Sprintf("rounds/%v", round), nil, nil)
+}
+
+func (t *Tabbycat) exchange(ctx context.Context, method string, path string, in interface{}, out interface{}) error {
+	return t.exchangeTo(ctx, t.endpoint, method, path, in, out)
+}
+
+// exchangeApi is exchange for resources that aren't part of a tournament, like
+// institutions.
+func (t *Tabbycat) exchangeApi(ctx context.Context, method string, path string, in interface{}, out interface{}) error {
+	return t.exchangeTo(ctx, t.api, method, path, in, out)
+}
+
+func (t *Tabbycat) exchangeTo(ctx context.Context, base string, method string, path string, in interface{}, out interface{}) error {
+	var body []byte
+
+	if in != nil {
+		serialized, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+
+		body = serialized
+	}
+
+	response, err := t.makeRequestTo(ctx, base, method, path, body)
+	if err != nil {
+		return err
+	}
+
+	if out == nil || len(response) == 0 {
+		return nil
+	}
+
+	return json.Unmarshal(response, out)
+}